@@ -3,40 +3,162 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"5mdt/bd_bot/internal/auth"
 	"5mdt/bd_bot/internal/bot"
 	"5mdt/bd_bot/internal/handlers"
 	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/metrics"
+	"5mdt/bd_bot/internal/middleware"
+	"5mdt/bd_bot/internal/storage"
 	"5mdt/bd_bot/internal/templates"
 )
 
+const (
+	// maxBodyBytes limits the size of request bodies, such as form posts.
+	maxBodyBytes = 1 << 20
+	// metricsPath is the path of the Prometheus metrics.
+	metricsPath = "/metrics"
+	// shutdownTimeout bounds how long shutdown waits for in-flight requests and the bot.
+	shutdownTimeout = 20 * time.Second
+)
+
 func main() {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	// Records of older versions get their IDs before anything else reads them
+	if err := storage.AssignRecordIDs(); err != nil {
+		logger.Error("MAIN", "Failed to assign record IDs: %v", err)
+	}
+
 	// Initialize Telegram bot
 	telegramBot, err := initBot()
 	if err != nil {
 		logger.Error("MAIN", "Failed to initialize Telegram bot: %v", err)
 	}
 
+	authenticator, err := initAuth(telegramBot)
+	if err != nil {
+		logger.Error("MAIN", "Invalid authentication configuration: %v", err)
+		os.Exit(1)
+	}
+
 	tpl := templates.LoadTemplates()
 
-	http.HandleFunc("/", handlers.IndexHandler(tpl, telegramBot))
-	http.HandleFunc("/bot-info", handlers.BotInfoHandler(tpl, telegramBot))
-	http.HandleFunc("/save-row", handlers.SaveRowHandler(tpl))
-	http.HandleFunc("/delete-row", handlers.DeleteRowHandler(tpl))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handlers.IndexHandler(tpl, telegramBot))
+	mux.HandleFunc("/bot-info", handlers.BotInfoHandler(tpl, telegramBot))
+	mux.HandleFunc("/save-row", handlers.SaveRowHandler(tpl))
+	mux.HandleFunc("/delete-row", handlers.DeleteRowHandler(tpl))
+	mux.HandleFunc(auth.LoginPath, handlers.LoginHandler(tpl, authenticator))
+	mux.HandleFunc(auth.TelegramLoginPath, handlers.TelegramLoginHandler(tpl, authenticator))
+	mux.HandleFunc(auth.LogoutPath, handlers.LogoutHandler(authenticator))
+	mux.HandleFunc(handlers.RolesPath, handlers.RolesHandler(tpl, authenticator))
+	mux.HandleFunc(handlers.HistoryPath, handlers.HistoryHandler(tpl))
+	mux.HandleFunc(handlers.RecordHistoryPath, handlers.RecordHistoryHandler(tpl))
+	mux.HandleFunc(handlers.AuditPath, handlers.AuditHandler(tpl))
+	mux.HandleFunc(handlers.TrashPath, handlers.TrashHandler(tpl))
+	mux.HandleFunc(handlers.UndoDeletePath, handlers.UndoDeleteHandler(tpl))
+	mux.HandleFunc(handlers.ExportCSVPath, handlers.ExportCSVHandler())
+	mux.HandleFunc(handlers.ImportPath, handlers.ImportHandler(tpl))
+
+	// JSON API
+	api := handlers.APIBirthdaysHandler()
+	mux.HandleFunc(handlers.APIBirthdaysPath, api)
+	mux.HandleFunc(handlers.APIBirthdaysPath+"/", api)
+	mux.HandleFunc("/api/v1/openapi.json", handlers.OpenAPIHandler())
 
 	addr := ":" + port
 	logger.Info("MAIN", "Server starting on %s", addr)
 	logger.Info("MAIN", "Debug logging enabled: %t", logger.IsDebugEnabled())
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		logger.Error("MAIN", "Server failed to start: %v", err)
+	// Probes and metrics are served without authentication
+	root := http.NewServeMux()
+	root.HandleFunc(handlers.HealthzPath, handlers.HealthHandler())
+	root.HandleFunc(handlers.ReadyzPath, handlers.ReadyHandler(telegramBot, os.Getenv("TELEGRAM_BOT_TOKEN") != ""))
+	root.HandleFunc(metricsPath, metrics.Handler())
+	root.Handle("/", authenticator.CSRFProtect(authenticator.Middleware(mux)))
+
+	handler := middleware.Chain(root,
+		middleware.RequestID,
+		middleware.LogRequests,
+		middleware.Metrics(routeOf(root, mux)),
+		middleware.Recover,
+		middleware.LimitBody(maxBodyBytes),
+	)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+	if err := serve(server, telegramBot); err != nil {
+		logger.Error("MAIN", "Server failed: %v", err)
+		os.Exit(1)
+	}
+}
+
+// routeOf returns a function mapping requests to the pattern they are served by, for metric labels.
+// Requests the application mux doesn't know are served by its catch-all "/" pattern.
+func routeOf(muxes ...*http.ServeMux) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		for _, mux := range muxes {
+			if _, pattern := mux.Handler(r); pattern != "" && pattern != "/" {
+				return pattern
+			}
+		}
+		return "/"
+	}
+}
+
+// serve runs the server until it fails or the process receives SIGINT or SIGTERM.
+// It then stops accepting requests, waits for in-flight requests to finish and stops the bot,
+// so that no birthday file write or notification is interrupted halfway.
+func serve(server *http.Server, telegramBot *bot.Bot) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-serverErr:
+	case <-ctx.Done():
+		logger.Info("MAIN", "Shutdown signal received, shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+		logger.Error("MAIN", "Failed to drain HTTP requests: %v", shutdownErr)
+	}
+	if telegramBot != nil {
+		if shutdownErr := telegramBot.Shutdown(shutdownCtx); shutdownErr != nil {
+			logger.Error("MAIN", "Failed to stop Telegram bot: %v", shutdownErr)
+		}
 	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("MAIN", "Shutdown complete")
+	return nil
 }
 
 // initBot creates and starts the Telegram bot from the TELEGRAM_BOT_TOKEN environment variable.
@@ -58,3 +180,32 @@ func initBot() (*bot.Bot, error) {
 	logger.Info("BOT", "Telegram bot started successfully")
 	return telegramBot, nil
 }
+
+// initAuth creates the authenticator from the AUTH_* environment variables.
+// Sign-in with Telegram checks group administrators with the bot, so it requires a running bot.
+// Without any sign-in method, the web interface is left open and a warning is logged.
+func initAuth(telegramBot *bot.Bot) (*auth.Authenticator, error) {
+	cfg, err := auth.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TelegramBotToken != "" {
+		if telegramBot == nil {
+			return nil, fmt.Errorf("AUTH_TELEGRAM_LOGIN requires a running Telegram bot")
+		}
+		cfg.TelegramBotUsername = telegramBot.GetUsername()
+		cfg.ChatAdmins = telegramBot
+	}
+
+	authenticator := auth.New(cfg)
+	if !authenticator.Enabled() {
+		logger.Warn("AUTH", "No AUTH_USERS, AUTH_USERS_FILE, AUTH_TRUSTED_PROXIES or AUTH_TELEGRAM_LOGIN configured, the web interface is open to anyone")
+		return authenticator, nil
+	}
+	if err := authenticator.CheckAdmins(); err != nil {
+		return nil, err
+	}
+	logger.Info("AUTH", "Authentication enabled with %d local user(s), %d trusted proxy network(s), Telegram sign-in: %t",
+		len(cfg.Users), len(cfg.TrustedProxies), cfg.TelegramBotToken != "")
+	return authenticator, nil
+}
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"5mdt/bd_bot/internal/handlers"
+	"5mdt/bd_bot/internal/storage"
 	"5mdt/bd_bot/internal/templates"
 )
 
@@ -47,7 +48,6 @@ func TestMainHandlers(t *testing.T) {
 	}
 
 	form := url.Values{
-		"idx":               {"-1"},
 		"name":              {"X"},
 		"birth_date":        {"01-01"},
 		"last_notification": {"2025-01-01T12:00:00Z"},
@@ -58,9 +58,36 @@ func TestMainHandlers(t *testing.T) {
 		t.Errorf("POST /save-row returned %d", w.Code)
 	}
 
-	del := url.Values{"idx": {"0"}}
+	bs, err := storage.LoadBirthdays()
+	if err != nil || len(bs) != 1 {
+		t.Fatalf("expected the saved record, got %+v, %v", bs, err)
+	}
+	del := url.Values{"id": {bs[0].ID}}
 	w = doRequest(t, "POST", "/delete-row", del, handlers.DeleteRowHandler(tpl))
 	if w.Code != http.StatusOK {
 		t.Errorf("POST /delete-row returned %d", w.Code)
 	}
 }
+
+func TestRouteOf(t *testing.T) {
+	noop := func(http.ResponseWriter, *http.Request) {}
+	root := http.NewServeMux()
+	root.HandleFunc("/metrics", noop)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", noop)
+	mux.HandleFunc(handlers.APIBirthdaysPath+"/", noop)
+	root.Handle("/", mux)
+
+	route := routeOf(root, mux)
+	tests := map[string]string{
+		"/metrics":                 "/metrics",
+		"/api/v1/birthdays/abc123": "/api/v1/birthdays/",
+		"/wp-login.php":            "/",
+		"/":                        "/",
+	}
+	for path, want := range tests {
+		if got := route(httptest.NewRequest(http.MethodGet, path, nil)); got != want {
+			t.Errorf("route(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
@@ -6,3 +6,5 @@ require (
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/crypto v0.32.0
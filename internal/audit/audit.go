@@ -0,0 +1,240 @@
+// Package audit records who created, changed, deleted or exported birthday records, with field-level
+// before and after values, in the append-only audit log.
+//
+// Entries are kept for as long as the log exists; there is no automatic expiry. When a chat is
+// forgotten, its entries are redacted (see Forget) so that only times, actions, record IDs and
+// field names remain.
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+// anonymousActor is recorded for changes in the web interface when authentication is disabled.
+const anonymousActor = "anonymous"
+
+// forgottenActor replaces the actor of changes made by a Telegram user who was forgotten.
+const forgottenActor = "forgotten"
+
+// TelegramActor returns the actor of a change made by a Telegram user, as identified in role assignments.
+func TelegramActor(userID int64) string {
+	return "telegram:" + strconv.FormatInt(userID, 10)
+}
+
+// fields returns the audited fields of the record as text, in a fixed order. Notification
+// state maintained by the bot (routes, gift collections) is not audited.
+func fields(b models.Birthday) [][2]string {
+	var links []string
+	for _, l := range b.Links {
+		link := strconv.FormatInt(l.ChatID, 10)
+		if l.Notify != models.NotifyAll {
+			link += " (" + l.Notify + ")"
+		}
+		links = append(links, link)
+	}
+	lastNotification := ""
+	if !b.LastNotification.IsZero() {
+		lastNotification = b.LastNotification.UTC().Format(time.RFC3339)
+	}
+	chatID := ""
+	if b.ChatID != 0 {
+		chatID = strconv.FormatInt(b.ChatID, 10)
+	}
+	return [][2]string{
+		{"name", b.Name},
+		{"type", b.Type},
+		{"birth_date", b.BirthDate},
+		{"chat_id", chatID},
+		{"tags", strings.Join(b.Tags, ", ")},
+		{"notes", b.Notes},
+		{"links", strings.Join(links, ", ")},
+		{"last_notification", lastNotification},
+	}
+}
+
+// Changes returns the fields that differ between the two versions of a record.
+func Changes(before, after models.Birthday) []models.FieldChange {
+	var changes []models.FieldChange
+	b, a := fields(before), fields(after)
+	for i := range b {
+		if b[i][1] != a[i][1] {
+			changes = append(changes, models.FieldChange{Field: b[i][0], Before: b[i][1], After: a[i][1]})
+		}
+	}
+	return changes
+}
+
+// Created records the creation of a record.
+func Created(actor, source string, b models.Birthday) {
+	write(models.AuditEntry{
+		Actor: actor, Source: source, Action: models.AuditCreate,
+		RecordID: b.ID, RecordName: b.Name, Changes: Changes(models.Birthday{}, b),
+	})
+}
+
+// Updated records the change of a record. Nothing is recorded if no audited field changed.
+func Updated(actor, source string, before, after models.Birthday) {
+	changes := Changes(before, after)
+	if len(changes) == 0 {
+		return
+	}
+	write(models.AuditEntry{
+		Actor: actor, Source: source, Action: models.AuditUpdate,
+		RecordID: after.ID, RecordName: after.Name, Changes: changes,
+	})
+}
+
+// Deleted records the deletion of a record.
+func Deleted(actor, source string, b models.Birthday) {
+	write(models.AuditEntry{
+		Actor: actor, Source: source, Action: models.AuditDelete,
+		RecordID: b.ID, RecordName: b.Name, Changes: Changes(b, models.Birthday{}),
+	})
+}
+
+// Restored records the restoration of a deleted record from the trash.
+func Restored(actor, source string, b models.Birthday) {
+	write(models.AuditEntry{
+		Actor: actor, Source: source, Action: models.AuditRestore,
+		RecordID: b.ID, RecordName: b.Name, Changes: Changes(models.Birthday{}, b),
+	})
+}
+
+// Purged records the permanent deletion of a record from the trash. The values were already
+// recorded when the record was deleted, so no changes are listed.
+func Purged(actor, source string, b models.Birthday) {
+	write(models.AuditEntry{
+		Actor: actor, Source: source, Action: models.AuditPurge,
+		RecordID: b.ID, RecordName: b.Name,
+	})
+}
+
+// Exported records that the record was exported with the personal data of a chat. The values
+// are not repeated, as the export went to the actor.
+func Exported(actor, source string, b models.Birthday) {
+	write(models.AuditEntry{
+		Actor: actor, Source: source, Action: models.AuditExport,
+		RecordID: b.ID, RecordName: b.Name,
+	})
+}
+
+// Forget redacts the audit log for a forgotten chat and returns how many entries were changed.
+// Entries of the given records, and of every record that ever belonged to the chat, lose their
+// record name and field values; the chat is removed from the links and chat IDs of other records.
+// For a private chat, whose ID is the user's Telegram ID, the user's changes no longer name them.
+func Forget(chatID int64, recordIDs []string) (int, error) {
+	chat := strconv.FormatInt(chatID, 10)
+	actor := ""
+	if chatID > 0 {
+		actor = TelegramActor(chatID)
+	}
+
+	redacted := 0
+	err := storage.RedactAuditLog(func(es []models.AuditEntry) bool {
+		forgotten := make(map[string]bool)
+		for _, id := range recordIDs {
+			forgotten[id] = true
+		}
+		for _, e := range es {
+			for _, c := range e.Changes {
+				if c.Field == "chat_id" && (c.Before == chat || c.After == chat) {
+					forgotten[e.RecordID] = true
+				}
+			}
+		}
+
+		redacted = 0
+		for i := range es {
+			if redactEntry(&es[i], forgotten[es[i].RecordID], chat, actor) {
+				redacted++
+			}
+		}
+		return redacted > 0
+	})
+	return redacted, err
+}
+
+// redactEntry removes the data of the forgotten chat from the entry and reports whether it changed.
+// With all set, the record name and every field value are removed.
+func redactEntry(e *models.AuditEntry, all bool, chat, actor string) bool {
+	changed := false
+	if all && !e.Redacted {
+		e.RecordName = ""
+		for i := range e.Changes {
+			e.Changes[i].Before, e.Changes[i].After = "", ""
+		}
+		e.Redacted = true
+		changed = true
+	}
+	if actor != "" && e.Actor == actor {
+		e.Actor = forgottenActor
+		changed = true
+	}
+	for i := range e.Changes {
+		c := &e.Changes[i]
+		switch c.Field {
+		case "links":
+			before, after := withoutLink(c.Before, chat), withoutLink(c.After, chat)
+			if before != c.Before || after != c.After {
+				c.Before, c.After = before, after
+				e.Redacted = true
+				changed = true
+			}
+		case "chat_id":
+			if c.Before == chat || c.After == chat {
+				if c.Before == chat {
+					c.Before = ""
+				}
+				if c.After == chat {
+					c.After = ""
+				}
+				e.Redacted = true
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// withoutLink removes the link to the chat from the text of the links field, as written by fields.
+func withoutLink(links, chat string) string {
+	if links == "" {
+		return links
+	}
+	var kept []string
+	for _, link := range strings.Split(links, ", ") {
+		if id, _, _ := strings.Cut(link, " "); id != chat {
+			kept = append(kept, link)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
+
+// write appends the entry to the audit log. Failures are logged; the change itself has already been saved.
+func write(e models.AuditEntry) {
+	e.Time = time.Now().UTC()
+	if e.Actor == "" {
+		e.Actor = anonymousActor
+	}
+	if err := storage.AppendAuditLog(e); err != nil {
+		logger.Error("AUDIT", "Failed to write audit entry (%s of record %s by %s): %v", e.Action, e.RecordID, e.Actor, err)
+		return
+	}
+	logger.Debug("AUDIT", "%s of record %s ('%s') by %s via %s: %s", e.Action, e.RecordID, e.RecordName, e.Actor, e.Source, describe(e.Changes))
+}
+
+// describe summarizes the changes for the log.
+func describe(changes []models.FieldChange) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = fmt.Sprintf("%s %q -> %q", c.Field, c.Before, c.After)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+func TestChanges(t *testing.T) {
+	before := models.Birthday{Name: "Alice", BirthDate: "1990-05-10", ChatID: 1, Tags: []string{"family"}}
+	after := before.Clone()
+	after.BirthDate = "1990-05-11"
+	after.Tags = append(after.Tags, "friends")
+	after.Links = []models.ChatLink{{ChatID: -100, Notify: models.NotifyMuted}}
+
+	changes := Changes(before, after)
+	want := []models.FieldChange{
+		{Field: "birth_date", Before: "1990-05-10", After: "1990-05-11"},
+		{Field: "tags", Before: "family", After: "family, friends"},
+		{Field: "links", After: "-100 (muted)"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("want %+v, got %+v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d: want %+v, got %+v", i, want[i], changes[i])
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+
+	b := models.Birthday{ID: "a1", Name: "Alice", BirthDate: "1990-05-10", ChatID: 1}
+	Created("", models.SourceWeb, b)
+	Updated(TelegramActor(1), models.SourceBot, b, b)
+	changed := b
+	changed.LastNotification = time.Date(2026, 5, 10, 9, 0, 0, 0, time.UTC)
+	Updated(TelegramActor(1), models.SourceBot, b, changed)
+	Deleted("admin", models.SourceAPI, changed)
+
+	es, err := storage.LoadAuditLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 3 {
+		t.Fatalf("an update without changes should not be recorded, got %+v", es)
+	}
+	if es[0].Actor != anonymousActor || es[0].Action != models.AuditCreate || len(es[0].Changes) != 3 {
+		t.Errorf("unexpected create entry: %+v", es[0])
+	}
+	if es[1].Actor != "telegram:1" || es[1].Changes[0].After != "2026-05-10T09:00:00Z" {
+		t.Errorf("unexpected update entry: %+v", es[1])
+	}
+	if es[2].Action != models.AuditDelete || es[2].RecordID != "a1" || es[2].Changes[0].Before != "Alice" || es[2].Changes[0].After != "" {
+		t.Errorf("unexpected delete entry: %+v", es[2])
+	}
+}
+
+func TestForgetRedactsEntriesOfTheChat(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+
+	alice := models.Birthday{ID: "a", Name: "Alice", BirthDate: "1990-05-10", ChatID: 1}
+	Created(TelegramActor(1), models.SourceBot, alice)
+	renamed := alice.Clone()
+	renamed.Name = "Alice B."
+	Updated(TelegramActor(1), models.SourceBot, alice, renamed)
+	bob := models.Birthday{ID: "b", Name: "Bob", BirthDate: "1985-02-03", ChatID: 2, Links: []models.ChatLink{{ChatID: -100}}}
+	linked := bob.Clone()
+	linked.Links = append(linked.Links, models.ChatLink{ChatID: 1, Notify: models.NotifyMuted})
+	Updated("admin", models.SourceWeb, bob, linked)
+
+	// Only the record ID of the deleted record is known; earlier records of the chat are found by their chat ID
+	n, err := Forget(1, nil)
+	if err != nil {
+		t.Fatalf("forget failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("want 3 redacted entries, got %d", n)
+	}
+
+	es, err := storage.LoadAuditLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range es[:2] {
+		if !e.Redacted || e.RecordName != "" || e.Actor != forgottenActor || e.RecordID != "a" {
+			t.Errorf("entries of the forgotten chat should be redacted, got %+v", e)
+		}
+		for _, c := range e.Changes {
+			if c.Field == "" || c.Before != "" || c.After != "" {
+				t.Errorf("only field names should remain, got %+v", c)
+			}
+		}
+	}
+	if es[2].RecordName != "Bob" || es[2].Actor != "admin" || es[2].Changes[0] != (models.FieldChange{Field: "links", Before: "-100", After: "-100"}) {
+		t.Errorf("only the link to the forgotten chat should be removed from other records, got %+v", es[2])
+	}
+
+	if n, err := Forget(1, nil); err != nil || n != 0 {
+		t.Errorf("forgetting again should change nothing, got %d, %v", n, err)
+	}
+}
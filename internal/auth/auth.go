@@ -0,0 +1,637 @@
+// Package auth provides authentication for the web interface and API: local users with
+// bcrypt-hashed passwords and session cookies, sign-in with the Telegram Login Widget,
+// and optionally a user header set by trusted reverse proxies.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SessionCookieName is the name of the session cookie.
+	SessionCookieName = "bd_session"
+	// LoginPath is the path of the login page; it is reachable without a session.
+	LoginPath = "/login"
+	// LogoutPath is the path that ends the session.
+	LogoutPath = "/logout"
+	// defaultProxyHeader is the user header read from trusted proxies unless configured otherwise.
+	defaultProxyHeader = "X-Forwarded-User"
+	// defaultSessionTTL is how long a session lasts unless configured otherwise.
+	defaultSessionTTL = 12 * time.Hour
+)
+
+// dummyHash is compared against when the user does not exist, so that unknown users take as long to reject.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+
+// User is a local user allowed to sign in.
+type User struct {
+	// Username is the login name.
+	Username string `yaml:"username"`
+	// PasswordHash is the bcrypt hash of the password, e.g. generated with `htpasswd -bnBC 10 "" password`.
+	PasswordHash string `yaml:"password_hash"`
+}
+
+// Config configures authentication.
+type Config struct {
+	// Users are the local users.
+	Users []User
+	// TrustedProxies are the networks of reverse proxies whose user header is trusted.
+	TrustedProxies []*net.IPNet
+	// ProxyHeader is the request header carrying the user name set by trusted proxies.
+	ProxyHeader string
+	// SessionTTL is how long a session lasts after sign-in.
+	SessionTTL time.Duration
+	// TelegramBotToken enables sign-in with the Telegram Login Widget; it verifies the widget data.
+	TelegramBotToken string
+	// TelegramBotUsername is the username of the bot shown in the login widget.
+	TelegramBotUsername string
+	// ChatAdmins answers whether Telegram users administer group chats.
+	ChatAdmins ChatAdminChecker
+	// Admins are users that are always admins, identified like in role assignments.
+	Admins []string
+	// DefaultRole is the role of local and proxy users without a role assignment.
+	DefaultRole string
+}
+
+// ChatAdminChecker reports whether a Telegram user administers a chat.
+type ChatAdminChecker interface {
+	// IsChatAdmin reports whether the user may manage the chat.
+	IsChatAdmin(chatID, userID int64) bool
+}
+
+// Identity is a signed-in user.
+// A nil identity, used when authentication is not configured, may do everything.
+type Identity struct {
+	// Name is the user name shown in the interface.
+	Name string
+	// TelegramID is the Telegram user ID of users signed in with Telegram, 0 for other users.
+	TelegramID int64
+	// Role is one of models.Roles.
+	Role string
+	// ChatIDs are the chats assigned to an editor.
+	ChatIDs []int64
+
+	admins ChatAdminChecker
+}
+
+// Key returns the user as identified in role assignments: the user name,
+// or "telegram:<id>" for users signed in with Telegram.
+func (id *Identity) Key() string {
+	if id.TelegramID != 0 {
+		return "telegram:" + strconv.FormatInt(id.TelegramID, 10)
+	}
+	return id.Name
+}
+
+// IsAdmin reports whether the user may change everything, including bot settings and roles.
+func (id *Identity) IsAdmin() bool {
+	return id == nil || id.Role == models.RoleAdmin
+}
+
+// CanView reports whether the user may see the records of the chat. Telegram users who are not
+// admins only see their private chat with the bot, the groups they administer and their assigned chats.
+func (id *Identity) CanView(chatID int64) bool {
+	if id.IsAdmin() || id.TelegramID == 0 {
+		return true
+	}
+	return id.ownsChat(chatID) || id.assigned(chatID)
+}
+
+// CanEdit reports whether the user may change the records of the chat. Editors may change their
+// assigned chats; Telegram users also count their private chat and the groups they administer.
+func (id *Identity) CanEdit(chatID int64) bool {
+	if id.IsAdmin() {
+		return true
+	}
+	if id.Role != models.RoleEditor {
+		return false
+	}
+	return id.assigned(chatID) || id.ownsChat(chatID)
+}
+
+// CanEditAny reports whether the user may change the records of any chat, e.g. to add records.
+func (id *Identity) CanEditAny() bool {
+	if id.IsAdmin() {
+		return true
+	}
+	return id.Role == models.RoleEditor && (len(id.ChatIDs) > 0 || id.TelegramID != 0)
+}
+
+// assigned reports whether the chat is assigned to the user.
+func (id *Identity) assigned(chatID int64) bool {
+	for _, c := range id.ChatIDs {
+		if c == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsChat reports whether a Telegram user manages the chat: their private chat with the bot,
+// or a group they administer.
+func (id *Identity) ownsChat(chatID int64) bool {
+	if id.TelegramID == 0 || chatID == 0 {
+		return false
+	}
+	if chatID == id.TelegramID {
+		return true
+	}
+	if chatID > 0 || id.admins == nil {
+		return false
+	}
+	return id.admins.IsChatAdmin(chatID, id.TelegramID)
+}
+
+// ConfigFromEnv reads the configuration from the environment:
+//
+//	AUTH_USERS            comma-separated username:bcrypt-hash pairs
+//	AUTH_USERS_FILE       YAML file with a list of {username, password_hash} entries
+//	AUTH_TRUSTED_PROXIES  comma-separated IP addresses or CIDR networks of trusted reverse proxies
+//	AUTH_PROXY_HEADER     user header set by trusted proxies (default X-Forwarded-User)
+//	AUTH_SESSION_TTL      session lifetime as a Go duration (default 12h)
+//	AUTH_TELEGRAM_LOGIN   "true" to allow sign-in with the Telegram Login Widget of the bot in TELEGRAM_BOT_TOKEN
+//	AUTH_ADMINS           comma-separated users that are always admins (user names or telegram:<id>)
+//	AUTH_DEFAULT_ROLE     role of local and proxy users without a role assignment (default viewer)
+//
+// The username and administrator checks of the bot are not known from the environment;
+// the caller sets TelegramBotUsername and ChatAdmins once the bot is running.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{ProxyHeader: defaultProxyHeader, SessionTTL: defaultSessionTTL, DefaultRole: models.RoleViewer}
+
+	if users := os.Getenv("AUTH_USERS"); users != "" {
+		for _, entry := range strings.Split(users, ",") {
+			name, hash, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok || name == "" || hash == "" {
+				return cfg, fmt.Errorf("invalid AUTH_USERS entry %q, expected username:bcrypt-hash", entry)
+			}
+			cfg.Users = append(cfg.Users, User{Username: name, PasswordHash: hash})
+		}
+	}
+
+	if path := os.Getenv("AUTH_USERS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to read AUTH_USERS_FILE: %w", err)
+		}
+		var users []User
+		if err := yaml.Unmarshal(data, &users); err != nil {
+			return cfg, fmt.Errorf("failed to parse AUTH_USERS_FILE: %w", err)
+		}
+		cfg.Users = append(cfg.Users, users...)
+	}
+
+	for _, u := range cfg.Users {
+		if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+			return cfg, fmt.Errorf("password hash of user %q is not a bcrypt hash: %w", u.Username, err)
+		}
+	}
+
+	if proxies := os.Getenv("AUTH_TRUSTED_PROXIES"); proxies != "" {
+		for _, p := range strings.Split(proxies, ",") {
+			network, err := parseNetwork(strings.TrimSpace(p))
+			if err != nil {
+				return cfg, fmt.Errorf("invalid AUTH_TRUSTED_PROXIES entry %q: %w", p, err)
+			}
+			cfg.TrustedProxies = append(cfg.TrustedProxies, network)
+		}
+	}
+
+	if header := os.Getenv("AUTH_PROXY_HEADER"); header != "" {
+		cfg.ProxyHeader = header
+	}
+
+	if ttl := os.Getenv("AUTH_SESSION_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid AUTH_SESSION_TTL %q", ttl)
+		}
+		cfg.SessionTTL = d
+	}
+
+	for _, admin := range strings.Split(os.Getenv("AUTH_ADMINS"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" {
+			cfg.Admins = append(cfg.Admins, admin)
+		}
+	}
+
+	if role := os.Getenv("AUTH_DEFAULT_ROLE"); role != "" {
+		if !models.IsRole(role) {
+			return cfg, fmt.Errorf("invalid AUTH_DEFAULT_ROLE %q, expected one of %s", role, strings.Join(models.Roles, ", "))
+		}
+		cfg.DefaultRole = role
+	}
+
+	if enabled := os.Getenv("AUTH_TELEGRAM_LOGIN"); enabled != "" {
+		on, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid AUTH_TELEGRAM_LOGIN %q", enabled)
+		}
+		if on {
+			cfg.TelegramBotToken = os.Getenv("TELEGRAM_BOT_TOKEN")
+			if cfg.TelegramBotToken == "" {
+				return cfg, fmt.Errorf("AUTH_TELEGRAM_LOGIN requires TELEGRAM_BOT_TOKEN")
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// parseNetwork parses an IP address or CIDR network; single addresses become host networks.
+func parseNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		return network, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address")
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// session is a signed-in user.
+type session struct {
+	identity Identity
+	expires  time.Time
+}
+
+// Authenticator checks credentials and sessions and protects handlers.
+type Authenticator struct {
+	users          map[string][]byte
+	trustedProxies []*net.IPNet
+	proxyHeader    string
+	sessionTTL     time.Duration
+	telegramToken  string
+	telegramBot    string
+	admins         ChatAdminChecker
+	superUsers     map[string]bool
+	defaultRole    string
+
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+// New creates an authenticator from the configuration.
+func New(cfg Config) *Authenticator {
+	a := &Authenticator{
+		users:          make(map[string][]byte),
+		trustedProxies: cfg.TrustedProxies,
+		proxyHeader:    cfg.ProxyHeader,
+		sessionTTL:     cfg.SessionTTL,
+		telegramToken:  cfg.TelegramBotToken,
+		telegramBot:    cfg.TelegramBotUsername,
+		admins:         cfg.ChatAdmins,
+		superUsers:     make(map[string]bool),
+		defaultRole:    cfg.DefaultRole,
+		sessions:       make(map[string]session),
+	}
+	if !models.IsRole(a.defaultRole) {
+		a.defaultRole = models.RoleViewer
+	}
+	for _, admin := range cfg.Admins {
+		a.superUsers[admin] = true
+	}
+	if a.proxyHeader == "" {
+		a.proxyHeader = defaultProxyHeader
+	}
+	if a.sessionTTL <= 0 {
+		a.sessionTTL = defaultSessionTTL
+	}
+	for _, u := range cfg.Users {
+		a.users[u.Username] = []byte(u.PasswordHash)
+	}
+	return a
+}
+
+// Enabled reports whether any authentication method is configured.
+// Without one, the web interface stays open as in earlier versions.
+func (a *Authenticator) Enabled() bool {
+	return len(a.users) > 0 || len(a.trustedProxies) > 0 || a.telegramToken != ""
+}
+
+// HasLocalUsers reports whether users can sign in with a password.
+func (a *Authenticator) HasLocalUsers() bool {
+	return len(a.users) > 0
+}
+
+// TelegramBot returns the username of the bot used by the login widget, or "" if Telegram sign-in is disabled.
+func (a *Authenticator) TelegramBot() string {
+	if a.telegramToken == "" {
+		return ""
+	}
+	return a.telegramBot
+}
+
+// VerifyTelegramLogin checks the data of the Telegram Login Widget and returns the identity of the user.
+func (a *Authenticator) VerifyTelegramLogin(values url.Values) (Identity, error) {
+	if a.telegramToken == "" {
+		return Identity{}, fmt.Errorf("telegram sign-in is not enabled")
+	}
+	user, err := VerifyTelegramLogin(values, a.telegramToken, time.Now())
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{Name: user.DisplayName(), TelegramID: user.ID}, nil
+}
+
+// LocalUsers returns the names of the local users, sorted.
+func (a *Authenticator) LocalUsers() []string {
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConfiguredAdmins returns the users that are always admins through AUTH_ADMINS, sorted.
+func (a *Authenticator) ConfiguredAdmins() []string {
+	names := make([]string, 0, len(a.superUsers))
+	for name := range a.superUsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsConfiguredAdmin reports whether the user is an admin through AUTH_ADMINS; such users can't be
+// changed in the role assignments.
+func (a *Authenticator) IsConfiguredAdmin(key string) bool {
+	return a.superUsers[key]
+}
+
+// CheckAdmins returns an error if local or proxy users can sign in but none of them can become an admin:
+// AUTH_ADMINS is empty, no role assignment makes anybody an admin and the default role is not admin.
+// Such a deployment, e.g. one set up before roles existed, would make every user a viewer, and nobody
+// could open the role page to change that.
+func (a *Authenticator) CheckAdmins() error {
+	if len(a.users) == 0 && len(a.trustedProxies) == 0 {
+		return nil
+	}
+	if len(a.superUsers) > 0 || a.defaultRole == models.RoleAdmin {
+		return nil
+	}
+	assignments, err := storage.LoadRoleAssignments()
+	if err != nil {
+		return fmt.Errorf("failed to load role assignments: %w", err)
+	}
+	for _, r := range assignments {
+		if r.Role == models.RoleAdmin {
+			return nil
+		}
+	}
+	return fmt.Errorf("no admin is configured, so every user would be a viewer: set AUTH_ADMINS to the users that manage roles")
+}
+
+// assignRole sets the role of the user: admin for configured admins, otherwise the stored role
+// assignment. Without one, Telegram users are editors of their own chats and other users get the default role.
+func (a *Authenticator) assignRole(identity *Identity) {
+	identity.Role, identity.ChatIDs = a.defaultRole, nil
+	if identity.TelegramID != 0 {
+		identity.Role = models.RoleEditor
+	}
+	if a.superUsers[identity.Key()] {
+		identity.Role = models.RoleAdmin
+		return
+	}
+
+	assignments, err := storage.LoadRoleAssignments()
+	if err != nil {
+		logger.Error("AUTH", "Failed to load role assignments: %v", err)
+		identity.Role = models.RoleViewer
+		return
+	}
+	for _, ra := range assignments {
+		if ra.User == identity.Key() && models.IsRole(ra.Role) {
+			identity.Role, identity.ChatIDs = ra.Role, ra.ChatIDs
+			return
+		}
+	}
+}
+
+// CheckPassword reports whether the password is correct for the local user.
+func (a *Authenticator) CheckPassword(username, password string) bool {
+	hash, ok := a.users[username]
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// StartSession creates a session for the user and sets its cookie. The CSRF token is replaced
+// so that tokens issued before signing in can't be used with the session.
+func (a *Authenticator) StartSession(w http.ResponseWriter, r *http.Request, identity Identity) {
+	token := newToken()
+	expires := time.Now().Add(a.sessionTTL)
+	identity.admins = a.admins
+
+	a.mu.Lock()
+	a.sessions[token] = session{identity: identity, expires: expires}
+	a.pruneLocked()
+	a.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   a.isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	a.setCSRFCookie(w, r, newToken())
+}
+
+// EndSession deletes the session of the request, clears its cookie and replaces the CSRF token.
+func (a *Authenticator) EndSession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		a.mu.Lock()
+		delete(a.sessions, cookie.Value)
+		a.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	a.setCSRFCookie(w, r, newToken())
+}
+
+// pruneLocked drops expired sessions. The caller must hold a.mu.
+func (a *Authenticator) pruneLocked() {
+	now := time.Now()
+	for token, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, token)
+		}
+	}
+}
+
+// newToken returns a random session token.
+func newToken() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// isTrustedProxy reports whether the request comes directly from a trusted reverse proxy.
+func (a *Authenticator) isTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range a.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHTTPS reports whether the client connected over HTTPS, directly or through a trusted proxy.
+func (a *Authenticator) isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || (a.isTrustedProxy(r) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"))
+}
+
+// Authenticate returns the user of the request: the proxy user header of trusted proxies,
+// for API requests the HTTP Basic credentials of a local user, otherwise the user of a valid
+// session cookie. It returns nil for anonymous requests and wrong Basic credentials.
+func (a *Authenticator) Authenticate(r *http.Request) *Identity {
+	if len(a.trustedProxies) > 0 && a.isTrustedProxy(r) {
+		if user := strings.TrimSpace(r.Header.Get(a.proxyHeader)); user != "" {
+			return &Identity{Name: user}
+		}
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		if username, password, ok := r.BasicAuth(); ok {
+			if !a.CheckPassword(username, password) {
+				logger.Warn("AUTH", "Failed API sign-in for user '%s' from %s", username, r.RemoteAddr)
+				return nil
+			}
+			return &Identity{Name: username}
+		}
+	}
+
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[cookie.Value]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.expires) {
+		delete(a.sessions, cookie.Value)
+		return nil
+	}
+	identity := s.identity
+	return &identity
+}
+
+// contextKey is the type of context keys of this package.
+type contextKey struct{}
+
+// WithIdentity returns a copy of the context carrying the signed-in user.
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// WithUser returns a copy of the context carrying a signed-in admin.
+func WithUser(ctx context.Context, username string) context.Context {
+	return WithIdentity(ctx, &Identity{Name: username, Role: models.RoleAdmin})
+}
+
+// IdentityFromContext returns the signed-in user of the request context, or nil if there is none.
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(contextKey{}).(*Identity)
+	return identity
+}
+
+// UserFromContext returns the name of the signed-in user of the request context, or "" if there is none.
+func UserFromContext(ctx context.Context) string {
+	if identity := IdentityFromContext(ctx); identity != nil {
+		return identity.Name
+	}
+	return ""
+}
+
+// Middleware requires a signed-in user for every request except the login pages.
+// Anonymous API requests get 401 with a JSON error, HTMX requests are redirected to the login page
+// through the HX-Redirect header, and other requests with a 303 redirect that returns them afterwards.
+// If authentication is not configured, requests pass through unchanged.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() || r.URL.Path == LoginPath || r.URL.Path == TelegramLoginPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity := a.Authenticate(r)
+		if identity == nil {
+			loginURL := LoginPath + "?next=" + url.QueryEscape(r.URL.RequestURI())
+			switch {
+			case strings.HasPrefix(r.URL.Path, "/api/"):
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error":{"code":"unauthorized","message":"authentication required"}}`+"\n")
+			case r.Header.Get("HX-Request") == "true":
+				w.Header().Set("HX-Redirect", LoginPath)
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				http.Redirect(w, r, loginURL, http.StatusSeeOther)
+			}
+			logger.Debug("AUTH", "Anonymous request to %s from %s rejected", r.URL.Path, r.RemoteAddr)
+			return
+		}
+
+		a.assignRole(identity)
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// SafeRedirect returns target if it is a local path, otherwise "/". It prevents open redirects
+// through the next parameter of the login page.
+func SafeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
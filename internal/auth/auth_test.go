@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hashPassword(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return string(hash)
+}
+
+func protected() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + UserFromContext(r.Context())))
+	})
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	hash := hashPassword(t, "secret")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "users.yaml")
+	if err := os.WriteFile(file, []byte("- username: bob\n  password_hash: "+hash+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AUTH_USERS", "alice:"+hash)
+	t.Setenv("AUTH_USERS_FILE", file)
+	t.Setenv("AUTH_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5")
+	t.Setenv("AUTH_PROXY_HEADER", "Remote-User")
+	t.Setenv("AUTH_SESSION_TTL", "1h")
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv() error = %v", err)
+	}
+	if len(cfg.Users) != 2 || cfg.Users[0].Username != "alice" || cfg.Users[1].Username != "bob" {
+		t.Errorf("users = %+v", cfg.Users)
+	}
+	if len(cfg.TrustedProxies) != 2 || cfg.TrustedProxies[1].String() != "192.168.1.5/32" {
+		t.Errorf("trusted proxies = %v", cfg.TrustedProxies)
+	}
+	if cfg.ProxyHeader != "Remote-User" || cfg.SessionTTL.Hours() != 1 {
+		t.Errorf("proxy header = %q, session TTL = %v", cfg.ProxyHeader, cfg.SessionTTL)
+	}
+}
+
+func TestConfigFromEnvRejectsInvalidValues(t *testing.T) {
+	tests := map[string]string{
+		"AUTH_USERS":           "alice:plaintext",
+		"AUTH_TRUSTED_PROXIES": "proxy.local",
+		"AUTH_SESSION_TTL":     "forever",
+	}
+	for key, value := range tests {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := ConfigFromEnv(); err == nil {
+				t.Errorf("%s=%q should be rejected", key, value)
+			}
+		})
+	}
+}
+
+func TestMiddlewareDisabledWithoutConfiguration(t *testing.T) {
+	a := New(Config{})
+	w := httptest.NewRecorder()
+	a.Middleware(protected()).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("unconfigured authentication should let requests through, got %d", w.Code)
+	}
+}
+
+func TestMiddlewareRejectsAnonymousRequests(t *testing.T) {
+	a := New(Config{Users: []User{{Username: "alice", PasswordHash: hashPassword(t, "secret")}}})
+	handler := a.Middleware(protected())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/?tag=family", nil))
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login?next=%2F%3Ftag%3Dfamily" {
+		t.Errorf("page request: got %d to %q, want redirect to the login page", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/birthdays", nil))
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `"unauthorized"`) {
+		t.Errorf("API request: got %d %q, want 401 JSON error", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/save-row", nil)
+	req.Header.Set("HX-Request", "true")
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("HX-Redirect") != LoginPath {
+		t.Errorf("HTMX request: got %d with HX-Redirect %q", w.Code, w.Header().Get("HX-Redirect"))
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", LoginPath, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("login page should be public, got %d", w.Code)
+	}
+}
+
+func TestAPIBasicAuth(t *testing.T) {
+	a := New(Config{Users: []User{{Username: "alice", PasswordHash: hashPassword(t, "secret")}}})
+	handler := a.Middleware(protected())
+
+	request := func(target, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.SetBasicAuth("alice", password)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	if w := request("/api/v1/birthdays", "secret"); w.Code != http.StatusOK || w.Body.String() != "hello alice" {
+		t.Errorf("API request with Basic credentials: got %d %q", w.Code, w.Body.String())
+	}
+	if w := request("/api/v1/birthdays", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("API request with a wrong password: got %d, want 401", w.Code)
+	}
+	if w := request("/", "secret"); w.Code != http.StatusSeeOther {
+		t.Errorf("Basic credentials should only sign in API requests, got %d", w.Code)
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	// Authenticated requests look up role assignments next to the birthday file
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	a := New(Config{Users: []User{{Username: "alice", PasswordHash: hashPassword(t, "secret")}}})
+
+	if a.CheckPassword("alice", "wrong") || a.CheckPassword("mallory", "secret") {
+		t.Fatal("wrong credentials should be rejected")
+	}
+	if !a.CheckPassword("alice", "secret") {
+		t.Fatal("correct credentials should be accepted")
+	}
+
+	w := httptest.NewRecorder()
+	a.StartSession(w, httptest.NewRequest("POST", LoginPath, nil), Identity{Name: "alice"})
+	cookies := w.Result().Cookies()
+	if len(cookies) != 2 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("session cookie = %+v", cookies)
+	}
+	if cookies[1].Name != CSRFCookieName {
+		t.Errorf("signing in should issue a new CSRF token, got %+v", cookies[1])
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	a.Middleware(protected()).ServeHTTP(w, req)
+	if w.Body.String() != "hello alice" {
+		t.Errorf("signed-in request: got %d %q", w.Code, w.Body.String())
+	}
+
+	a.EndSession(httptest.NewRecorder(), req)
+	if identity := a.Authenticate(req); identity != nil {
+		t.Errorf("session should end on logout, still signed in as %q", identity.Name)
+	}
+}
+
+func TestProxyHeaderOnlyTrustedFromProxies(t *testing.T) {
+	proxy, _ := parseNetwork("10.0.0.1")
+	a := New(Config{TrustedProxies: []*net.IPNet{proxy}})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:51234"
+	req.Header.Set("X-Forwarded-User", "alice")
+	if identity := a.Authenticate(req); identity == nil || identity.Name != "alice" {
+		t.Errorf("trusted proxy: identity = %+v, want alice", identity)
+	}
+
+	req.RemoteAddr = "203.0.113.7:51234"
+	if identity := a.Authenticate(req); identity != nil {
+		t.Errorf("untrusted client should not set the user, got %q", identity.Name)
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := map[string]string{
+		"/?tag=family":        "/?tag=family",
+		"":                    "/",
+		"https://evil.test/":  "/",
+		"//evil.test/":        "/",
+		"/\\evil.test/":       "/",
+		"javascript:alert(1)": "/",
+	}
+	for target, want := range tests {
+		if got := SafeRedirect(target); got != want {
+			t.Errorf("SafeRedirect(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+type fakeChatAdmins map[int64]int64
+
+func (f fakeChatAdmins) IsChatAdmin(chatID, userID int64) bool {
+	return f[chatID] == userID
+}
+
+func TestIdentityPermissions(t *testing.T) {
+	var anonymous *Identity
+	if !anonymous.IsAdmin() || !anonymous.CanEdit(-100) {
+		t.Error("without authentication everything should be allowed")
+	}
+
+	admins := fakeChatAdmins{-100: 42, -200: 7}
+	viewer := &Identity{Name: "alice", Role: models.RoleViewer}
+	editor := &Identity{Name: "carol", Role: models.RoleEditor, ChatIDs: []int64{-300}}
+	telegram := &Identity{Name: "@bob", TelegramID: 42, Role: models.RoleEditor, admins: admins}
+	telegramViewer := &Identity{Name: "@bob", TelegramID: 42, Role: models.RoleViewer, admins: admins}
+	admin := &Identity{Name: "root", Role: models.RoleAdmin}
+
+	tests := []struct {
+		name     string
+		identity *Identity
+		chatID   int64
+		view     bool
+		edit     bool
+	}{
+		{"viewer sees every chat", viewer, -100, true, false},
+		{"editor edits assigned chat", editor, -300, true, true},
+		{"editor sees other chats read-only", editor, -100, true, false},
+		{"telegram user edits own chat", telegram, 42, true, true},
+		{"telegram user edits administered group", telegram, -100, true, true},
+		{"telegram user can't see other groups", telegram, -200, false, false},
+		{"telegram user can't see other private chats", telegram, 7, false, false},
+		{"telegram user can't see records without chat", telegram, 0, false, false},
+		{"telegram viewer sees own chat read-only", telegramViewer, 42, true, false},
+		{"admin edits everything", admin, -200, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.identity.CanView(tt.chatID); got != tt.view {
+				t.Errorf("CanView(%d) = %t, want %t", tt.chatID, got, tt.view)
+			}
+			if got := tt.identity.CanEdit(tt.chatID); got != tt.edit {
+				t.Errorf("CanEdit(%d) = %t, want %t", tt.chatID, got, tt.edit)
+			}
+		})
+	}
+
+	if viewer.CanEditAny() || !editor.CanEditAny() || !telegram.CanEditAny() {
+		t.Error("only editors and admins may add records")
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	if err := storage.SaveRoleAssignments([]models.RoleAssignment{
+		{User: "carol", Role: models.RoleEditor, ChatIDs: []int64{-300}},
+		{User: "telegram:7", Role: models.RoleViewer},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	a := New(Config{Admins: []string{"root"}, DefaultRole: models.RoleViewer})
+	tests := []struct {
+		identity Identity
+		want     string
+	}{
+		{Identity{Name: "root"}, models.RoleAdmin},
+		{Identity{Name: "carol"}, models.RoleEditor},
+		{Identity{Name: "dave"}, models.RoleViewer},
+		{Identity{Name: "@bob", TelegramID: 42}, models.RoleEditor},
+		{Identity{Name: "@eve", TelegramID: 7}, models.RoleViewer},
+	}
+	for _, tt := range tests {
+		identity := tt.identity
+		a.assignRole(&identity)
+		if identity.Role != tt.want {
+			t.Errorf("role of %s = %q, want %q", identity.Key(), identity.Role, tt.want)
+		}
+	}
+}
+
+func TestCheckAdmins(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	users := []User{{Username: "alice", PasswordHash: "hash"}}
+
+	if err := New(Config{}).CheckAdmins(); err != nil {
+		t.Errorf("without sign-in no admin is needed: %v", err)
+	}
+	if err := New(Config{TelegramBotToken: "123:abc"}).CheckAdmins(); err != nil {
+		t.Errorf("Telegram users edit their own chats without an admin: %v", err)
+	}
+	if err := New(Config{Users: users}).CheckAdmins(); err == nil || !strings.Contains(err.Error(), "AUTH_ADMINS") {
+		t.Errorf("local users without any admin should be rejected, got %v", err)
+	}
+	if err := New(Config{Users: users, Admins: []string{"alice"}}).CheckAdmins(); err != nil {
+		t.Errorf("AUTH_ADMINS should be enough: %v", err)
+	}
+	if err := New(Config{Users: users, DefaultRole: models.RoleAdmin}).CheckAdmins(); err != nil {
+		t.Errorf("admin as default role should be enough: %v", err)
+	}
+	if err := storage.SetRoleAssignment(models.RoleAssignment{User: "alice", Role: models.RoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(Config{Users: users}).CheckAdmins(); err != nil {
+		t.Errorf("a stored admin assignment should be enough: %v", err)
+	}
+}
+
+func TestTelegramSessionKeepsAdminChecks(t *testing.T) {
+	a := New(Config{TelegramBotToken: "123:abc", ChatAdmins: fakeChatAdmins{-100: 42}})
+	w := httptest.NewRecorder()
+	a.StartSession(w, httptest.NewRequest("GET", TelegramLoginPath, nil), Identity{Name: "@bob", TelegramID: 42})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(w.Result().Cookies()[0])
+	identity := a.Authenticate(req)
+	if identity == nil {
+		t.Fatal("session should authenticate the Telegram user")
+	}
+	identity.Role = models.RoleEditor
+	if !identity.CanEdit(-100) || identity.CanEdit(-200) {
+		t.Errorf("session identity = %+v, should edit only its own chats", identity)
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"5mdt/bd_bot/internal/logger"
+)
+
+const (
+	// CSRFCookieName is the name of the cookie holding the CSRF token of the browser session.
+	CSRFCookieName = "bd_csrf"
+	// CSRFHeader is the request header HTMX requests send the CSRF token in.
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFField is the form field plain HTML forms send the CSRF token in.
+	CSRFField = "csrf_token"
+)
+
+// csrfContextKey is the context key of the CSRF token.
+type csrfContextKey struct{}
+
+// CSRFToken returns the CSRF token of the request context, to be embedded in forms.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfContextKey{}).(string)
+	return token
+}
+
+// WithCSRFToken returns a copy of the context carrying the CSRF token.
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfContextKey{}, token)
+}
+
+// isSafeMethod reports whether the method does not change data.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// setCSRFCookie issues a new CSRF token for the browser session. Like the session cookie, it is
+// only marked Secure for HTTPS connections, directly or through a trusted proxy.
+func (a *Authenticator) setCSRFCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   a.isHTTPS(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// sameOrigin reports whether the Origin header, or the Referer header if there is no Origin,
+// names the host the request was sent to. Requests with neither header pass; browsers send
+// at least one of them with cross-site form submissions.
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" || source == "null" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return r.Header.Get("Origin") != "null"
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+// needsCSRFToken reports whether an unsafe request must carry the CSRF token. JSON API requests
+// with a JSON body or a method other than POST can't be sent cross-site without a CORS preflight,
+// which this server never allows, so API clients don't need a token.
+func needsCSRFToken(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+	if r.Method != http.MethodPost {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType != "application/json"
+}
+
+// CSRFProtect protects state-changing requests against cross-site request forgery.
+// Every browser session gets a random token in an HttpOnly cookie that templates embed in their
+// forms; unsafe requests must send it back in the X-CSRF-Token header or the csrf_token form field,
+// and must come from the same origin according to their Origin or Referer header.
+func (a *Authenticator) CSRFProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := ""
+		if cookie, err := r.Cookie(CSRFCookieName); err == nil && len(cookie.Value) == 64 {
+			token = cookie.Value
+		}
+
+		if !isSafeMethod(r.Method) {
+			if !sameOrigin(r) {
+				logger.Warn("AUTH", "Rejected cross-origin %s %s from %s (Origin %q, Referer %q)",
+					r.Method, r.URL.Path, r.RemoteAddr, r.Header.Get("Origin"), r.Header.Get("Referer"))
+				http.Error(w, "Forbidden: cross-origin request", http.StatusForbidden)
+				return
+			}
+			if needsCSRFToken(r) {
+				sent := r.Header.Get(CSRFHeader)
+				if sent == "" {
+					sent = r.PostFormValue(CSRFField)
+				}
+				if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+					logger.Warn("AUTH", "Rejected %s %s from %s: missing or invalid CSRF token", r.Method, r.URL.Path, r.RemoteAddr)
+					http.Error(w, "Forbidden: invalid CSRF token", http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		if token == "" {
+			token = newToken()
+			a.setCSRFCookie(w, r, token)
+		}
+		next.ServeHTTP(w, r.WithContext(WithCSRFToken(r.Context(), token)))
+	})
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// csrfRequest returns a same-origin request carrying the CSRF cookie.
+func csrfRequest(method, target, body, token string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Origin", "http://example.com")
+	req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: token})
+	if body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return req
+}
+
+func TestCSRFProtectIssuesToken(t *testing.T) {
+	var seen string
+	handler := New(Config{}).CSRFProtect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = CSRFToken(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CSRFCookieName || !cookies[0].HttpOnly {
+		t.Fatalf("CSRF cookie = %+v", cookies)
+	}
+	if seen != cookies[0].Value || len(seen) != 64 {
+		t.Errorf("handler token %q should match the cookie", seen)
+	}
+
+	// The token is kept for the rest of the browser session
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, csrfRequest("GET", "/", "", seen))
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("an existing token should not be replaced")
+	}
+}
+
+func TestCSRFCookieSecureOnlyThroughTrustedProxies(t *testing.T) {
+	_, proxy, _ := net.ParseCIDR("10.0.0.0/8")
+	handler := New(Config{TrustedProxies: []*net.IPNet{proxy}}).CSRFProtect(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, tt := range []struct {
+		remoteAddr string
+		want       bool
+	}{
+		{"10.0.0.1:1234", true},
+		{"192.0.2.1:1234", false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Secure != tt.want {
+			t.Errorf("request from %s: CSRF cookie = %+v, want Secure %t", tt.remoteAddr, cookies, tt.want)
+		}
+	}
+}
+
+func TestCSRFProtectValidatesUnsafeRequests(t *testing.T) {
+	token := strings.Repeat("ab", 32)
+	handler := New(Config{}).CSRFProtect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	headerRequest := csrfRequest("POST", "/save-row", "idx=0", token)
+	headerRequest.Header.Set(CSRFHeader, token)
+
+	crossOrigin := csrfRequest("POST", "/save-row", "idx=0&csrf_token="+token, token)
+	crossOrigin.Header.Set("Origin", "https://evil.test")
+
+	crossReferer := csrfRequest("POST", "/save-row", "idx=0&csrf_token="+token, token)
+	crossReferer.Header.Del("Origin")
+	crossReferer.Header.Set("Referer", "https://evil.test/page")
+
+	apiJSON := csrfRequest("POST", "/api/v1/birthdays", "", token)
+	apiJSON.Header.Set("Content-Type", "application/json")
+
+	apiForm := csrfRequest("POST", "/api/v1/birthdays", url.Values{"name": {"x"}}.Encode(), token)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"token in header", headerRequest, http.StatusOK},
+		{"token in form", csrfRequest("POST", "/logout", "csrf_token="+token, token), http.StatusOK},
+		{"missing token", csrfRequest("POST", "/delete-row", "idx=0", token), http.StatusForbidden},
+		{"wrong token", csrfRequest("POST", "/delete-row", "idx=0&csrf_token="+strings.Repeat("cd", 32), token), http.StatusForbidden},
+		{"no cookie", csrfRequest("POST", "/delete-row", "idx=0&csrf_token=", ""), http.StatusForbidden},
+		{"cross-origin", crossOrigin, http.StatusForbidden},
+		{"cross-site referer", crossReferer, http.StatusForbidden},
+		{"JSON API request", apiJSON, http.StatusOK},
+		{"API delete", csrfRequest("DELETE", "/api/v1/birthdays/1", "", ""), http.StatusOK},
+		{"form post to API", apiForm, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramLoginPath is the path the Telegram Login Widget redirects to after the user confirms.
+const TelegramLoginPath = "/login/telegram"
+
+// maxTelegramAuthAge is how old the data of the login widget may be before it is rejected.
+const maxTelegramAuthAge = 24 * time.Hour
+
+var (
+	errTelegramHash    = errors.New("telegram login data has an invalid hash")
+	errTelegramExpired = errors.New("telegram login data has expired")
+)
+
+// TelegramUser is the user confirmed by the Telegram Login Widget.
+type TelegramUser struct {
+	// ID is the Telegram user ID, which is also the ID of the user's private chat with the bot.
+	ID int64
+	// FirstName is the user's first name.
+	FirstName string
+	// LastName is the user's last name, if set.
+	LastName string
+	// Username is the user's Telegram username without @, if set.
+	Username string
+}
+
+// DisplayName returns @username if the user has one, otherwise the full name.
+func (u TelegramUser) DisplayName() string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// VerifyTelegramLogin checks the data sent by the Telegram Login Widget: the hash must be the
+// HMAC-SHA256 of the other fields, keyed with the SHA-256 of the bot token, and the data must be
+// at most a day old. The next parameter of the login page is not part of the signed data.
+func VerifyTelegramLogin(values url.Values, botToken string, now time.Time) (TelegramUser, error) {
+	var keys []string
+	for key := range values {
+		if key != "hash" && key != "next" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = key + "=" + values.Get(key)
+	}
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	want := mac.Sum(nil)
+
+	got, err := hex.DecodeString(values.Get("hash"))
+	if err != nil || !hmac.Equal(got, want) {
+		return TelegramUser{}, errTelegramHash
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil || now.Sub(time.Unix(authDate, 0)) > maxTelegramAuthAge {
+		return TelegramUser{}, errTelegramExpired
+	}
+
+	id, err := strconv.ParseInt(values.Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return TelegramUser{}, errTelegramHash
+	}
+	return TelegramUser{
+		ID:        id,
+		FirstName: values.Get("first_name"),
+		LastName:  values.Get("last_name"),
+		Username:  values.Get("username"),
+	}, nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signTelegramLogin signs the widget data like Telegram does.
+func signTelegramLogin(values url.Values, botToken string) url.Values {
+	var lines []string
+	for key := range values {
+		lines = append(lines, key+"="+values.Get(key))
+	}
+	sort.Strings(lines)
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+
+	signed := url.Values{}
+	for key := range values {
+		signed.Set(key, values.Get(key))
+	}
+	signed.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return signed
+}
+
+func TestVerifyTelegramLogin(t *testing.T) {
+	const token = "123456:secret"
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := url.Values{
+		"id":         {"42"},
+		"first_name": {"Bob"},
+		"username":   {"bob"},
+		"auth_date":  {strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)},
+	}
+
+	signed := signTelegramLogin(data, token)
+	signed.Set("next", "/?tag=family")
+	user, err := VerifyTelegramLogin(signed, token, now)
+	if err != nil {
+		t.Fatalf("VerifyTelegramLogin() error = %v", err)
+	}
+	if user.ID != 42 || user.DisplayName() != "@bob" {
+		t.Errorf("user = %+v", user)
+	}
+
+	if _, err := VerifyTelegramLogin(signed, "654321:other", now); err != errTelegramHash {
+		t.Errorf("wrong bot token: error = %v, want %v", err, errTelegramHash)
+	}
+
+	tampered := signTelegramLogin(data, token)
+	tampered.Set("id", "7")
+	if _, err := VerifyTelegramLogin(tampered, token, now); err != errTelegramHash {
+		t.Errorf("tampered data: error = %v, want %v", err, errTelegramHash)
+	}
+
+	if _, err := VerifyTelegramLogin(signed, token, now.Add(48*time.Hour)); err != errTelegramExpired {
+		t.Errorf("old data: error = %v, want %v", err, errTelegramExpired)
+	}
+}
@@ -4,15 +4,22 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/i18n"
 	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/metrics"
 	"5mdt/bd_bot/internal/models"
 	"5mdt/bd_bot/internal/storage"
 
@@ -25,6 +32,9 @@ var (
 	dateRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
 )
 
+// errNoChange aborts a storage update that would not change anything, so that nothing is written.
+var errNoChange = errors.New("nothing to change")
+
 // Bot represents a Telegram bot instance that manages birthday notifications.
 type Bot struct {
 	// api is the Telegram Bot API client.
@@ -37,8 +47,14 @@ type Bot struct {
 	firstName string
 	// startTime is the bot startup timestamp.
 	startTime time.Time
-	// notificationsSent is the counter of birthday notifications sent.
-	notificationsSent int64
+	// stats holds the lifetime statistics of the bot, kept in the stats file.
+	stats models.BotStats
+	// persistStats indicates whether stats are saved; it is false if the stats file could not be loaded.
+	persistStats bool
+	// statsSaveMu serializes writes of the stats file, which happen outside mu.
+	statsSaveMu sync.Mutex
+	// lastCheck is when the last birthday check pass completed.
+	lastCheck time.Time
 	// notificationStartHour is the start hour for notifications (0-23, UTC).
 	notificationStartHour int
 	// notificationEndHour is the end hour for notifications (0-23, UTC).
@@ -51,6 +67,16 @@ type Bot struct {
 	ctx context.Context
 	// cancel is the function to cancel the bot's context.
 	cancel context.CancelFunc
+	// wg tracks the run and checkBirthdays goroutines so that shutdown can wait for them.
+	wg sync.WaitGroup
+	// commands is the registry of supported bot commands.
+	commands *commandRegistry
+	// superAdmins is the set of Telegram user IDs allowed to manage any chat.
+	superAdmins map[int64]bool
+	// adminCache caches chat administrator lists for permission checks.
+	adminCache *adminCache
+	// conversations holds the state of interactive date entry conversations per chat.
+	conversations *conversationStore
 }
 
 // New creates and initializes a new Telegram bot instance with the given token.
@@ -61,7 +87,7 @@ func New(token string) (*Bot, error) {
 		return nil, fmt.Errorf("telegram bot token is required")
 	}
 
-	api, err := tgbotapi.NewBotAPI(token)
+	api, err := tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, &instrumentedClient{client: &http.Client{}})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
@@ -104,12 +130,19 @@ func New(token string) (*Bot, error) {
 		notificationEndHour:   notificationEndHour,
 		ctx:                   ctx,
 		cancel:                cancel,
+		commands:              defaultCommands(),
+		superAdmins:           superAdminsFromEnv(),
+		adminCache:            newAdminCache(),
+		conversations:         newConversationStore(),
 	}
 
+	bot.loadStats()
+
 	logger.Info("BOT", "Bot initialized successfully")
 	logger.Info("BOT", "Username: @%s", me.UserName)
 	logger.Info("BOT", "Display Name: %s", me.FirstName)
 	logger.Info("BOT", "Notification hours: %02d:00 - %02d:00 UTC", notificationStartHour, notificationEndHour)
+	logger.Info("BOT", "Super-admins configured: %d", len(bot.superAdmins))
 	return bot, nil
 }
 
@@ -126,13 +159,38 @@ func (b *Bot) Start() {
 	}
 
 	b.running = true
-	go b.run()
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.run()
+	}()
 }
 
 // Stop gracefully shuts down the bot by canceling its context and updating its status.
+// It waits until the update loop and the birthday checker have finished their current iteration.
 func (b *Bot) Stop() {
+	_ = b.Shutdown(context.Background())
+}
+
+// Shutdown cancels the bot's context and waits until the update loop and the birthday checker
+// have finished their current iteration, or until ctx is done, in which case it returns ctx.Err().
+func (b *Bot) Shutdown(ctx context.Context) error {
 	b.cancel()
-	b.setStatus("stopped")
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		b.setStatus("stopped")
+		return nil
+	case <-ctx.Done():
+		logger.Warn("BOT", "Shutdown timed out while waiting for the bot to finish")
+		return ctx.Err()
+	}
 }
 
 // GetStatus returns the current bot status (e.g., "running", "stopped", "not configured").
@@ -179,7 +237,7 @@ func (b *Bot) GetUptime() time.Duration {
 	return time.Since(b.startTime)
 }
 
-// GetNotificationsSent returns the total number of birthday notifications sent by the bot.
+// GetNotificationsSent returns the total number of notifications sent by the bot, across restarts.
 // Returns 0 if the bot is nil.
 func (b *Bot) GetNotificationsSent() int64 {
 	if b == nil {
@@ -187,7 +245,7 @@ func (b *Bot) GetNotificationsSent() int64 {
 	}
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.notificationsSent
+	return b.stats.NotificationsSent
 }
 
 // GetNotificationHours returns the configured start and end hours (UTC) for sending notifications.
@@ -201,6 +259,24 @@ func (b *Bot) GetNotificationHours() (int, int) {
 	return b.notificationStartHour, b.notificationEndHour
 }
 
+// GetLastCheck returns when the last birthday check pass completed, or the zero time if none has yet.
+// Returns the zero time if the bot is nil.
+func (b *Bot) GetLastCheck() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.lastCheck
+}
+
+// markChecked records that a birthday check pass completed.
+func (b *Bot) markChecked() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastCheck = time.Now()
+}
+
 func (b *Bot) setStatus(status string) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -211,6 +287,8 @@ func (b *Bot) setStatus(status string) {
 func (b *Bot) run() {
 	b.setStatus("connecting")
 
+	b.publishCommands()
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
@@ -218,7 +296,11 @@ func (b *Bot) run() {
 	b.setStatus("running")
 
 	// Start birthday checker
-	go b.checkBirthdays()
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.checkBirthdays()
+	}()
 
 	for {
 		select {
@@ -230,7 +312,9 @@ func (b *Bot) run() {
 			b.setStatus("stopped")
 			return
 		case update := <-updates:
-			if update.Message != nil {
+			if update.CallbackQuery != nil {
+				b.handleCallbackQuery(update.CallbackQuery)
+			} else if update.Message != nil {
 				// Check if bot was added to a group
 				if update.Message.NewChatMembers != nil {
 					for _, member := range update.Message.NewChatMembers {
@@ -269,43 +353,46 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 	}
 
 	// For private chats, send help prompt
-	msg := tgbotapi.NewMessage(message.Chat.ID, "Hello! Send /help to see available commands.")
+	msg := tgbotapi.NewMessage(message.Chat.ID, i18n.T(languageFor(message.Chat, message.From), "bot.hello"))
 	if _, err := b.api.Send(msg); err != nil {
 		logger.Error("BOT", "Failed to send message: %v", err)
 	}
 }
 
 func (b *Bot) handleCommand(message *tgbotapi.Message) {
-	command := message.Command()
-	args := message.CommandArguments()
-
-	switch command {
-	case "start":
-		b.handleStartCommand(message)
-	case "help":
-		b.handleHelpCommand(message)
-	case "update_birth_date":
-		b.handleUpdateBirthDateCommand(message, args)
-	case "my_info":
-		b.handleMyInfoCommand(message)
-	default:
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Unknown command. Send /help for available commands.")
+	lang := languageFor(message.Chat, message.From)
+
+	cmd, ok := b.commands.lookup(message.Command())
+	if !ok {
+		msg := tgbotapi.NewMessage(message.Chat.ID, i18n.T(lang, "bot.unknown_command"))
 		if _, err := b.api.Send(msg); err != nil {
 			logger.Error("BOT", "Failed to send message: %v", err)
 		}
+		return
 	}
-}
-
-func (b *Bot) handleStartCommand(message *tgbotapi.Message) {
-	welcomeText := `Hi, I am Jeeves bot. I can send you notifications about birthdays. Send me a message like:
 
-/update_birth_date 1999-12-31
+	if cmd.scope&scopeOf(message.Chat) == 0 {
+		text := i18n.T(lang, "bot.group_only")
+		if cmd.scope == scopePrivate {
+			text = i18n.T(lang, "bot.private_only")
+		}
+		msg := tgbotapi.NewMessage(message.Chat.ID, text)
+		if _, err := b.api.Send(msg); err != nil {
+			logger.Error("BOT", "Failed to send message: %v", err)
+		}
+		return
+	}
 
-to configure your birthdate.
+	if !b.hasPermission(cmd, message) {
+		b.denyPermission(cmd, message)
+		return
+	}
 
-Note: Only one birth date can be configured per chat.
+	cmd.handler(b, message, message.CommandArguments())
+}
 
-Use /help to see all available commands.`
+func (b *Bot) handleStartCommand(message *tgbotapi.Message) {
+	welcomeText := i18n.T(languageFor(message.Chat, message.From), "bot.welcome")
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, welcomeText)
 	if _, err := b.api.Send(msg); err != nil {
@@ -314,18 +401,7 @@ Use /help to see all available commands.`
 }
 
 func (b *Bot) handleHelpCommand(message *tgbotapi.Message) {
-	helpText := `Available commands:
-
-/start - Welcome message and getting started
-/help - Show this help message
-/update_birth_date - Set your birth date
-  • YYYY-MM-DD format (e.g., /update_birth_date 1999-12-31)
-  • MM-DD format (e.g., /update_birth_date 12-31) - year unknown
-/my_info - Show your current information
-
-Note: Commands work with or without the bot username (e.g., both /help and /help@bot_name work)
-
-The bot will send you birthday greetings on your special day! 🎉`
+	helpText := b.commands.helpText(scopeOf(message.Chat), languageFor(message.Chat, message.From))
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, helpText)
 	if _, err := b.api.Send(msg); err != nil {
@@ -333,154 +409,199 @@ The bot will send you birthday greetings on your special day! 🎉`
 	}
 }
 
+// messageActor returns the audit actor of a change requested by the message.
+func messageActor(message *tgbotapi.Message) string {
+	if message.From == nil {
+		return ""
+	}
+	return audit.TelegramActor(message.From.ID)
+}
+
 // resolveChatName determines the appropriate display name for a chat.
 // For group chats, it returns the group title. For private chats, it returns
 // the user's full name (first + last) or username. Falls back to "Unknown" if
 // no name information is available.
 func resolveChatName(message *tgbotapi.Message) string {
+	return resolveChatNameFor(message.Chat, message.From)
+}
+
+// resolveChatNameFor determines the display name for a chat on behalf of the given user.
+// It is used where no user message is available, e.g. for callback queries.
+func resolveChatNameFor(chat *tgbotapi.Chat, from *tgbotapi.User) string {
 	chatName := "Unknown"
-	if message.Chat.Type == "group" || message.Chat.Type == "supergroup" {
+	if chat.Type == "group" || chat.Type == "supergroup" {
 		// For group chats, use the group name
-		if message.Chat.Title != "" {
-			chatName = message.Chat.Title
+		if chat.Title != "" {
+			chatName = chat.Title
 		}
-	} else {
+	} else if from != nil {
 		// For private chats, use user's name
-		if message.From.FirstName != "" {
-			chatName = message.From.FirstName
-			if message.From.LastName != "" {
-				chatName += " " + message.From.LastName
+		if from.FirstName != "" {
+			chatName = from.FirstName
+			if from.LastName != "" {
+				chatName += " " + from.LastName
 			}
-		} else if message.From.UserName != "" {
-			chatName = message.From.UserName
+		} else if from.UserName != "" {
+			chatName = from.UserName
 		}
 	}
 
 	// Final fallback to chat title if still unknown
-	if chatName == "Unknown" && message.Chat.Title != "" {
-		chatName = message.Chat.Title
+	if chatName == "Unknown" && chat.Title != "" {
+		chatName = chat.Title
 	}
 
 	return chatName
 }
 
-func (b *Bot) handleUpdateBirthDateCommand(message *tgbotapi.Message, args string) {
-	if args == "" {
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Please provide a birth date. Example: /update_birth_date 1999-12-31")
-		if _, err := b.api.Send(msg); err != nil {
-			logger.Error("BOT", "Failed to send message: %v", err)
-		}
-		return
-	}
-
+// validateBirthDate checks a birth date in YYYY-MM-DD or MM-DD format and returns it in
+// storage format, where MM-DD is converted to 0000-MM-DD (year unknown).
+// Errors are of type userError and name the catalog message to reply with.
+func validateBirthDate(input string) (string, error) {
 	// Handle MM-DD format by converting to 0000-MM-DD (year unknown)
-	if mmddRegex.MatchString(args) {
+	if mmddRegex.MatchString(input) {
 		// Validate the MM-DD date
-		_, err := time.Parse("01-02", args)
-		if err != nil {
-			msg := tgbotapi.NewMessage(message.Chat.ID, "Invalid date. Please use a valid MM-DD format (e.g., 12-31)")
-			if _, err := b.api.Send(msg); err != nil {
-				logger.Error("BOT", "Failed to send message: %v", err)
-			}
-			return
+		if _, err := time.Parse("01-02", input); err != nil {
+			return "", userError("date.invalid_mmdd")
 		}
 		// Convert MM-DD to 0000-MM-DD format
-		args = "0000-" + args
+		input = "0000-" + input
 	}
 
 	// Validate date format (YYYY-MM-DD)
-	if !dateRegex.MatchString(args) {
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Invalid date format. Please use YYYY-MM-DD format (e.g., 1999-12-31)")
-		if _, err := b.api.Send(msg); err != nil {
-			logger.Error("BOT", "Failed to send message: %v", err)
-		}
-		return
+	if !dateRegex.MatchString(input) {
+		return "", userError("date.invalid_format")
 	}
 
 	// Parse and validate the date
-	_, err := time.Parse("2006-01-02", args)
+	parsed, err := time.Parse("2006-01-02", input)
 	if err != nil {
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Invalid date. Please use a valid date in YYYY-MM-DD format.")
-		if _, err := b.api.Send(msg); err != nil {
-			logger.Error("BOT", "Failed to send message: %v", err)
-		}
-		return
+		return "", userError("date.invalid")
+	}
+	if !dateparse.ValidYear(parsed.Year()) {
+		return "", userError("date.year_out_of_range")
 	}
 
-	// Get chat name using helper function
-	chatName := resolveChatName(message)
+	return input, nil
+}
 
-	// Load existing birthdays
-	birthdays, err := storage.LoadBirthdays()
-	if err != nil {
-		logger.Error("STORAGE", "Failed to load birthdays: %v", err)
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Sorry, there was an error accessing the database.")
-		if _, err := b.api.Send(msg); err != nil {
-			logger.Error("BOT", "Failed to send error message: %v", err)
+// birthDateConfirmation returns the reply confirming that the birth date was stored.
+func birthDateConfirmation(lang, date string) string {
+	if strings.HasPrefix(date, "0000-") {
+		// MM-DD format was converted to 0000-MM-DD
+		return i18n.T(lang, "date.saved_year_unknown", strings.TrimPrefix(date, "0000-"))
+	}
+	return i18n.T(lang, "date.saved", date)
+}
+
+// storeBirthDate creates or updates the birthday entry of the chat with a validated birth date
+// on behalf of the actor.
+func storeBirthDate(chatID int64, chatName string, date string, actor string) error {
+	// Find existing birthday entry by chat ID; other events of the chat are kept as they are
+	found := -1
+	var before models.Birthday
+	var saved []models.Birthday
+	err := storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		for i := range birthdays {
+			if birthdays[i].ChatID == chatID && birthdays[i].IsBirthday() {
+				// Update existing entry
+				before = birthdays[i].Clone()
+				birthdays[i].BirthDate = date
+				birthdays[i].Name = chatName
+				birthdays[i].LastNotification = time.Time{} // Reset notification
+				found = i
+				break
+			}
+		}
+
+		if found < 0 {
+			// Add new birthday entry
+			birthdays = append(birthdays, models.Birthday{
+				Name:             chatName,
+				BirthDate:        date,
+				LastNotification: time.Time{}, // Zero value (null)
+				ChatID:           chatID,
+			})
 		}
+		saved = birthdays
+		return birthdays, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save birthdays: %w", err)
+	}
+
+	// The saved records hold the ID assigned to a new record
+	if found < 0 {
+		logger.Info("BOT", "Added new birthday for %s (Chat ID: %d): %s", chatName, chatID, date)
+		audit.Created(actor, models.SourceBot, saved[len(saved)-1])
+	} else {
+		logger.Info("BOT", "Updated birthday for %s (Chat ID: %d): %s -> %s", chatName, chatID, before.BirthDate, date)
+		audit.Updated(actor, models.SourceBot, before, saved[found])
+	}
+	return nil
+}
+
+func (b *Bot) handleUpdateBirthDateCommand(message *tgbotapi.Message, args string) {
+	if args == "" {
+		// No date given, let the user pick it with an inline keyboard
+		b.startDateConversation(message)
 		return
 	}
 
-	// Find existing birthday entry by chat ID
-	found := false
-	for i := range birthdays {
-		if birthdays[i].ChatID == message.Chat.ID {
-			// Update existing entry
-			oldDate := birthdays[i].BirthDate
-			birthdays[i].BirthDate = args
-			birthdays[i].Name = chatName
-			birthdays[i].LastNotification = time.Time{} // Reset notification
-			found = true
+	lang := languageFor(message.Chat, message.From)
 
-			logger.Info("BOT", "Updated birthday for %s (Chat ID: %d): %s -> %s", chatName, message.Chat.ID, oldDate, args)
-			break
+	// Dates in the documented YYYY-MM-DD and MM-DD formats are stored right away
+	if mmddRegex.MatchString(args) || dateRegex.MatchString(args) {
+		date, err := validateBirthDate(args)
+		if err != nil {
+			b.sendText(message.Chat.ID, i18n.T(lang, err.Error()))
+			return
 		}
-	}
 
-	if !found {
-		// Add new birthday entry
-		newBirthday := models.Birthday{
-			Name:             chatName,
-			BirthDate:        args,
-			LastNotification: time.Time{}, // Zero value (null)
-			ChatID:           message.Chat.ID,
+		if err := storeBirthDate(message.Chat.ID, resolveChatName(message), date, messageActor(message)); err != nil {
+			logger.Error("STORAGE", "Failed to store birth date: %v", err)
+			b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_save"))
+			return
 		}
-		birthdays = append(birthdays, newBirthday)
-		logger.Info("BOT", "Added new birthday for %s (Chat ID: %d): %s", chatName, message.Chat.ID, args)
+
+		b.sendText(message.Chat.ID, birthDateConfirmation(lang, date))
+		return
 	}
 
-	// Save updated birthdays
-	if err := storage.SaveBirthdays(birthdays); err != nil {
-		logger.Error("STORAGE", "Failed to save birthdays: %v", err)
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Sorry, there was an error saving your information.")
-		if _, err := b.api.Send(msg); err != nil {
-			logger.Error("BOT", "Failed to send error message: %v", err)
+	// Anything else is parsed leniently and confirmed by the user before saving
+	result, err := dateparse.Parse(args, dateparse.OrderForLocale(dateLocale(message.Chat, message.From)))
+	if err != nil {
+		if errors.Is(err, dateparse.ErrInvalidDate) {
+			b.sendText(message.Chat.ID, i18n.T(lang, "date.invalid_day_month"))
+			return
+		}
+		if errors.Is(err, dateparse.ErrYearOutOfRange) {
+			b.sendText(message.Chat.ID, i18n.T(lang, "date.year_out_of_range"))
+			return
 		}
+		b.sendText(message.Chat.ID, i18n.T(lang, "date.unrecognized"))
 		return
 	}
 
-	// Send confirmation
-	var responseText string
-	if strings.HasPrefix(args, "0000-") {
-		// MM-DD format was converted to 0000-MM-DD
-		mmdd := strings.TrimPrefix(args, "0000-")
-		responseText = fmt.Sprintf("✅ Your birth date has been set to %s (year unknown)!\n\nI'll send you birthday greetings every %s! 🎉", mmdd, mmdd)
-	} else {
-		responseText = fmt.Sprintf("✅ Your birth date has been set to %s!\n\nI'll send you birthday greetings on your special day! 🎉", args)
-	}
+	b.askBirthDateConfirmation(message, result, lang)
+}
 
-	msg := tgbotapi.NewMessage(message.Chat.ID, responseText)
+// sendText sends a plain text message to the chat and logs delivery failures.
+func (b *Bot) sendText(chatID int64, text string) {
+	msg := tgbotapi.NewMessage(chatID, text)
 	if _, err := b.api.Send(msg); err != nil {
-		logger.Error("BOT", "Failed to send confirmation message: %v", err)
+		logger.Error("BOT", "Failed to send message to chat %d: %v", chatID, err)
 	}
 }
 
 func (b *Bot) handleMyInfoCommand(message *tgbotapi.Message) {
+	lang := languageFor(message.Chat, message.From)
+
 	// Load birthdays to find user's info
 	birthdays, err := storage.LoadBirthdays()
 	if err != nil {
 		logger.Error("STORAGE", "Failed to load birthdays: %v", err)
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Sorry, there was an error accessing the database.")
+		msg := tgbotapi.NewMessage(message.Chat.ID, i18n.T(lang, "bot.error_database"))
 		if _, err := b.api.Send(msg); err != nil {
 			logger.Error("BOT", "Failed to send error message: %v", err)
 		}
@@ -489,12 +610,19 @@ func (b *Bot) handleMyInfoCommand(message *tgbotapi.Message) {
 
 	// Find user's birthday entry
 	for _, birthday := range birthdays {
-		if birthday.ChatID == message.Chat.ID {
-			responseText := fmt.Sprintf("📋 Your Information:\n\nName: %s\nBirth Date: %s\nChat ID: %d",
-				birthday.Name, birthday.BirthDate, birthday.ChatID)
+		if birthday.ChatID == message.Chat.ID && birthday.IsBirthday() {
+			responseText := i18n.T(lang, "info.details", birthday.Name, birthday.BirthDate, birthday.ChatID)
 
 			if !birthday.LastNotification.IsZero() {
-				responseText += fmt.Sprintf("\nLast Notification: %s", birthday.LastNotification.Format("2006-01-02 15:04:05"))
+				responseText += "\n" + i18n.T(lang, "info.last_notification", birthday.LastNotification.Format("2006-01-02 15:04:05"))
+			}
+
+			if len(birthday.Links) > 0 {
+				titles := make([]string, 0, len(birthday.Links))
+				for _, link := range birthday.Links {
+					titles = append(titles, link.Title)
+				}
+				responseText += "\n" + i18n.T(lang, "info.links", strings.Join(titles, ", "))
 			}
 
 			msg := tgbotapi.NewMessage(message.Chat.ID, responseText)
@@ -506,7 +634,7 @@ func (b *Bot) handleMyInfoCommand(message *tgbotapi.Message) {
 	}
 
 	// User not found
-	msg := tgbotapi.NewMessage(message.Chat.ID, "You don't have any information stored yet. Use /update_birth_date to set your birth date.")
+	msg := tgbotapi.NewMessage(message.Chat.ID, i18n.T(lang, "info.none"))
 	if _, err := b.api.Send(msg); err != nil {
 		logger.Error("BOT", "Failed to send message: %v", err)
 	}
@@ -518,32 +646,39 @@ func (b *Bot) handleChatTitleChange(message *tgbotapi.Message) {
 
 	logger.Info("BOT", "Chat title changed to '%s' for chat ID: %d", newTitle, chatID)
 
-	// Load existing birthdays
-	birthdays, err := storage.LoadBirthdays()
-	if err != nil {
-		logger.Error("STORAGE", "Failed to load birthdays during title change: %v", err)
-		return
-	}
-
-	// Find and update the birthday entry for this chat
-	updated := false
-	for i := range birthdays {
-		if birthdays[i].ChatID == chatID {
-			oldName := birthdays[i].Name
-			birthdays[i].Name = newTitle
-			updated = true
-			logger.Info("BOT", "Updated chat name from '%s' to '%s' for chat ID: %d", oldName, newTitle, chatID)
-			break
+	// Update the birthday entry for this chat and the links of other entries to it
+	var before, after []models.Birthday
+	err := storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		for i := range birthdays {
+			original := birthdays[i].Clone()
+			changed := false
+			if birthdays[i].ChatID == chatID && birthdays[i].IsBirthday() {
+				logger.Info("BOT", "Updated chat name from '%s' to '%s' for chat ID: %d", birthdays[i].Name, newTitle, chatID)
+				birthdays[i].Name = newTitle
+				changed = true
+			}
+			if idx := birthdays[i].LinkIndex(chatID); idx >= 0 {
+				birthdays[i].Links[idx].Title = newTitle
+				changed = true
+			}
+			if changed {
+				before, after = append(before, original), append(after, birthdays[i])
+			}
 		}
-	}
-
-	if updated {
-		// Save the updated birthdays
-		if err := storage.SaveBirthdays(birthdays); err != nil {
-			logger.Error("STORAGE", "Failed to save birthdays after title change: %v", err)
+		if len(before) == 0 {
+			return nil, errNoChange
 		}
-	} else {
+		return birthdays, nil
+	})
+	switch {
+	case errors.Is(err, errNoChange):
 		logger.Debug("BOT", "No existing birthday entry found for chat ID: %d", chatID)
+	case err != nil:
+		logger.Error("STORAGE", "Failed to save birthdays after title change: %v", err)
+	default:
+		for i := range before {
+			audit.Updated(messageActor(message), models.SourceBot, before[i], after[i])
+		}
 	}
 
 	// Don't send any message to the chat for title changes
@@ -596,8 +731,8 @@ func (b *Bot) checkBirthdays() {
 }
 
 func (b *Bot) shouldSendBirthdayNotification(birthday models.Birthday, notificationType string) bool {
-	// Always send birthday today notification
-	if notificationType == "BIRTHDAY_TODAY" {
+	// Always send the greeting on the day of the event
+	if isGreeting(notificationType) {
 		// Check if last notification was today
 		now := time.Now().UTC()
 		lastNotificationDate := ""
@@ -619,6 +754,7 @@ func (b *Bot) processBirthdays() {
 
 	// Check if current time is within notification hours
 	if !b.isWithinNotificationHours(currentHour) {
+		b.markChecked()
 		return // Skip logging during frequent checks
 	}
 
@@ -629,16 +765,35 @@ func (b *Bot) processBirthdays() {
 		logger.LogNotification("ERROR", "Failed to load birthdays: %v", err)
 		return
 	}
+	defer metrics.CheckDuration.ObserveSince(now)
 
 	logger.LogNotification("INFO", "Loaded %d birthday entries from storage", len(birthdays))
+	metrics.RecordsLoaded.Set(float64(len(birthdays)))
+
+	// Notifications are sent in the language configured for each chat,
+	// and chats with a digest get it instead of the reminders of the events it lists
+	chatLanguages := make(map[int64]string)
+	digestCovered := make(map[int64]time.Time)
+	chatSettings, settingsErr := storage.LoadChatSettings()
+	if settingsErr != nil {
+		logger.LogNotification("WARN", "Failed to load chat settings, using defaults: %v", settingsErr)
+	}
+	for _, s := range chatSettings {
+		chatLanguages[s.ChatID] = i18n.Resolve(s.Language)
+		digestCovered[s.ChatID] = digestCoverage(s, now)
+	}
+	// Records are also announced in the chats subscribed to one of their tags
+	routes := b.tagChats(chatSettings)
 
 	today := now.Format("2006-01-02")
 
-	logger.LogNotification("INFO", "Checking for birthdays today, in 2 weeks (+14 days), and in 4 weeks (+28 days)")
+	logger.LogNotification("INFO", "Checking for events today and at the reminder offsets of their types")
 
-	notificationsSent := false
+	// delivered holds the chats notified per record ID, applied to the stored records once the pass is done
+	delivered := make(map[string][]int64)
 	entriesProcessed := 0
 	entriesSkipped := 0
+	notificationsSentCount := 0
 
 	for i, birthday := range birthdays {
 		entriesProcessed++
@@ -647,9 +802,10 @@ func (b *Bot) processBirthdays() {
 			i+1, birthday.Name, birthday.BirthDate, birthday.ChatID)
 
 		// Skip if no chat ID configured
-		if birthday.ChatID == 0 {
+		if birthday.ChatID == 0 && len(birthday.Links) == 0 && len(routedChats(&birthday, routes)) == 0 {
 			logger.LogNotification("WARN", "SKIP: No chat ID configured for '%s'", birthday.Name)
 			entriesSkipped++
+			metrics.RecordsSkipped.Inc(skipNoChat)
 			continue
 		}
 
@@ -665,127 +821,106 @@ func (b *Bot) processBirthdays() {
 		if birthdayMMDD == "" {
 			logger.LogNotification("WARN", "SKIP: Invalid birth date format for '%s': '%s'", birthday.Name, birthday.BirthDate)
 			entriesSkipped++
+			metrics.RecordsSkipped.Inc(skipInvalidFormat)
 			continue
 		}
 
 		logger.LogNotification("DEBUG", "Extracted birthday MM-DD: %s for '%s'", birthdayMMDD, birthday.Name)
 
-		// Check if we already sent notification today
-		lastNotificationDate := ""
-		if !birthday.LastNotification.IsZero() {
-			lastNotificationDate = birthday.LastNotification.Format("2006-01-02")
-		}
-
-		logger.LogNotification("DEBUG", "Last notification for '%s': %s (today: %s)",
-			birthday.Name, lastNotificationDate, today)
+		eventType := birthday.EventType()
 
-		if lastNotificationDate == today {
-			logger.LogNotification("DEBUG", "SKIP: Already sent notification today for '%s'", birthday.Name)
+		// Find the next occurrence of the event, this year or next year
+		nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		nextDate, ok := nextOccurrence(birthday.BirthDate, nowDate)
+		if !ok {
+			logger.LogNotification("ERROR", "SKIP: Failed to parse birthday date for '%s': '%s'", birthday.Name, birthday.BirthDate)
 			entriesSkipped++
-			continue // Already sent notification today
+			metrics.RecordsSkipped.Inc(skipInvalidDate)
+			continue
 		}
 
-		var message string
-		var notificationType string
+		// Calculate days difference using date-only comparison
+		daysDiff := int(nextDate.Sub(nowDate).Hours() / 24)
 
-		// Parse the birthday MM-DD to determine this year's birthday date
-		thisYearBirthday, err := time.Parse("2006-01-02", fmt.Sprintf("%d-%s", now.Year(), birthdayMMDD))
-		if err != nil {
-			logger.LogNotification("ERROR", "SKIP: Failed to parse birthday date for '%s': %v", birthday.Name, err)
-			entriesSkipped++
-			continue
+		logger.LogNotification("DEBUG", "Event analysis for '%s': Type=%s, Next=%s, DaysDiff=%d",
+			birthday.Name, eventType, nextDate.Format("2006-01-02"), daysDiff)
+
+		// The greeting is sent on the day itself, reminders at the offsets of the event type
+		var notificationType string
+		switch {
+		case daysDiff == 0:
+			notificationType = greetingNotification(eventType)
+		case isReminderDay(eventType, daysDiff):
+			notificationType = reminderNotification(daysDiff, nextDate.Year() > now.Year())
+		default:
+			continue // No notification matches
 		}
 
-		// Normalize current time to start of day (midnight) for accurate date comparison
-		nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		// Notify the owner chat, every linked chat and the chats routed by tag,
+		// each with its own preferences and dedup state
+		sent := false
+		targets := notificationTargets(&birthdays[i])
+		targets = append(targets, routedTargets(&birthdays[i], routes, targets)...)
+		for _, target := range targets {
+			if !target.wants(notificationType) {
+				logger.LogNotification("DEBUG", "SKIP: %s for '%s' is disabled for ChatID %d",
+					notificationType, birthday.Name, target.chatID)
+				continue
+			}
+			if !isGreeting(notificationType) && !nextDate.After(digestCovered[target.chatID]) {
+				logger.LogNotification("DEBUG", "SKIP: %s for '%s' is covered by the digest of ChatID %d",
+					notificationType, birthday.Name, target.chatID)
+				continue
+			}
 
-		// Calculate days difference using date-only comparison
-		daysDiff := int(thisYearBirthday.Sub(nowDate).Hours() / 24)
-
-		logger.LogNotification("DEBUG", "Birthday analysis for '%s': ThisYear=%s, DaysDiff=%d",
-			birthday.Name, thisYearBirthday.Format("2006-01-02"), daysDiff)
-
-		// Check for different notification scenarios
-		if daysDiff == 0 {
-			// Birthday is today
-			message = fmt.Sprintf("🎉 Happy Birthday, %s! 🎂", birthday.Name)
-			notificationType = "BIRTHDAY_TODAY"
-		} else if daysDiff == 14 {
-			// Birthday is in exactly 2 weeks
-			message = fmt.Sprintf("📅 Reminder: %s's birthday is in 2 weeks (%s)! 🎈", birthday.Name, birthdayMMDD)
-			notificationType = "REMINDER_2_WEEKS"
-		} else if daysDiff == 28 {
-			// Birthday is in exactly 4 weeks
-			message = fmt.Sprintf("📅 Early reminder: %s's birthday is in 4 weeks (%s)! 🗓️", birthday.Name, birthdayMMDD)
-			notificationType = "REMINDER_4_WEEKS"
-		} else if daysDiff < 0 {
-			// Birthday has passed this year - check next year
-			nextYearBirthday := thisYearBirthday.AddDate(1, 0, 0)
-			nextYearDaysDiff := int(nextYearBirthday.Sub(nowDate).Hours() / 24)
-
-			logger.LogNotification("DEBUG", "Birthday passed this year for '%s': NextYear=%s, NextYearDaysDiff=%d",
-				birthday.Name, nextYearBirthday.Format("2006-01-02"), nextYearDaysDiff)
-
-			if nextYearDaysDiff == 14 {
-				// Birthday is in 2 weeks next year
-				message = fmt.Sprintf("📅 Reminder: %s's birthday is in 2 weeks (%s)! 🎈", birthday.Name, birthdayMMDD)
-				notificationType = "REMINDER_2_WEEKS_NEXT_YEAR"
-			} else if nextYearDaysDiff == 28 {
-				// Birthday is in 4 weeks next year
-				message = fmt.Sprintf("📅 Early reminder: %s's birthday is in 4 weeks (%s)! 🗓️", birthday.Name, birthdayMMDD)
-				notificationType = "REMINDER_4_WEEKS_NEXT_YEAR"
-			} else {
-				continue // No notification matches
+			// Check if we already sent notification today
+			check := birthday
+			check.LastNotification = *target.lastNotification
+			if !check.LastNotification.IsZero() && check.LastNotification.Format("2006-01-02") == today {
+				logger.LogNotification("DEBUG", "SKIP: Already sent notification today for '%s' to ChatID %d", birthday.Name, target.chatID)
+				continue
+			}
+			if !b.shouldSendBirthdayNotification(check, notificationType) {
+				continue
 			}
-		} else {
-			continue // No notification matches
-		}
 
-		// Check if this notification should be sent
-		if b.shouldSendBirthdayNotification(birthday, notificationType) {
+			message := notificationMessage(chatLanguages[target.chatID], eventType, daysDiff, birthday.Name, birthdayMMDD)
 			logger.LogNotification("INFO", "SENDING: Type=%s, Name='%s', ChatID=%d, Message='%s'",
-				notificationType, birthday.Name, birthday.ChatID, message)
+				notificationType, birthday.Name, target.chatID, message)
 
-			msg := tgbotapi.NewMessage(birthday.ChatID, message)
+			msg := tgbotapi.NewMessage(target.chatID, message)
 
 			if _, err := b.api.Send(msg); err != nil {
 				logger.LogNotification("ERROR", "Failed to send %s notification for '%s' to ChatID %d: %v",
-					notificationType, birthday.Name, birthday.ChatID, err)
-				entriesSkipped++
+					notificationType, birthday.Name, target.chatID, err)
+				b.countFailedNotification(notificationType, target.chatID, err)
+				recordHistory(notificationType, &birthday, target.chatID, message, err)
 				continue
 			}
+			recordHistory(notificationType, &birthday, target.chatID, message, nil)
 
 			// Increment notification counter
-			b.mu.Lock()
-			b.notificationsSent++
-			totalSent := b.notificationsSent
-			b.mu.Unlock()
+			totalSent := b.countNotification(notificationType)
 
 			// Update last notification time
-			birthdays[i].LastNotification = now
-			notificationsSent = true
+			*target.lastNotification = now
+			delivered[birthday.ID] = append(delivered[birthday.ID], target.chatID)
+			sent = true
+			notificationsSentCount++
 
 			logger.LogNotification("INFO", "SUCCESS: %s notification sent for '%s' (ChatID: %d, Total sent: %d)",
-				notificationType, birthday.Name, birthday.ChatID, totalSent)
-		} else {
-			if daysDiff < 0 {
-				// Birthday has passed, show next year info
-				nextYearBirthday := thisYearBirthday.AddDate(1, 0, 0)
-				nextYearDaysDiff := int(nextYearBirthday.Sub(nowDate).Hours() / 24)
-				logger.LogNotification("DEBUG", "NO_MATCH: Birthday '%s' (%s) passed this year (%d days ago), next occurrence in %d days",
-					birthday.Name, birthdayMMDD, -daysDiff, nextYearDaysDiff)
-			} else {
-				logger.LogNotification("DEBUG", "NO_MATCH: Birthday '%s' (%s) is in %d days (not 0, 14, or 28)",
-					birthday.Name, birthdayMMDD, daysDiff)
-			}
+				notificationType, birthday.Name, target.chatID, totalSent)
+		}
+		if !sent {
 			entriesSkipped++
+			metrics.RecordsSkipped.Inc(skipNotSent)
 		}
 	}
 
 	// Save updated birthdays if any notifications were sent
-	if notificationsSent {
+	if len(delivered) > 0 {
 		logger.LogNotification("INFO", "SAVING: Updating YAML file with new last_notification timestamps")
-		if err := storage.SaveBirthdays(birthdays); err != nil {
+		if err := markNotified(now, delivered, routes); err != nil {
 			logger.LogNotification("ERROR", "Failed to save birthdays after notifications: %v", err)
 		} else {
 			logger.LogNotification("INFO", "SAVED: Successfully updated YAML file")
@@ -794,7 +929,34 @@ func (b *Bot) processBirthdays() {
 		logger.LogNotification("DEBUG", "NO_SAVE: No notifications sent, YAML file unchanged")
 	}
 
-	notificationsSentCount := entriesProcessed - entriesSkipped
+	// Digests and gift collections depend on the chat settings, so they are skipped if the settings could not be loaded
+	if settingsErr == nil {
+		b.processDigests(now, birthdays, chatSettings)
+		b.processGiftCollections(now, chatSettings)
+	}
+
 	logger.LogNotification("INFO", "SUMMARY: Processed=%d, Sent=%d, Skipped=%d, Duration=%v",
 		entriesProcessed, notificationsSentCount, entriesSkipped, time.Since(now).Truncate(time.Millisecond))
+	b.markChecked()
+}
+
+// markNotified stores the dedup timestamps of the delivered notifications, keyed by record ID.
+// Only the notified targets are touched, so changes made to the records during the check are kept.
+func markNotified(now time.Time, delivered map[string][]int64, routes tagRoutes) error {
+	return storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		for i := range birthdays {
+			chatIDs, ok := delivered[birthdays[i].ID]
+			if !ok {
+				continue
+			}
+			targets := notificationTargets(&birthdays[i])
+			targets = append(targets, routedTargets(&birthdays[i], routes, targets)...)
+			for _, target := range targets {
+				if slices.Contains(chatIDs, target.chatID) {
+					*target.lastNotification = now
+				}
+			}
+		}
+		return birthdays, nil
+	})
 }
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"strings"
+
+	"5mdt/bd_bot/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// callbackHandler processes a callback query. The data argument is the callback data
+// with the routing prefix removed.
+type callbackHandler func(b *Bot, query *tgbotapi.CallbackQuery, data string)
+
+// callbackHandlers maps callback data prefixes (the part before the first ':') to their handlers.
+var callbackHandlers = map[string]callbackHandler{
+	dateCallbackPrefix:   (*Bot).handleDateCallback,
+	forgetCallbackPrefix: (*Bot).handleForgetCallback,
+	followCallbackPrefix: (*Bot).handleFollowCallback,
+	giftCallbackPrefix:   (*Bot).handleGiftCallback,
+}
+
+// handleCallbackQuery routes inline keyboard button presses to the handler registered for their prefix.
+func (b *Bot) handleCallbackQuery(query *tgbotapi.CallbackQuery) {
+	if query.From != nil {
+		logger.Debug("BOT", "Callback from %s (user ID: %d): %s", query.From.UserName, query.From.ID, query.Data)
+	}
+
+	// Only buttons attached to bot messages are supported
+	if query.Message == nil {
+		b.answerCallback(query.ID, "", false)
+		return
+	}
+
+	prefix, data, _ := strings.Cut(query.Data, ":")
+	handler, ok := callbackHandlers[prefix]
+	if !ok {
+		logger.Warn("BOT", "Unknown callback data: %s", query.Data)
+		b.answerCallback(query.ID, "", false)
+		return
+	}
+	handler(b, query, data)
+}
+
+// answerCallback acknowledges a callback query, optionally showing a notification or alert to the user.
+func (b *Bot) answerCallback(queryID, text string, alert bool) {
+	cfg := tgbotapi.NewCallback(queryID, text)
+	if alert {
+		cfg = tgbotapi.NewCallbackWithAlert(queryID, text)
+	}
+	if _, err := b.api.Request(cfg); err != nil {
+		logger.Error("BOT", "Failed to answer callback query: %v", err)
+	}
+}
+
+// editMessage replaces the text and inline keyboard of a bot message.
+// A nil markup removes the keyboard.
+func (b *Bot) editMessage(chatID int64, messageID int, text string, markup *tgbotapi.InlineKeyboardMarkup) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ReplyMarkup = markup
+	if _, err := b.api.Send(edit); err != nil {
+		logger.Error("BOT", "Failed to edit message %d in chat %d: %v", messageID, chatID, err)
+	}
+}
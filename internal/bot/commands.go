@@ -0,0 +1,268 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatScope is a set of chat types in which a command is available.
+type chatScope int
+
+const (
+	// scopePrivate covers one-to-one chats with the bot.
+	scopePrivate chatScope = 1 << iota
+	// scopeGroup covers groups and supergroups.
+	scopeGroup
+
+	// scopeAll covers every chat type the bot handles.
+	scopeAll = scopePrivate | scopeGroup
+)
+
+// permission describes who is allowed to run a command.
+type permission int
+
+const (
+	// permAnyone allows every chat member to run the command.
+	permAnyone permission = iota
+	// permChatAdmin restricts the command to chat administrators in group chats.
+	// In private chats the caller always owns the chat and is allowed.
+	permChatAdmin
+)
+
+// command describes a single bot command and how it is dispatched.
+type command struct {
+	// name is the command name without the leading slash.
+	// The one-line summary shown in /help and the command menu is the catalog message "cmd.<name>".
+	name string
+	// args is the human-readable argument syntax (empty if the command takes none).
+	args string
+	// scope lists the chat types in which the command is available.
+	scope chatScope
+	// permission is the permission required to run the command.
+	permission permission
+	// handler processes the command message with its raw argument string.
+	handler func(b *Bot, message *tgbotapi.Message, args string)
+}
+
+// description returns the one-line summary of the command in the given language.
+func (c *command) description(lang string) string {
+	return i18n.T(lang, "cmd."+c.name)
+}
+
+// commandRegistry holds the bot commands in registration order.
+type commandRegistry struct {
+	commands []*command
+	byName   map[string]*command
+}
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{byName: make(map[string]*command)}
+}
+
+// register adds a command to the registry.
+// It panics on duplicate names since the registry is built once at startup.
+func (r *commandRegistry) register(cmd *command) {
+	if _, exists := r.byName[cmd.name]; exists {
+		panic(fmt.Sprintf("bot: command %q registered twice", cmd.name))
+	}
+	r.commands = append(r.commands, cmd)
+	r.byName[cmd.name] = cmd
+}
+
+// lookup returns the command registered under the given name.
+func (r *commandRegistry) lookup(name string) (*command, bool) {
+	cmd, ok := r.byName[name]
+	return cmd, ok
+}
+
+// forScope returns the commands available in the given chat scope, in registration order.
+func (r *commandRegistry) forScope(scope chatScope) []*command {
+	var cmds []*command
+	for _, cmd := range r.commands {
+		if cmd.scope&scope != 0 {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// helpText renders the /help message for the given chat scope from the registered commands.
+func (r *commandRegistry) helpText(scope chatScope, lang string) string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T(lang, "help.header") + "\n\n")
+	for _, cmd := range r.forScope(scope) {
+		sb.WriteString("/" + cmd.name)
+		if cmd.args != "" {
+			sb.WriteString(" " + cmd.args)
+		}
+		sb.WriteString(" - " + cmd.description(lang))
+		if cmd.permission == permChatAdmin && scope == scopeGroup {
+			sb.WriteString(" " + i18n.T(lang, "help.admins_only"))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n" + i18n.T(lang, "help.footer"))
+	return sb.String()
+}
+
+// botCommands converts the commands available in the given scope into the setMyCommands payload.
+func (r *commandRegistry) botCommands(scope chatScope, lang string) []tgbotapi.BotCommand {
+	var cmds []tgbotapi.BotCommand
+	for _, cmd := range r.forScope(scope) {
+		cmds = append(cmds, tgbotapi.BotCommand{Command: cmd.name, Description: cmd.description(lang)})
+	}
+	return cmds
+}
+
+// defaultCommands builds the registry of all commands supported by the bot.
+func defaultCommands() *commandRegistry {
+	r := newCommandRegistry()
+	r.register(&command{
+		name:  "start",
+		scope: scopeAll,
+		handler: func(b *Bot, message *tgbotapi.Message, _ string) {
+			b.handleStartCommand(message)
+		},
+	})
+	r.register(&command{
+		name:  "help",
+		scope: scopeAll,
+		handler: func(b *Bot, message *tgbotapi.Message, _ string) {
+			b.handleHelpCommand(message)
+		},
+	})
+	r.register(&command{
+		name:       "update_birth_date",
+		args:       "[date]",
+		scope:      scopeAll,
+		permission: permChatAdmin,
+		handler:    (*Bot).handleUpdateBirthDateCommand,
+	})
+	r.register(&command{
+		name:  "my_info",
+		scope: scopeAll,
+		handler: func(b *Bot, message *tgbotapi.Message, _ string) {
+			b.handleMyInfoCommand(message)
+		},
+	})
+	r.register(&command{
+		name:  "follow",
+		args:  "(reply to a member)",
+		scope: scopeGroup,
+		handler: func(b *Bot, message *tgbotapi.Message, _ string) {
+			b.handleFollowCommand(message)
+		},
+	})
+	r.register(&command{
+		name:  "unfollow",
+		args:  "(reply to a member)",
+		scope: scopeGroup,
+		handler: func(b *Bot, message *tgbotapi.Message, _ string) {
+			b.handleUnfollowCommand(message)
+		},
+	})
+	r.register(&command{
+		name:       "add_event",
+		args:       "<type> <date> \"<name>\"",
+		scope:      scopeAll,
+		permission: permChatAdmin,
+		handler:    (*Bot).handleAddEventCommand,
+	})
+	r.register(&command{
+		name:    "events",
+		args:    "[type]",
+		scope:   scopeAll,
+		handler: (*Bot).handleEventsCommand,
+	})
+	r.register(&command{
+		name:    "upcoming",
+		args:    "[tag]",
+		scope:   scopeAll,
+		handler: (*Bot).handleUpcomingCommand,
+	})
+	r.register(&command{
+		name:       "tags",
+		args:       "[tag ...|off]",
+		scope:      scopeAll,
+		permission: permChatAdmin,
+		handler:    (*Bot).handleTagsCommand,
+	})
+	r.register(&command{
+		name:       "digest",
+		args:       "[off|daily|weekly <weekday>|monthly]",
+		scope:      scopeAll,
+		permission: permChatAdmin,
+		handler:    (*Bot).handleDigestCommand,
+	})
+	r.register(&command{
+		name:       "gift_collection",
+		args:       "[off|<days>]",
+		scope:      scopeGroup,
+		permission: permChatAdmin,
+		handler:    (*Bot).handleGiftCollectionCommand,
+	})
+	r.register(&command{
+		name:    "export_my_data",
+		args:    "[chat]",
+		scope:   scopeAll,
+		handler: (*Bot).handleExportMyDataCommand,
+	})
+	r.register(&command{
+		name:    "forget_me",
+		args:    "[chat]",
+		scope:   scopeAll,
+		handler: (*Bot).handleForgetMeCommand,
+	})
+	r.register(&command{
+		name:       "language",
+		args:       "[code]",
+		scope:      scopeAll,
+		permission: permChatAdmin,
+		handler:    (*Bot).handleLanguageCommand,
+	})
+	return r
+}
+
+// scopeOf returns the command scope matching the chat type.
+func scopeOf(chat *tgbotapi.Chat) chatScope {
+	if chat != nil && (chat.IsGroup() || chat.IsSuperGroup()) {
+		return scopeGroup
+	}
+	return scopePrivate
+}
+
+// publishCommands registers the command menu with Telegram, separately for private and group chats.
+// The default menu uses the default language; every supported language also gets its own menu,
+// which Telegram shows to users with a matching client language.
+// Failures are logged and do not prevent the bot from running.
+func (b *Bot) publishCommands() {
+	scopes := []struct {
+		scope    chatScope
+		botScope tgbotapi.BotCommandScope
+		label    string
+	}{
+		{scopePrivate, tgbotapi.NewBotCommandScopeAllPrivateChats(), "private chats"},
+		{scopeGroup, tgbotapi.NewBotCommandScopeAllGroupChats(), "group chats"},
+	}
+
+	for _, s := range scopes {
+		cfg := tgbotapi.NewSetMyCommandsWithScope(s.botScope, b.commands.botCommands(s.scope, i18n.DefaultLanguage)...)
+		if _, err := b.api.Request(cfg); err != nil {
+			logger.Error("BOT", "Failed to publish commands for %s: %v", s.label, err)
+			continue
+		}
+		logger.Info("BOT", "Published %d commands for %s", len(cfg.Commands), s.label)
+
+		for _, lang := range i18n.Supported() {
+			cfg := tgbotapi.NewSetMyCommandsWithScopeAndLanguage(s.botScope, lang, b.commands.botCommands(s.scope, lang)...)
+			if _, err := b.api.Request(cfg); err != nil {
+				logger.Error("BOT", "Failed to publish %s commands for %s: %v", lang, s.label, err)
+			}
+		}
+	}
+}
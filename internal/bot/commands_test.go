@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestHelpTextListsRegisteredCommands(t *testing.T) {
+	registry := defaultCommands()
+
+	for _, lang := range i18n.Supported() {
+		for _, scope := range []chatScope{scopePrivate, scopeGroup} {
+			help := registry.helpText(scope, lang)
+			for _, cmd := range registry.forScope(scope) {
+				if !strings.Contains(help, "/"+cmd.name) {
+					t.Errorf("%s help text for scope %d is missing /%s", lang, scope, cmd.name)
+				}
+				if !strings.Contains(help, cmd.description(lang)) {
+					t.Errorf("%s help text for scope %d is missing description of /%s", lang, scope, cmd.name)
+				}
+			}
+		}
+	}
+}
+
+func TestCommandsHaveTranslatedDescriptions(t *testing.T) {
+	for _, cmd := range defaultCommands().commands {
+		if cmd.description(i18n.DefaultLanguage) == "cmd."+cmd.name {
+			t.Errorf("command /%s has no description in the message catalog", cmd.name)
+		}
+	}
+}
+
+func TestCommandScopeFiltering(t *testing.T) {
+	registry := newCommandRegistry()
+	registry.register(&command{name: "private_only", scope: scopePrivate})
+	registry.register(&command{name: "group_only", scope: scopeGroup, permission: permChatAdmin})
+	registry.register(&command{name: "everywhere", scope: scopeAll})
+
+	private := registry.botCommands(scopePrivate, "en")
+	if len(private) != 2 || private[0].Command != "private_only" || private[1].Command != "everywhere" {
+		t.Errorf("unexpected private commands: %+v", private)
+	}
+
+	group := registry.botCommands(scopeGroup, "en")
+	if len(group) != 2 || group[0].Command != "group_only" || group[1].Command != "everywhere" {
+		t.Errorf("unexpected group commands: %+v", group)
+	}
+
+	if help := registry.helpText(scopeGroup, "en"); !strings.Contains(help, "/group_only - cmd.group_only (admins only)") {
+		t.Errorf("group help should mark admin-only commands, got:\n%s", help)
+	}
+	if help := registry.helpText(scopePrivate, "en"); strings.Contains(help, "admins only") {
+		t.Errorf("private help should not mark admin-only commands, got:\n%s", help)
+	}
+}
+
+func TestCommandRegistryRejectsDuplicates(t *testing.T) {
+	registry := newCommandRegistry()
+	registry.register(&command{name: "help"})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate command should panic")
+		}
+	}()
+	registry.register(&command{name: "help"})
+}
+
+func TestScopeOf(t *testing.T) {
+	tests := []struct {
+		chatType string
+		want     chatScope
+	}{
+		{"private", scopePrivate},
+		{"group", scopeGroup},
+		{"supergroup", scopeGroup},
+	}
+
+	for _, tt := range tests {
+		if got := scopeOf(&tgbotapi.Chat{Type: tt.chatType}); got != tt.want {
+			t.Errorf("scopeOf(%q) = %d; want %d", tt.chatType, got, tt.want)
+		}
+	}
+}
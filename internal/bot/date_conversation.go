@@ -0,0 +1,393 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// dateCallbackPrefix routes inline keyboard presses of the date picker.
+	dateCallbackPrefix = "bd"
+	// conversationTimeout is how long a date picker stays usable after the last interaction.
+	conversationTimeout = 5 * time.Minute
+	// yearsPerPage is the number of years shown on one page of the year picker.
+	yearsPerPage = 10
+	// defaultYearOffset is how many years before the current one the year picker opens.
+	defaultYearOffset = 30
+	// minPickerYear is the earliest year the year picker pages back to. Year 0 stands for
+	// an unknown year, so paging must never reach it.
+	minPickerYear = dateparse.MinYear
+)
+
+// dateConversation is the state of an interactive birth date entry in a chat.
+type dateConversation struct {
+	// userID is the user who started the conversation; only they may press the buttons.
+	userID int64
+	// messageID is the bot message carrying the inline keyboard.
+	messageID int
+	// month is the selected month (1-12), 0 if not selected yet.
+	month int
+	// day is the selected day of month, 0 if not selected yet.
+	day int
+	// pendingDate is a parsed date in command input format awaiting confirmation.
+	pendingDate string
+	// expiresAt is when the conversation times out.
+	expiresAt time.Time
+}
+
+// conversationStore keeps per-chat date entry conversations.
+type conversationStore struct {
+	mu     sync.Mutex
+	byChat map[int64]*dateConversation
+}
+
+func newConversationStore() *conversationStore {
+	return &conversationStore{byChat: make(map[int64]*dateConversation)}
+}
+
+// start replaces any conversation in the chat with a new one and drops expired conversations.
+func (s *conversationStore) start(chatID int64, conv *dateConversation, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, c := range s.byChat {
+		if now.After(c.expiresAt) {
+			delete(s.byChat, id)
+		}
+	}
+	conv.expiresAt = now.Add(conversationTimeout)
+	s.byChat[chatID] = conv
+}
+
+// get returns a copy of the active conversation of the chat.
+// Expired conversations are removed and reported as missing.
+func (s *conversationStore) get(chatID int64, now time.Time) (dateConversation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conv, ok := s.byChat[chatID]
+	if !ok {
+		return dateConversation{}, false
+	}
+	if now.After(conv.expiresAt) {
+		delete(s.byChat, chatID)
+		return dateConversation{}, false
+	}
+	return *conv, true
+}
+
+// update applies a change to the active conversation of the chat and extends its timeout.
+func (s *conversationStore) update(chatID int64, now time.Time, apply func(conv *dateConversation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conv, ok := s.byChat[chatID]; ok {
+		apply(conv)
+		conv.expiresAt = now.Add(conversationTimeout)
+	}
+}
+
+// finish removes the conversation of the chat.
+func (s *conversationStore) finish(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.byChat, chatID)
+}
+
+// daysInMonth returns the number of days in a month, counting Feb 29 for unknown years.
+func daysInMonth(month int) int {
+	// Year 2000 is a leap year, so February offers the 29th
+	return time.Date(2000, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// dateCallbackData builds the callback data of a date picker button.
+func dateCallbackData(action string, value int) string {
+	return fmt.Sprintf("%s:%s:%d", dateCallbackPrefix, action, value)
+}
+
+// monthName returns the name of the month (1-12) in the given language.
+func monthName(lang string, month int) string {
+	return i18n.T(lang, fmt.Sprintf("month.%d", month))
+}
+
+// cancelButton returns the button that aborts the date picker.
+func cancelButton(lang string) tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.cancel"), dateCallbackPrefix+":cancel")
+}
+
+// monthKeyboard builds the month selection step.
+func monthKeyboard(lang string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for start := 0; start < 12; start += 4 {
+		var row []tgbotapi.InlineKeyboardButton
+		for m := start; m < start+4; m++ {
+			label := i18n.T(lang, fmt.Sprintf("month.short.%d", m+1))
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, dateCallbackData("m", m+1)))
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(cancelButton(lang)))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// dayKeyboard builds the day selection step for the given month.
+func dayKeyboard(lang string, month int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for d := 1; d <= daysInMonth(month); d++ {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(d), dateCallbackData("d", d)))
+		if len(row) == 7 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "date.back_month"), dateCallbackPrefix+":back"),
+		cancelButton(lang),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// yearPageStart returns the first year of the page that contains the given year,
+// but never a page before minPickerYear.
+func yearPageStart(year int) int {
+	if year < minPickerYear {
+		year = minPickerYear
+	}
+	return year - year%yearsPerPage
+}
+
+// yearKeyboard builds the optional year selection step starting at pageStart.
+// Years after currentYear and pages before minPickerYear are not offered.
+func yearKeyboard(lang string, pageStart, currentYear int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for y := pageStart; y < pageStart+yearsPerPage && y <= currentYear; y++ {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(y), dateCallbackData("y", y)))
+		if len(row) == 5 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if prev := pageStart - yearsPerPage; prev >= minPickerYear {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("« %ds", prev), dateCallbackData("yp", prev)))
+	}
+	if next := pageStart + yearsPerPage; next <= currentYear {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%ds »", next), dateCallbackData("yp", next)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "date.year_unknown"), dateCallbackData("y", 0))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "date.back_day"), dateCallbackPrefix+":back"),
+			cancelButton(lang),
+		),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// composeBirthDate turns the picked values into the textual command format (YYYY-MM-DD or MM-DD),
+// so the result goes through the same validation as typed dates.
+func composeBirthDate(year, month, day int) string {
+	if year == 0 {
+		return fmt.Sprintf("%02d-%02d", month, day)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+}
+
+// startDateConversation sends the month picker and starts a date entry conversation in the chat.
+func (b *Bot) startDateConversation(message *tgbotapi.Message) {
+	if message.From == nil {
+		return
+	}
+
+	lang := languageFor(message.Chat, message.From)
+	msg := tgbotapi.NewMessage(message.Chat.ID, i18n.T(lang, "date.pick_month"))
+	msg.ReplyMarkup = monthKeyboard(lang)
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		logger.Error("BOT", "Failed to send date picker: %v", err)
+		return
+	}
+
+	b.conversations.start(message.Chat.ID, &dateConversation{
+		userID:    message.From.ID,
+		messageID: sent.MessageID,
+	}, time.Now())
+	logger.Debug("BOT", "Started date conversation in chat %d for user %d", message.Chat.ID, message.From.ID)
+}
+
+// handleDateCallback advances the date entry conversation for a pressed date picker button.
+func (b *Bot) handleDateCallback(query *tgbotapi.CallbackQuery, data string) {
+	chat := query.Message.Chat
+	messageID := query.Message.MessageID
+	now := time.Now()
+	lang := languageFor(chat, query.From)
+
+	conv, ok := b.conversations.get(chat.ID, now)
+	if !ok || conv.messageID != messageID {
+		b.answerCallback(query.ID, i18n.T(lang, "date.expired_alert"), true)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "date.expired"), nil)
+		return
+	}
+	if query.From == nil || query.From.ID != conv.userID {
+		b.answerCallback(query.ID, i18n.T(lang, "date.not_owner"), true)
+		return
+	}
+
+	action, value, _ := strings.Cut(data, ":")
+	number, _ := strconv.Atoi(value)
+
+	switch action {
+	case "cancel":
+		b.conversations.finish(chat.ID)
+		b.answerCallback(query.ID, "", false)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "date.cancelled"), nil)
+
+	case "back":
+		b.answerCallback(query.ID, "", false)
+		if conv.day != 0 {
+			// Back from the year step to the day step
+			b.conversations.update(chat.ID, now, func(c *dateConversation) { c.day = 0 })
+			markup := dayKeyboard(lang, conv.month)
+			b.editMessage(chat.ID, messageID, i18n.T(lang, "date.pick_day", monthName(lang, conv.month)), &markup)
+			return
+		}
+		// Back from the day step to the month step
+		b.conversations.update(chat.ID, now, func(c *dateConversation) { c.month = 0 })
+		markup := monthKeyboard(lang)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "date.pick_month"), &markup)
+
+	case "m":
+		if number < 1 || number > 12 {
+			b.answerCallback(query.ID, i18n.T(lang, "date.invalid_month"), true)
+			return
+		}
+		b.conversations.update(chat.ID, now, func(c *dateConversation) { c.month = number })
+		b.answerCallback(query.ID, "", false)
+		markup := dayKeyboard(lang, number)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "date.pick_day", monthName(lang, number)), &markup)
+
+	case "d":
+		if conv.month == 0 || number < 1 || number > daysInMonth(conv.month) {
+			b.answerCallback(query.ID, i18n.T(lang, "date.invalid_day_choice"), true)
+			return
+		}
+		b.conversations.update(chat.ID, now, func(c *dateConversation) { c.day = number })
+		b.answerCallback(query.ID, "", false)
+		currentYear := now.Year()
+		markup := yearKeyboard(lang, yearPageStart(currentYear-defaultYearOffset), currentYear)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "date.pick_year", monthName(lang, conv.month), number), &markup)
+
+	case "yp":
+		if conv.day == 0 {
+			b.answerCallback(query.ID, i18n.T(lang, "date.pick_day_first"), true)
+			return
+		}
+		b.conversations.update(chat.ID, now, func(c *dateConversation) {})
+		b.answerCallback(query.ID, "", false)
+		markup := yearKeyboard(lang, yearPageStart(number), now.Year())
+		edit := tgbotapi.NewEditMessageReplyMarkup(chat.ID, messageID, markup)
+		if _, err := b.api.Send(edit); err != nil {
+			logger.Error("BOT", "Failed to switch year page: %v", err)
+		}
+
+	case "y":
+		if conv.month == 0 || conv.day == 0 {
+			b.answerCallback(query.ID, i18n.T(lang, "date.pick_month_day_first"), true)
+			return
+		}
+		b.completeDateConversation(query, composeBirthDate(number, conv.month, conv.day), lang)
+
+	case "confirm":
+		if conv.pendingDate == "" {
+			b.answerCallback(query.ID, i18n.T(lang, "date.nothing_to_confirm"), true)
+			return
+		}
+		b.completeDateConversation(query, conv.pendingDate, lang)
+
+	default:
+		logger.Warn("BOT", "Unknown date picker action: %s", data)
+		b.answerCallback(query.ID, "", false)
+	}
+}
+
+// askBirthDateConfirmation echoes how a free-form date was understood and asks the user to confirm it.
+func (b *Bot) askBirthDateConfirmation(message *tgbotapi.Message, result dateparse.Result, lang string) {
+	if message.From == nil {
+		return
+	}
+
+	text := i18n.T(lang, "date.confirm", describeDate(lang, result.Date))
+	if result.Ambiguous {
+		swapped := dateparse.Date{Year: result.Year, Month: result.Day, Day: result.Month}
+		text += "\n\n" + i18n.T(lang, "date.confirm_ambiguous", describeDate(lang, swapped))
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.save"), dateCallbackPrefix+":confirm"),
+		cancelButton(lang),
+	))
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		logger.Error("BOT", "Failed to send date confirmation: %v", err)
+		return
+	}
+
+	b.conversations.start(message.Chat.ID, &dateConversation{
+		userID:      message.From.ID,
+		messageID:   sent.MessageID,
+		pendingDate: result.Input(),
+	}, time.Now())
+}
+
+// completeDateConversation validates and stores a picked or confirmed date exactly like a typed
+// /update_birth_date. The input is in command format (YYYY-MM-DD or MM-DD).
+func (b *Bot) completeDateConversation(query *tgbotapi.CallbackQuery, input, lang string) {
+	chat := query.Message.Chat
+	messageID := query.Message.MessageID
+
+	// Admin rights may have changed since the conversation started
+	if !b.canManageChat(chat, query.From.ID) {
+		logger.LogAudit("PERMISSION_DENIED", query.From.ID, "user @%s tried to set the birth date of chat %d via inline keyboard",
+			query.From.UserName, chat.ID)
+		b.answerCallback(query.ID, i18n.T(lang, "date.not_admin"), true)
+		return
+	}
+
+	date, err := validateBirthDate(input)
+	if err != nil {
+		b.answerCallback(query.ID, i18n.T(lang, err.Error()), true)
+		return
+	}
+
+	b.conversations.finish(chat.ID)
+	if err := storeBirthDate(chat.ID, resolveChatNameFor(chat, query.From), date, audit.TelegramActor(query.From.ID)); err != nil {
+		logger.Error("STORAGE", "Failed to store birth date: %v", err)
+		b.answerCallback(query.ID, "", false)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "bot.error_save"), nil)
+		return
+	}
+
+	b.answerCallback(query.ID, i18n.T(lang, "date.saved_toast"), false)
+	b.editMessage(chat.ID, messageID, birthDateConfirmation(lang, date), nil)
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDaysInMonth(t *testing.T) {
+	tests := map[int]int{1: 31, 2: 29, 4: 30, 12: 31}
+	for month, want := range tests {
+		if got := daysInMonth(month); got != want {
+			t.Errorf("daysInMonth(%d) = %d; want %d", month, got, want)
+		}
+	}
+}
+
+func TestComposedDatesMatchTypedDates(t *testing.T) {
+	tests := []struct {
+		name       string
+		year       int
+		month, day int
+		typed      string
+	}{
+		{"known year", 1999, 12, 31, "1999-12-31"},
+		{"unknown year", 0, 12, 31, "12-31"},
+		{"unknown year leap day", 0, 2, 29, "02-29"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, err := validateBirthDate(composeBirthDate(tt.year, tt.month, tt.day))
+			if err != nil {
+				t.Fatalf("picked date rejected: %v", err)
+			}
+			typed, err := validateBirthDate(tt.typed)
+			if err != nil {
+				t.Fatalf("typed date rejected: %v", err)
+			}
+			if picked != typed {
+				t.Errorf("picked date stored as %q, typed date stored as %q", picked, typed)
+			}
+		})
+	}
+
+	if _, err := validateBirthDate(composeBirthDate(2023, 2, 29)); err == nil {
+		t.Error("Feb 29 in a non-leap year should be rejected")
+	}
+	if _, err := validateBirthDate("1899-12-31"); err != userError("date.year_out_of_range") {
+		t.Errorf("year before %d: error = %v; want date.year_out_of_range", minPickerYear, err)
+	}
+	future := fmt.Sprintf("%d-01-01", time.Now().Year()+1)
+	if _, err := validateBirthDate(future); err != userError("date.year_out_of_range") {
+		t.Errorf("future year: error = %v; want date.year_out_of_range", err)
+	}
+}
+
+func TestYearKeyboardDoesNotOfferFutureYears(t *testing.T) {
+	markup := yearKeyboard("en", 2020, 2025)
+
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil && *button.CallbackData == dateCallbackData("y", 2026) {
+				t.Error("year keyboard should not offer years after the current one")
+			}
+			if button.CallbackData != nil && *button.CallbackData == dateCallbackData("yp", 2030) {
+				t.Error("year keyboard should not page past the current year")
+			}
+		}
+	}
+}
+
+func TestYearKeyboardStopsPagingAtMinimumYear(t *testing.T) {
+	markup := yearKeyboard("en", yearPageStart(minPickerYear), 2025)
+
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil && *button.CallbackData == dateCallbackData("yp", minPickerYear-yearsPerPage) {
+				t.Error("year keyboard should not page before the minimum year")
+			}
+		}
+	}
+
+	if got := yearPageStart(-20); got != minPickerYear {
+		t.Errorf("yearPageStart(-20) = %d, want %d", got, minPickerYear)
+	}
+}
+
+func TestConversationStoreTimeout(t *testing.T) {
+	store := newConversationStore()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	store.start(1, &dateConversation{userID: 10, messageID: 100}, now)
+	store.update(1, now.Add(conversationTimeout-time.Second), func(c *dateConversation) { c.month = 5 })
+
+	conv, ok := store.get(1, now.Add(2*conversationTimeout-2*time.Second))
+	if !ok {
+		t.Fatal("conversation should still be active after an update extended it")
+	}
+	if conv.month != 5 || conv.userID != 10 || conv.messageID != 100 {
+		t.Errorf("unexpected conversation state: %+v", conv)
+	}
+
+	if _, ok := store.get(1, now.Add(3*conversationTimeout)); ok {
+		t.Error("conversation should expire after the timeout")
+	}
+}
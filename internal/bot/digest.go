@@ -0,0 +1,315 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Digest modes stored in models.ChatSettings.Digest.
+const (
+	digestOff     = ""
+	digestDaily   = "daily"
+	digestWeekly  = "weekly"
+	digestMonthly = "monthly"
+)
+
+// digestEntry is a single upcoming birthday listed in a digest.
+type digestEntry struct {
+	// name is the name of the birthday person.
+	name string
+	// eventType is the type of the event, models.EventBirthday for birthdays.
+	eventType string
+	// date is the day of the upcoming birthday.
+	date time.Time
+	// age is the age the person turns, 0 if the birth year is unknown.
+	age int
+}
+
+// nextOccurrence returns the next day on or after today on which the birthday falls.
+// Feb 29 birthdays are celebrated on Feb 28 in non-leap years.
+func nextOccurrence(birthDate string, today time.Time) (time.Time, bool) {
+	if len(birthDate) != 10 {
+		return time.Time{}, false
+	}
+	month, errMonth := strconv.Atoi(birthDate[5:7])
+	day, errDay := strconv.Atoi(birthDate[8:10])
+	if errMonth != nil || errDay != nil || month < 1 || month > 12 || day < 1 || day > daysInMonth(month) {
+		return time.Time{}, false
+	}
+
+	occurrence := func(year int) time.Time {
+		d := day
+		if month == 2 && d == 29 && time.Date(year, 3, 0, 0, 0, 0, 0, time.UTC).Day() != 29 {
+			d = 28
+		}
+		return time.Date(year, time.Month(month), d, 0, 0, 0, 0, time.UTC)
+	}
+
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	next := occurrence(today.Year())
+	if next.Before(today) {
+		next = occurrence(today.Year() + 1)
+	}
+	return next, true
+}
+
+// digestPeriod returns the last day covered by a digest sent today: the same day for daily digests,
+// the next six days for weekly digests and the rest of the month for monthly digests.
+func digestPeriod(mode string, today time.Time) time.Time {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	switch mode {
+	case digestWeekly:
+		return today.AddDate(0, 0, 6)
+	case digestMonthly:
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	default:
+		return today
+	}
+}
+
+// digestDue reports whether the chat should get its digest today.
+// Daily digests go out every day, weekly digests on the chosen weekday and monthly digests
+// on the first day of the month; each at most once per day.
+func digestDue(s models.ChatSettings, now time.Time) bool {
+	if !s.LastDigest.IsZero() && s.LastDigest.UTC().Format("2006-01-02") == now.Format("2006-01-02") {
+		return false
+	}
+	switch s.Digest {
+	case digestDaily:
+		return true
+	case digestWeekly:
+		return now.Weekday() == s.DigestWeekday
+	case digestMonthly:
+		return now.Day() == 1
+	default:
+		return false
+	}
+}
+
+// digestCoverage returns the last day covered by the chat's latest digest: the one due today or,
+// if none is, the last one sent. It returns the zero time if the chat has no digest yet.
+// Reminders of events up to that day are left to the digest; later ones are still sent.
+func digestCoverage(s models.ChatSettings, now time.Time) time.Time {
+	if s.Digest == digestOff {
+		return time.Time{}
+	}
+	sent := s.LastDigest.UTC()
+	if digestDue(s, now) {
+		sent = now
+	}
+	if sent.IsZero() {
+		return time.Time{}
+	}
+	return digestPeriod(s.Digest, sent)
+}
+
+// visibleBirthdays returns the birthday records announced in the chat: records owned by the chat,
+// records linked to it unless the link is muted, and records routed to it by tag.
+func visibleBirthdays(birthdays []models.Birthday, chatID int64, routes tagRoutes) []models.Birthday {
+	var visible []models.Birthday
+	for i := range birthdays {
+		targeted, muted := false, false
+		for _, target := range notificationTargets(&birthdays[i]) {
+			if target.chatID == chatID {
+				targeted = target.notify != models.NotifyMuted
+				muted = !targeted
+				break
+			}
+		}
+		if targeted || (!muted && slices.Contains(routedChats(&birthdays[i], routes), chatID)) {
+			visible = append(visible, birthdays[i])
+		}
+	}
+	return visible
+}
+
+// digestEntries collects the birthdays that fall between today and the end of the digest period,
+// sorted by date and name.
+func digestEntries(birthdays []models.Birthday, mode string, now time.Time) []digestEntry {
+	last := digestPeriod(mode, now)
+
+	var entries []digestEntry
+	for _, b := range birthdays {
+		date, ok := nextOccurrence(b.BirthDate, now)
+		if !ok || date.After(last) {
+			continue
+		}
+		entry := digestEntry{name: b.Name, eventType: b.EventType(), date: date}
+		if year, err := strconv.Atoi(b.BirthDate[:4]); err == nil && year > 0 && b.IsBirthday() {
+			entry.age = date.Year() - year
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].date.Equal(entries[j].date) {
+			return entries[i].date.Before(entries[j].date)
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries
+}
+
+// formatDigest renders the digest message, e.g. "🎂 This week: Alice (Tue), Bob (Fri, turns 40)".
+func formatDigest(lang, mode string, entries []digestEntry) string {
+	items := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.name
+		if e.eventType != models.EventBirthday {
+			name = i18n.T(lang, "event.entry", e.name, eventTypeName(lang, e.eventType))
+		}
+
+		var label string
+		switch mode {
+		case digestWeekly:
+			label = i18n.T(lang, fmt.Sprintf("weekday.short.%d", e.date.Weekday()))
+		case digestMonthly:
+			label = i18n.T(lang, "digest.day_month", e.date.Day(), i18n.T(lang, fmt.Sprintf("month.of.%d", e.date.Month())))
+		}
+
+		switch {
+		case label != "" && e.age > 0:
+			items = append(items, i18n.T(lang, "digest.entry_label_age", name, label, e.age))
+		case label != "":
+			items = append(items, i18n.T(lang, "digest.entry_label", name, label))
+		case e.age > 0:
+			items = append(items, i18n.T(lang, "digest.entry_age", name, e.age))
+		default:
+			items = append(items, name)
+		}
+	}
+	return i18n.T(lang, "digest.header."+mode, strings.Join(items, ", "))
+}
+
+// processDigests sends the due digests and records when each chat got its digest.
+// Chats without upcoming birthdays in the period get no message.
+func (b *Bot) processDigests(now time.Time, birthdays []models.Birthday, settings []models.ChatSettings) {
+	routes := b.tagChats(settings)
+	for _, s := range settings {
+		if !digestDue(s, now) {
+			continue
+		}
+
+		entries := digestEntries(visibleBirthdays(birthdays, s.ChatID, routes), s.Digest, now)
+		if len(entries) == 0 {
+			logger.LogNotification("DEBUG", "DIGEST: No upcoming birthdays for ChatID %d (%s)", s.ChatID, s.Digest)
+			markDigestSent(s.ChatID, now)
+			continue
+		}
+
+		message := formatDigest(i18n.Resolve(s.Language), s.Digest, entries)
+		notificationType := "DIGEST_" + strings.ToUpper(s.Digest)
+		logger.LogNotification("INFO", "SENDING: Type=%s, ChatID=%d, Entries=%d", notificationType, s.ChatID, len(entries))
+		if _, err := b.api.Send(tgbotapi.NewMessage(s.ChatID, message)); err != nil {
+			// Retry on the next check
+			logger.LogNotification("ERROR", "Failed to send %s digest to ChatID %d: %v", s.Digest, s.ChatID, err)
+			b.countFailedNotification(notificationType, s.ChatID, err)
+			recordHistory(notificationType, nil, s.ChatID, message, err)
+			continue
+		}
+
+		b.countNotification(notificationType)
+		recordHistory(notificationType, nil, s.ChatID, message, nil)
+		logger.LogNotification("INFO", "SUCCESS: %s digest sent to ChatID %d", s.Digest, s.ChatID)
+		markDigestSent(s.ChatID, now)
+	}
+}
+
+// markDigestSent records when the chat got its digest. Only the timestamp is saved, so that settings
+// changed while the digests were sent are kept, and chats forgotten in the meantime stay forgotten.
+func markDigestSent(chatID int64, now time.Time) {
+	if _, err := storage.UpdateStoredChatSettings(chatID, func(s *models.ChatSettings) { s.LastDigest = now }); err != nil {
+		logger.LogNotification("ERROR", "Failed to save digest state of ChatID %d: %v", chatID, err)
+	}
+}
+
+// parseWeekday reads a weekday name or abbreviation in any supported language.
+func parseWeekday(input string) (time.Weekday, bool) {
+	input = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(input), "."))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		for _, lang := range i18n.Supported() {
+			if input == strings.ToLower(i18n.T(lang, fmt.Sprintf("weekday.%d", d))) ||
+				input == strings.ToLower(i18n.T(lang, fmt.Sprintf("weekday.short.%d", d))) {
+				return d, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// describeDigest returns the human-readable digest setting of the chat.
+func describeDigest(lang string, s models.ChatSettings) string {
+	switch s.Digest {
+	case digestWeekly:
+		return i18n.T(lang, "digest.mode.weekly", i18n.T(lang, fmt.Sprintf("weekday.%d", s.DigestWeekday)))
+	case digestDaily, digestMonthly:
+		return i18n.T(lang, "digest.mode."+s.Digest)
+	default:
+		return i18n.T(lang, "digest.mode.off")
+	}
+}
+
+func (b *Bot) handleDigestCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 {
+		settings, err := storage.GetChatSettings(message.Chat.ID)
+		if err != nil {
+			logger.Error("STORAGE", "Failed to load settings of chat %d: %v", message.Chat.ID, err)
+			b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_database"))
+			return
+		}
+		b.sendText(message.Chat.ID, i18n.T(lang, "digest.current", describeDigest(lang, settings)))
+		return
+	}
+
+	mode := fields[0]
+	weekday := time.Monday
+	switch {
+	case mode == "off" && len(fields) == 1:
+		mode = digestOff
+	case (mode == digestDaily || mode == digestMonthly) && len(fields) == 1:
+		// No options
+	case mode == digestWeekly && len(fields) <= 2:
+		if len(fields) == 2 {
+			var ok bool
+			if weekday, ok = parseWeekday(fields[1]); !ok {
+				b.sendText(message.Chat.ID, i18n.T(lang, "digest.invalid_weekday", fields[1]))
+				return
+			}
+		}
+	default:
+		b.sendText(message.Chat.ID, i18n.T(lang, "digest.usage"))
+		return
+	}
+
+	var updated models.ChatSettings
+	err := storage.UpdateChatSettings(message.Chat.ID, func(s *models.ChatSettings) {
+		s.Digest = mode
+		s.DigestWeekday = 0
+		if mode == digestWeekly {
+			s.DigestWeekday = weekday
+		}
+		updated = *s
+	})
+	if err != nil {
+		logger.Error("STORAGE", "Failed to save digest setting of chat %d: %v", message.Chat.ID, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_save"))
+		return
+	}
+
+	logger.Info("BOT", "Digest of chat %d set to %q", message.Chat.ID, mode)
+	b.sendText(message.Chat.ID, i18n.T(lang, "digest.set", describeDigest(lang, updated)))
+}
@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newFakeTelegramBot returns a bot talking to a fake Telegram API that accepts every request.
+// onSend is called with the chat ID of every sent message before it is answered.
+func newFakeTelegramBot(t *testing.T, onSend func(chatID int64)) *Bot {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chatID, _ := strconv.ParseInt(r.FormValue("chat_id"), 10, 64)
+		if onSend != nil {
+			onSend(chatID)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":%d,"type":"private"}}}`, chatID)
+	}))
+	t.Cleanup(server.Close)
+
+	api := &tgbotapi.BotAPI{Token: "TOKEN", Client: server.Client(), Buffer: 100}
+	api.SetAPIEndpoint(server.URL + "/bot%s/%s")
+	return &Bot{api: api, commands: defaultCommands(), conversations: newConversationStore()}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	today := time.Date(2025, 6, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		birthDate string
+		want      string
+	}{
+		{"1990-06-10", "2025-06-10"},
+		{"0000-06-11", "2025-06-11"},
+		{"1990-06-09", "2026-06-09"},
+		{"2000-02-29", "2026-02-28"},
+	}
+	for _, tt := range tests {
+		got, ok := nextOccurrence(tt.birthDate, today)
+		if !ok || got.Format("2006-01-02") != tt.want {
+			t.Errorf("nextOccurrence(%q) = %s, %t; want %s", tt.birthDate, got.Format("2006-01-02"), ok, tt.want)
+		}
+	}
+	if _, ok := nextOccurrence("not a date", today); ok {
+		t.Error("invalid birth dates should be rejected")
+	}
+}
+
+func TestDigestDue(t *testing.T) {
+	tuesday := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		settings models.ChatSettings
+		now      time.Time
+		want     bool
+	}{
+		{"off", models.ChatSettings{}, tuesday, false},
+		{"daily", models.ChatSettings{Digest: digestDaily}, tuesday, true},
+		{"daily already sent", models.ChatSettings{Digest: digestDaily, LastDigest: tuesday.Add(-time.Hour)}, tuesday, false},
+		{"daily sent yesterday", models.ChatSettings{Digest: digestDaily, LastDigest: tuesday.AddDate(0, 0, -1)}, tuesday, true},
+		{"weekly on weekday", models.ChatSettings{Digest: digestWeekly, DigestWeekday: time.Tuesday}, tuesday, true},
+		{"weekly other weekday", models.ChatSettings{Digest: digestWeekly, DigestWeekday: time.Friday}, tuesday, false},
+		{"monthly on the 1st", models.ChatSettings{Digest: digestMonthly}, time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC), true},
+		{"monthly other day", models.ChatSettings{Digest: digestMonthly}, tuesday, false},
+	}
+	for _, tt := range tests {
+		if got := digestDue(tt.settings, tt.now); got != tt.want {
+			t.Errorf("%s: digestDue = %t; want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDigestCoverage(t *testing.T) {
+	tuesday := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		settings models.ChatSettings
+		want     string
+	}{
+		{"off", models.ChatSettings{}, ""},
+		{"daily", models.ChatSettings{Digest: digestDaily}, "2025-06-10"},
+		{"weekly due today", models.ChatSettings{Digest: digestWeekly, DigestWeekday: time.Tuesday}, "2025-06-16"},
+		{"weekly sent on Friday", models.ChatSettings{Digest: digestWeekly, DigestWeekday: time.Friday,
+			LastDigest: time.Date(2025, 6, 6, 9, 0, 0, 0, time.UTC)}, "2025-06-12"},
+		{"weekly never sent", models.ChatSettings{Digest: digestWeekly, DigestWeekday: time.Friday}, ""},
+		{"monthly sent on the 1st", models.ChatSettings{Digest: digestMonthly,
+			LastDigest: time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)}, "2025-06-30"},
+	}
+	for _, tt := range tests {
+		got := digestCoverage(tt.settings, tuesday)
+		if (tt.want == "" && !got.IsZero()) || (tt.want != "" && got.Format("2006-01-02") != tt.want) {
+			t.Errorf("%s: digestCoverage = %v; want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWeeklyDigest(t *testing.T) {
+	monday := time.Date(2025, 6, 9, 9, 0, 0, 0, time.UTC)
+	birthdays := []models.Birthday{
+		{Name: "Bob", BirthDate: "1985-06-13", ChatID: 1},
+		{Name: "Alice", BirthDate: "0000-06-10", ChatID: 1},
+		{Name: "Carol", BirthDate: "1990-06-16", ChatID: 1},
+		{Name: "Dave", BirthDate: "1990-06-11", ChatID: 2},
+	}
+
+	entries := digestEntries(visibleBirthdays(birthdays, 1, tagRoutes{}), digestWeekly, monday)
+	if got := formatDigest("en", digestWeekly, entries); got != "🎂 This week: Alice (Tue), Bob (Fri, turns 40)" {
+		t.Errorf("unexpected weekly digest: %q", got)
+	}
+	if got := formatDigest("ru", digestWeekly, entries); got != "🎂 На этой неделе: Alice (вт), Bob (пт, исполнится 40)" {
+		t.Errorf("unexpected Russian weekly digest: %q", got)
+	}
+}
+
+func TestMonthlyAndDailyDigest(t *testing.T) {
+	first := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	birthdays := []models.Birthday{
+		{Name: "Alice", BirthDate: "0000-06-01"},
+		{Name: "Bob", BirthDate: "1985-06-30"},
+		{Name: "Carol", BirthDate: "1990-07-01"},
+	}
+
+	monthly := formatDigest("en", digestMonthly, digestEntries(birthdays, digestMonthly, first))
+	if monthly != "🎂 This month: Alice (June 1), Bob (June 30, turns 40)" {
+		t.Errorf("unexpected monthly digest: %q", monthly)
+	}
+
+	daily := formatDigest("en", digestDaily, digestEntries(birthdays, digestDaily, first))
+	if daily != "🎂 Today: Alice" {
+		t.Errorf("unexpected daily digest: %q", daily)
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := map[string]time.Weekday{"monday": time.Monday, "Fri": time.Friday, "вт": time.Tuesday, "воскресенье": time.Sunday}
+	for input, want := range tests {
+		if got, ok := parseWeekday(input); !ok || got != want {
+			t.Errorf("parseWeekday(%q) = %v, %t; want %v", input, got, ok, want)
+		}
+	}
+	if _, ok := parseWeekday("someday"); ok {
+		t.Error("unknown weekday names should be rejected")
+	}
+}
+
+func TestProcessDigestsKeepsConcurrentSettingsChanges(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	now := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	birthdays := []models.Birthday{
+		{Name: "Alice", BirthDate: "1990-06-10", ChatID: 1},
+		{Name: "Bob", BirthDate: "1991-06-10", ChatID: 2},
+	}
+	settings := []models.ChatSettings{{ChatID: 1, Digest: digestDaily}, {ChatID: 2, Digest: digestDaily}}
+	if err := storage.SaveChatSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	// While the first digest is sent, chat 1 changes its language and chat 2 is forgotten
+	b := newFakeTelegramBot(t, func(chatID int64) {
+		if chatID == 1 {
+			storage.UpdateChatSettings(1, func(s *models.ChatSettings) { s.Language = "ru" })
+			storage.DeleteChatSettings(2)
+		}
+	})
+	b.processDigests(now, birthdays, settings)
+
+	stored, _ := storage.LoadChatSettings()
+	if len(stored) != 1 || stored[0].ChatID != 1 {
+		t.Fatalf("forgotten chats must not come back, got %+v", stored)
+	}
+	if stored[0].Language != "ru" || !stored[0].LastDigest.Equal(now) {
+		t.Errorf("want the language change kept and the digest recorded, got %+v", stored[0])
+	}
+}
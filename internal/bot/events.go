@@ -0,0 +1,249 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// earlyReminderDays is the offset from which reminders are worded as early reminders.
+const earlyReminderDays = 28
+
+// greetingNotification returns the notification type of the greeting sent on the day of the event,
+// e.g. "BIRTHDAY_TODAY" or "ANNIVERSARY_TODAY".
+func greetingNotification(eventType string) string {
+	return strings.ToUpper(eventType) + "_TODAY"
+}
+
+// reminderNotification returns the notification type of a reminder the given number of days ahead,
+// e.g. "REMINDER_2_WEEKS" or "REMINDER_7_DAYS_NEXT_YEAR" for events falling into the next year.
+func reminderNotification(days int, nextYear bool) string {
+	notificationType := fmt.Sprintf("REMINDER_%d_DAYS", days)
+	if days%7 == 0 {
+		notificationType = fmt.Sprintf("REMINDER_%d_WEEKS", days/7)
+	}
+	if nextYear {
+		notificationType += "_NEXT_YEAR"
+	}
+	return notificationType
+}
+
+// isGreeting reports whether the notification type is the greeting on the day of the event.
+func isGreeting(notificationType string) bool {
+	return strings.HasSuffix(notificationType, "_TODAY")
+}
+
+// isReminderDay reports whether a reminder of the event type is due the given number of days ahead.
+func isReminderDay(eventType string, daysLeft int) bool {
+	for _, days := range models.ReminderDays(eventType) {
+		if days == daysLeft {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationMessage renders the greeting (daysLeft == 0) or a reminder of the event in the given language.
+func notificationMessage(lang, eventType string, daysLeft int, name, mmdd string) string {
+	if daysLeft == 0 {
+		return i18n.T(lang, "notify."+eventType+".today", name)
+	}
+
+	when := i18n.N(lang, "time.in_days", daysLeft)
+	if daysLeft%7 == 0 {
+		when = i18n.N(lang, "time.in_weeks", daysLeft/7)
+	}
+	if daysLeft >= earlyReminderDays {
+		return i18n.T(lang, "notify."+eventType+".early_reminder", name, when, mmdd)
+	}
+	return i18n.T(lang, "notify."+eventType+".reminder", name, when, mmdd)
+}
+
+// eventTypeName returns the translated name of the event type, e.g. "wedding anniversary".
+func eventTypeName(lang, eventType string) string {
+	return i18n.T(lang, "event.type."+eventType)
+}
+
+// eventTypeList returns the event types accepted by /add_event for replies.
+func eventTypeList(lang string) string {
+	items := make([]string, 0, len(models.EventTypes))
+	for _, eventType := range models.EventTypes {
+		items = append(items, fmt.Sprintf("%s (%s)", eventType, eventTypeName(lang, eventType)))
+	}
+	return strings.Join(items, ", ")
+}
+
+// parseEventArgs splits the arguments of /add_event into the event type, date and name.
+// The name may be quoted, in which case the date is everything else after the type:
+// `anniversary 2015-06-01 "Alice & Bob"` or `work June 1 2015 "Alice"`. Without quotes the date
+// is the single word after the type and the name is the rest.
+func parseEventArgs(args string) (eventType, date, name string, ok bool) {
+	typeArg, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+
+	if start := strings.IndexAny(rest, "\"«“"); start >= 0 {
+		quoted := rest[start:]
+		_, size := utf8.DecodeRuneInString(quoted)
+		end := strings.IndexAny(quoted[size:], "\"»”")
+		if end < 0 {
+			return "", "", "", false
+		}
+		closing := quoted[size+end:]
+		_, closingSize := utf8.DecodeRuneInString(closing)
+		name = strings.TrimSpace(quoted[size : size+end])
+		date = strings.TrimSpace(rest[:start] + " " + closing[closingSize:])
+	} else {
+		date, name, _ = strings.Cut(rest, " ")
+		name = strings.TrimSpace(name)
+	}
+
+	eventType, valid := models.ParseEventType(typeArg)
+	if !valid || date == "" || name == "" {
+		return typeArg, "", "", false
+	}
+	return eventType, date, name, true
+}
+
+// parseEventDate parses the date of /add_event. Besides YYYY-MM-DD and MM-DD it accepts the formats
+// understood by dateparse, using the chat's locale for ambiguous numeric dates.
+func parseEventDate(input, languageCode string) (dateparse.Date, error) {
+	if mmddRegex.MatchString(input) || dateRegex.MatchString(input) {
+		date, err := validateBirthDate(input)
+		if err != nil {
+			return dateparse.Date{}, err
+		}
+		year, _ := strconv.Atoi(date[:4])
+		month, _ := strconv.Atoi(date[5:7])
+		day, _ := strconv.Atoi(date[8:10])
+		return dateparse.Date{Year: year, Month: month, Day: day}, nil
+	}
+	result, err := dateparse.Parse(input, dateparse.OrderForLocale(languageCode))
+	if errors.Is(err, dateparse.ErrInvalidDate) {
+		return dateparse.Date{}, userError("date.invalid_day_month")
+	}
+	if errors.Is(err, dateparse.ErrYearOutOfRange) {
+		return dateparse.Date{}, userError("date.year_out_of_range")
+	}
+	if err != nil {
+		return dateparse.Date{}, userError("date.unrecognized")
+	}
+	return result.Date, nil
+}
+
+// handleAddEventCommand adds a recurring event announced in the chat, e.g.
+// /add_event anniversary 2015-06-01 "Alice & Bob".
+func (b *Bot) handleAddEventCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+
+	eventType, dateArg, name, ok := parseEventArgs(args)
+	if !ok {
+		if eventType != "" {
+			if _, valid := models.ParseEventType(eventType); !valid {
+				b.sendText(message.Chat.ID, i18n.T(lang, "event.unknown_type", eventType, eventTypeList(lang)))
+				return
+			}
+		}
+		b.sendText(message.Chat.ID, i18n.T(lang, "event.usage", eventTypeList(lang)))
+		return
+	}
+
+	date, err := parseEventDate(dateArg, dateLocale(message.Chat, message.From))
+	if err != nil {
+		b.sendText(message.Chat.ID, i18n.T(lang, err.Error()))
+		return
+	}
+
+	event := models.Birthday{Name: name, BirthDate: date.String(), ChatID: message.Chat.ID}
+	event.SetEventType(eventType)
+	var saved []models.Birthday
+	err = storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		saved = append(birthdays, event)
+		return saved, nil
+	})
+	if err != nil {
+		logger.Error("STORAGE", "Failed to add event: %v", err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_save"))
+		return
+	}
+	// The saved slice holds the event with the ID assigned on save
+	audit.Created(messageActor(message), models.SourceBot, saved[len(saved)-1])
+
+	logger.Info("BOT", "Added %s '%s' (%s) in chat %d", eventType, name, date, message.Chat.ID)
+	b.sendText(message.Chat.ID, i18n.T(lang, "event.added", eventTypeName(lang, eventType), name, describeDate(lang, date)))
+}
+
+// handleEventsCommand lists the upcoming events announced in the chat, optionally only those of one type.
+func (b *Bot) handleEventsCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+
+	filter := ""
+	if args = strings.TrimSpace(args); args != "" {
+		eventType, ok := models.ParseEventType(args)
+		if !ok {
+			b.sendText(message.Chat.ID, i18n.T(lang, "event.unknown_type", args, eventTypeList(lang)))
+			return
+		}
+		filter = eventType
+	}
+
+	events, err := b.chatEvents(message.Chat.ID)
+	if err != nil {
+		logger.Error("STORAGE", "Failed to load events of chat %d: %v", message.Chat.ID, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_database"))
+		return
+	}
+
+	b.sendText(message.Chat.ID, formatEventList(lang, filterEvents(events, filter), time.Now().UTC()))
+}
+
+// filterEvents returns the records of the event type, or all records for an empty type.
+func filterEvents(birthdays []models.Birthday, eventType string) []models.Birthday {
+	if eventType == "" {
+		return birthdays
+	}
+	var filtered []models.Birthday
+	for i := range birthdays {
+		if birthdays[i].EventType() == eventType {
+			filtered = append(filtered, birthdays[i])
+		}
+	}
+	return filtered
+}
+
+// formatEventList renders the events sorted by their next occurrence, one per line.
+func formatEventList(lang string, events []models.Birthday, now time.Time) string {
+	type upcoming struct {
+		event models.Birthday
+		date  time.Time
+	}
+	var list []upcoming
+	for _, e := range events {
+		if date, ok := nextOccurrence(e.BirthDate, now); ok {
+			list = append(list, upcoming{e, date})
+		}
+	}
+	if len(list) == 0 {
+		return i18n.T(lang, "event.none")
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].date.Before(list[j].date) })
+
+	lines := []string{i18n.T(lang, "event.list_header")}
+	for _, u := range list {
+		when := i18n.T(lang, "digest.day_month", u.date.Day(), i18n.T(lang, fmt.Sprintf("month.of.%d", u.date.Month())))
+		lines = append(lines, i18n.T(lang, "event.list_item", when, u.event.Name, eventTypeName(lang, u.event.EventType())))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+)
+
+func TestParseEventArgs(t *testing.T) {
+	tests := []struct {
+		args                  string
+		eventType, date, name string
+		ok                    bool
+	}{
+		{`anniversary 2015-06-01 "Alice & Bob"`, models.EventAnniversary, "2015-06-01", "Alice & Bob", true},
+		{`work June 1 2015 "Alice"`, models.EventWorkAnniversary, "June 1 2015", "Alice", true},
+		{`name_day 12-06 «Мария Иванова»`, models.EventNameDay, "12-06", "Мария Иванова", true},
+		{`memorial 1990-03-15 Grandpa Joe`, models.EventMemorial, "1990-03-15", "Grandpa Joe", true},
+		{`anniversary 2015-06-01`, "", "", "", false},
+		{`anniversary 2015-06-01 "Alice`, "", "", "", false},
+		{`holiday 2015-06-01 "Alice"`, "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		eventType, date, name, ok := parseEventArgs(tt.args)
+		if ok != tt.ok {
+			t.Errorf("parseEventArgs(%q) ok = %t; want %t", tt.args, ok, tt.ok)
+			continue
+		}
+		if ok && (eventType != tt.eventType || date != tt.date || name != tt.name) {
+			t.Errorf("parseEventArgs(%q) = %q, %q, %q; want %q, %q, %q",
+				tt.args, eventType, date, name, tt.eventType, tt.date, tt.name)
+		}
+	}
+}
+
+func TestEventNotifications(t *testing.T) {
+	if got := greetingNotification(models.EventBirthday); got != "BIRTHDAY_TODAY" || !isGreeting(got) {
+		t.Errorf("unexpected birthday greeting type %q", got)
+	}
+	if got := reminderNotification(14, false); got != "REMINDER_2_WEEKS" || isGreeting(got) {
+		t.Errorf("unexpected reminder type %q", got)
+	}
+	if got := reminderNotification(10, true); got != "REMINDER_10_DAYS_NEXT_YEAR" {
+		t.Errorf("unexpected reminder type %q", got)
+	}
+
+	if !isReminderDay(models.EventBirthday, 28) || isReminderDay(models.EventWorkAnniversary, 28) {
+		t.Error("birthdays should get a reminder four weeks ahead, work anniversaries should not")
+	}
+
+	tests := []struct {
+		eventType string
+		daysLeft  int
+		want      string
+	}{
+		{models.EventBirthday, 0, "🎉 Happy Birthday, Alice! 🎂"},
+		{models.EventBirthday, 14, "📅 Reminder: Alice's birthday is in 2 weeks (06-01)! 🎈"},
+		{models.EventBirthday, 28, "📅 Early reminder: Alice's birthday is in 4 weeks (06-01)! 🗓️"},
+		{models.EventAnniversary, 0, "💍 Happy anniversary, Alice! 🥂"},
+		{models.EventMemorial, 7, "🕯 The day of remembrance of Alice is in 1 week (06-01)."},
+		{models.EventNameDay, 3, "📅 Reminder: Alice's name day is in 3 days (06-01)! 💐"},
+	}
+	for _, tt := range tests {
+		if got := notificationMessage("en", tt.eventType, tt.daysLeft, "Alice", "06-01"); got != tt.want {
+			t.Errorf("notificationMessage(%s, %d) = %q; want %q", tt.eventType, tt.daysLeft, got, tt.want)
+		}
+	}
+}
+
+func TestFormatEventList(t *testing.T) {
+	now := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	events := []models.Birthday{
+		{Name: "Alice & Bob", Type: models.EventAnniversary, BirthDate: "2015-06-01"},
+		{Name: "Carol", BirthDate: "0000-05-10"},
+	}
+
+	all := formatEventList("en", events, now)
+	if !strings.Contains(all, "May 10: Carol (birthday)\nJune 1: Alice & Bob (wedding anniversary)") {
+		t.Errorf("events should be listed by next occurrence, got %q", all)
+	}
+
+	anniversaries := formatEventList("en", filterEvents(events, models.EventAnniversary), now)
+	if strings.Contains(anniversaries, "Carol") || !strings.Contains(anniversaries, "Alice & Bob") {
+		t.Errorf("expected only anniversaries, got %q", anniversaries)
+	}
+
+	if got := formatEventList("en", nil, now); got != "No upcoming events here yet. Add one with /add_event." {
+		t.Errorf("unexpected empty list %q", got)
+	}
+}
+
+func TestDigestNamesEventTypes(t *testing.T) {
+	monday := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	birthdays := []models.Birthday{
+		{Name: "Alice & Bob", Type: models.EventAnniversary, BirthDate: "2015-06-03", ChatID: 1},
+	}
+
+	entries := digestEntries(birthdays, digestWeekly, monday)
+	if got := formatDigest("en", digestWeekly, entries); got != "🎂 This week: Alice & Bob's wedding anniversary (Tue)" {
+		t.Errorf("unexpected digest %q", got)
+	}
+}
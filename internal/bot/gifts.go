@@ -0,0 +1,412 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// giftCallbackPrefix routes presses of the gift collection join button.
+	giftCallbackPrefix = "gc"
+	// minGiftOffsetDays leaves at least one day between the start of a collection and its summary.
+	minGiftOffsetDays = 2
+	// maxGiftOffsetDays is the longest supported gift collection.
+	maxGiftOffsetDays = 60
+	// giftCollectionNotification is the notification type of gift collection announcements.
+	giftCollectionNotification = "GIFT_COLLECTION"
+	// giftSummaryNotification is the notification type of gift collection summaries.
+	giftSummaryNotification = "GIFT_SUMMARY"
+)
+
+// errCollectionNotFound is returned when a join button refers to an unknown or closed collection.
+var errCollectionNotFound = errors.New("gift collection not found")
+
+// errOwnGiftCollection is returned when the birthday person presses the join button of their own collection.
+var errOwnGiftCollection = errors.New("own gift collection")
+
+// giftCandidates returns the indices of records eligible for a gift collection in the group:
+// members' records linked to the group (records owned by the group itself are the group's own birthday).
+func giftCandidates(birthdays []models.Birthday, chatID int64) []int {
+	var indices []int
+	for i := range birthdays {
+		b := &birthdays[i]
+		if b.ChatID == chatID || b.ChatID <= 0 || !b.IsBirthday() {
+			continue
+		}
+		if idx := b.LinkIndex(chatID); idx >= 0 && b.Links[idx].Notify != models.NotifyMuted {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// giftCallbackData builds the callback data of the join button for the collection of a member's birthday record.
+func giftCallbackData(recordID string, date string) string {
+	return fmt.Sprintf("%s:rec:%s:%s", giftCallbackPrefix, recordID, strings.ReplaceAll(date, "-", ""))
+}
+
+// giftRef identifies the record of a join button: by record ID, or by owner chat for the "in"
+// buttons posted by earlier versions.
+type giftRef struct {
+	recordID string
+	ownerID  int64
+}
+
+// matches reports whether the record is the one the join button refers to.
+func (r giftRef) matches(b *models.Birthday) bool {
+	if r.recordID != "" {
+		return b.ID == r.recordID
+	}
+	return b.ChatID == r.ownerID && b.IsBirthday()
+}
+
+// participantNames returns the comma-separated names of the participants.
+func participantNames(participants []models.GiftParticipant) string {
+	names := make([]string, 0, len(participants))
+	for _, p := range participants {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// giftCollectionText renders the gift collection announcement with its current participants.
+func giftCollectionText(lang, name string, date time.Time, participants []models.GiftParticipant) string {
+	text := i18n.T(lang, "gift.announce", name, describeDate(lang, dateparse.Date{Month: int(date.Month()), Day: date.Day()}))
+	if len(participants) > 0 {
+		text += "\n\n" + i18n.N(lang, "gift.participants", len(participants), participantNames(participants))
+	}
+	return text
+}
+
+// giftKeyboard returns the join button of a gift collection.
+func giftKeyboard(lang string, recordID string, date string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "gift.join"), giftCallbackData(recordID, date)),
+	))
+}
+
+// giftAction is a gift collection message planned by processGiftCollections.
+type giftAction struct {
+	// record is a copy of the birthday record the collection is for.
+	record models.Birthday
+	// collection is the collection to start, or a copy of the one to summarise.
+	collection models.GiftCollection
+	// lang is the language of the chat.
+	lang string
+	// summary indicates whether the participant summary is sent rather than the collection started.
+	summary bool
+}
+
+// processGiftCollections starts gift collections at the configured offset before members' birthdays
+// and posts the participant summary the day before. Collections of past birthdays are dropped.
+// The messages are sent outside the storage lock: summaries are claimed before they are sent
+// and released again if sending fails, started collections are stored afterwards.
+func (b *Bot) processGiftCollections(now time.Time, settings []models.ChatSettings) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	actions, err := planGiftCollections(today, settings)
+	if err != nil {
+		logger.LogNotification("ERROR", "Failed to update gift collections: %v", err)
+		return
+	}
+
+	var started, failed []giftAction
+	for _, a := range actions {
+		if a.summary {
+			if !b.sendGiftSummary(&a.record, &a.collection, a.lang) {
+				failed = append(failed, a)
+			}
+			continue
+		}
+		if messageID, ok := b.startGiftCollection(&a.record, a.collection.ChatID, a.lang, mustParseDate(a.collection.Date)); ok {
+			a.collection.MessageID = messageID
+			started = append(started, a)
+		}
+	}
+	if len(started) == 0 && len(failed) == 0 {
+		return
+	}
+
+	err = storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		for i := range birthdays {
+			for _, a := range started {
+				if birthdays[i].ID == a.record.ID && birthdays[i].GiftCollectionIndex(a.collection.ChatID, a.collection.Date) < 0 {
+					birthdays[i].GiftCollections = append(birthdays[i].GiftCollections, a.collection)
+				}
+			}
+			for _, a := range failed {
+				if birthdays[i].ID != a.record.ID {
+					continue
+				}
+				if idx := birthdays[i].GiftCollectionIndex(a.collection.ChatID, a.collection.Date); idx >= 0 {
+					birthdays[i].GiftCollections[idx].SummarySent = false
+				}
+			}
+		}
+		return birthdays, nil
+	})
+	if err != nil {
+		logger.LogNotification("ERROR", "Failed to update gift collections: %v", err)
+	}
+}
+
+// planGiftCollections drops the collections of past birthdays and returns the collections to start
+// or summarise today. Summaries are marked as sent, so that no one joins after the list went out.
+// The birthday file is only written if something changed.
+func planGiftCollections(today time.Time, settings []models.ChatSettings) ([]giftAction, error) {
+	var actions []giftAction
+	err := storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		actions = nil
+		changed := false
+		for i := range birthdays {
+			active := activeCollections(birthdays[i].GiftCollections, today)
+			if len(active) != len(birthdays[i].GiftCollections) {
+				birthdays[i].GiftCollections = active
+				changed = true
+			}
+		}
+
+		for _, s := range settings {
+			if s.GiftOffsetDays == 0 {
+				continue
+			}
+			lang := i18n.Resolve(s.Language)
+
+			for _, i := range giftCandidates(birthdays, s.ChatID) {
+				record := &birthdays[i]
+				date, ok := nextOccurrence(record.BirthDate, today)
+				if !ok {
+					continue
+				}
+				dateStr := date.Format("2006-01-02")
+				daysLeft := int(date.Sub(today).Hours() / 24)
+				idx := record.GiftCollectionIndex(s.ChatID, dateStr)
+
+				switch {
+				case idx < 0 && daysLeft <= s.GiftOffsetDays && daysLeft > 1:
+					actions = append(actions, giftAction{
+						record:     record.Clone(),
+						collection: models.GiftCollection{ChatID: s.ChatID, Date: dateStr},
+						lang:       lang,
+					})
+				case idx >= 0 && daysLeft <= 1 && !record.GiftCollections[idx].SummarySent:
+					record.GiftCollections[idx].SummarySent = true
+					changed = true
+					actions = append(actions, giftAction{
+						record:     record.Clone(),
+						collection: record.Clone().GiftCollections[idx],
+						lang:       lang,
+						summary:    true,
+					})
+				}
+			}
+		}
+		if !changed {
+			return nil, errNoChange
+		}
+		return birthdays, nil
+	})
+	if errors.Is(err, errNoChange) {
+		err = nil
+	}
+	return actions, err
+}
+
+// activeCollections drops the collections of birthdays that have passed.
+func activeCollections(collections []models.GiftCollection, today time.Time) []models.GiftCollection {
+	var active []models.GiftCollection
+	for _, c := range collections {
+		date, err := time.Parse("2006-01-02", c.Date)
+		if err == nil && date.Before(today) {
+			continue
+		}
+		active = append(active, c)
+	}
+	return active
+}
+
+// startGiftCollection posts the join button for the record's birthday.
+// It returns the ID of the posted message and whether it was sent.
+func (b *Bot) startGiftCollection(record *models.Birthday, chatID int64, lang string, date time.Time) (int, bool) {
+	dateStr := date.Format("2006-01-02")
+	msg := tgbotapi.NewMessage(chatID, giftCollectionText(lang, record.Name, date, nil))
+	msg.ReplyMarkup = giftKeyboard(lang, record.ID, dateStr)
+	sent, err := b.api.Send(msg)
+	recordHistory(giftCollectionNotification, record, chatID, msg.Text, err)
+	if err != nil {
+		logger.LogNotification("ERROR", "Failed to start gift collection for '%s' in ChatID %d: %v", record.Name, chatID, err)
+		return 0, false
+	}
+
+	logger.LogNotification("INFO", "SUCCESS: Gift collection for '%s' started in ChatID %d", record.Name, chatID)
+	return sent.MessageID, true
+}
+
+// sendGiftSummary posts the list of participants and removes the join button.
+// It reports whether the summary was sent.
+func (b *Bot) sendGiftSummary(record *models.Birthday, collection *models.GiftCollection, lang string) bool {
+	text := i18n.T(lang, "gift.summary_empty", record.Name)
+	if len(collection.Participants) > 0 {
+		text = i18n.N(lang, "gift.summary", len(collection.Participants), record.Name, participantNames(collection.Participants))
+	}
+	_, err := b.api.Send(tgbotapi.NewMessage(collection.ChatID, text))
+	recordHistory(giftSummaryNotification, record, collection.ChatID, text, err)
+	if err != nil {
+		logger.LogNotification("ERROR", "Failed to send gift summary for '%s' to ChatID %d: %v", record.Name, collection.ChatID, err)
+		return false
+	}
+
+	// The join button is removed once the summary is out
+	b.editMessage(collection.ChatID, collection.MessageID, giftCollectionText(lang, record.Name, mustParseDate(collection.Date), collection.Participants), nil)
+	logger.LogNotification("INFO", "SUCCESS: Gift summary for '%s' sent to ChatID %d (%d participants)",
+		record.Name, collection.ChatID, len(collection.Participants))
+	return true
+}
+
+// mustParseDate parses a stored YYYY-MM-DD date, returning the zero time for malformed values.
+func mustParseDate(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+// toggleGiftParticipant adds the user to the collection, or removes them if they already joined.
+// It reports whether the user is a participant afterwards and returns the updated collection.
+// The birthday person can't take part in their own collection.
+func toggleGiftParticipant(ref giftRef, chatID int64, date string, user models.GiftParticipant) (joined bool, record models.Birthday, collection models.GiftCollection, err error) {
+	err = storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		for i := range birthdays {
+			if !ref.matches(&birthdays[i]) {
+				continue
+			}
+			// Other records of the same owner may have no collection in this chat
+			idx := birthdays[i].GiftCollectionIndex(chatID, date)
+			if idx < 0 || birthdays[i].GiftCollections[idx].SummarySent {
+				continue
+			}
+			if birthdays[i].ChatID == user.UserID {
+				return nil, errOwnGiftCollection
+			}
+
+			c := &birthdays[i].GiftCollections[idx]
+			joined = true
+			for j, p := range c.Participants {
+				if p.UserID == user.UserID {
+					c.Participants = append(c.Participants[:j], c.Participants[j+1:]...)
+					joined = false
+					break
+				}
+			}
+			if joined {
+				c.Participants = append(c.Participants, user)
+			}
+			record, collection = birthdays[i], *c
+			return birthdays, nil
+		}
+		return nil, errCollectionNotFound
+	})
+	return joined, record, collection, err
+}
+
+// handleGiftCallback joins or leaves a gift collection. The birthday person cannot take part.
+func (b *Bot) handleGiftCallback(query *tgbotapi.CallbackQuery, data string) {
+	chat := query.Message.Chat
+	lang := languageFor(chat, query.From)
+
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || (parts[0] != "rec" && parts[0] != "in") || len(parts[2]) != 8 || query.From == nil {
+		logger.Warn("BOT", "Unknown gift collection action: %s", data)
+		b.answerCallback(query.ID, "", false)
+		return
+	}
+	ref := giftRef{recordID: parts[1]}
+	if parts[0] == "in" {
+		ownerID, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			b.answerCallback(query.ID, "", false)
+			return
+		}
+		ref = giftRef{ownerID: ownerID}
+	}
+	date := parts[2][:4] + "-" + parts[2][4:6] + "-" + parts[2][6:]
+
+	participant := models.GiftParticipant{UserID: query.From.ID, Name: userDisplayName(query.From)}
+	joined, record, collection, err := toggleGiftParticipant(ref, chat.ID, date, participant)
+	if errors.Is(err, errOwnGiftCollection) {
+		b.answerCallback(query.ID, i18n.T(lang, "gift.own_birthday"), true)
+		return
+	}
+	if errors.Is(err, errCollectionNotFound) {
+		b.answerCallback(query.ID, i18n.T(lang, "gift.closed"), true)
+		return
+	}
+	if err != nil {
+		logger.Error("STORAGE", "Failed to update gift collection: %v", err)
+		b.answerCallback(query.ID, i18n.T(lang, "bot.error_save"), true)
+		return
+	}
+
+	if joined {
+		b.answerCallback(query.ID, i18n.T(lang, "gift.joined"), false)
+	} else {
+		b.answerCallback(query.ID, i18n.T(lang, "gift.left"), false)
+	}
+
+	chatLang := i18n.Resolve(chatLanguage(chat.ID))
+	markup := giftKeyboard(chatLang, record.ID, date)
+	b.editMessage(chat.ID, query.Message.MessageID,
+		giftCollectionText(chatLang, record.Name, mustParseDate(collection.Date), collection.Participants), &markup)
+}
+
+func (b *Bot) handleGiftCollectionCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+
+	args = strings.ToLower(strings.TrimSpace(args))
+	if args == "" {
+		settings, err := storage.GetChatSettings(message.Chat.ID)
+		if err != nil {
+			logger.Error("STORAGE", "Failed to load settings of chat %d: %v", message.Chat.ID, err)
+			b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_database"))
+			return
+		}
+		if settings.GiftOffsetDays == 0 {
+			b.sendText(message.Chat.ID, i18n.T(lang, "gift.status_off"))
+		} else {
+			b.sendText(message.Chat.ID, i18n.N(lang, "gift.status_on", settings.GiftOffsetDays))
+		}
+		return
+	}
+
+	days := 0
+	if args != "off" {
+		var err error
+		days, err = strconv.Atoi(args)
+		if err != nil || days < minGiftOffsetDays || days > maxGiftOffsetDays {
+			b.sendText(message.Chat.ID, i18n.T(lang, "gift.usage", minGiftOffsetDays, maxGiftOffsetDays))
+			return
+		}
+	}
+
+	if err := storage.UpdateChatSettings(message.Chat.ID, func(s *models.ChatSettings) { s.GiftOffsetDays = days }); err != nil {
+		logger.Error("STORAGE", "Failed to save gift collection setting of chat %d: %v", message.Chat.ID, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_save"))
+		return
+	}
+
+	logger.Info("BOT", "Gift collection offset of chat %d set to %d days", message.Chat.ID, days)
+	if days == 0 {
+		b.sendText(message.Chat.ID, i18n.T(lang, "gift.status_off"))
+		return
+	}
+	b.sendText(message.Chat.ID, i18n.N(lang, "gift.status_on", days))
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+func TestGiftCandidates(t *testing.T) {
+	birthdays := []models.Birthday{
+		{Name: "Alice", ChatID: 1, Links: []models.ChatLink{{ChatID: -100}}},
+		{Name: "Bob", ChatID: 2, Links: []models.ChatLink{{ChatID: -100, Notify: models.NotifyMuted}}},
+		{Name: "Team", ChatID: -100},
+		{Name: "Carol", ChatID: 3, Links: []models.ChatLink{{ChatID: -200}}},
+	}
+
+	got := giftCandidates(birthdays, -100)
+	if len(got) != 1 || birthdays[got[0]].Name != "Alice" {
+		t.Errorf("only linked, unmuted member records should be candidates, got %v", got)
+	}
+}
+
+func TestGiftCallbackDataFitsTelegramLimit(t *testing.T) {
+	data := giftCallbackData("0123456789abcdef", "2025-12-31")
+	if data != "gc:rec:0123456789abcdef:20251231" {
+		t.Errorf("unexpected callback data %q", data)
+	}
+	if len(data) > 64 {
+		t.Errorf("callback data is %d bytes, Telegram allows 64", len(data))
+	}
+}
+
+func TestActiveCollections(t *testing.T) {
+	today := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	collections := []models.GiftCollection{
+		{ChatID: -100, Date: "2025-06-09"},
+		{ChatID: -100, Date: "2025-06-10"},
+		{ChatID: -100, Date: "2025-06-20"},
+	}
+
+	active := activeCollections(collections, today)
+	if len(active) != 2 || active[0].Date != "2025-06-10" {
+		t.Errorf("collections of past birthdays should be dropped, got %+v", active)
+	}
+}
+
+func TestGiftCollectionText(t *testing.T) {
+	date := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+	participants := []models.GiftParticipant{{UserID: 2, Name: "Bob"}, {UserID: 3, Name: "Carol"}}
+
+	text := giftCollectionText("en", "Alice", date, participants)
+	if !strings.Contains(text, "Alice") || !strings.Contains(text, "In (2): Bob, Carol") {
+		t.Errorf("unexpected collection text: %q", text)
+	}
+	if text := giftCollectionText("en", "Alice", date, nil); strings.Contains(text, "In (") {
+		t.Errorf("collection without participants should not list them: %q", text)
+	}
+}
+
+func TestToggleGiftParticipant(t *testing.T) {
+	os.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	// The chat owns another birthday record without a collection, listed first
+	err := storage.SaveBirthdays([]models.Birthday{
+		{ID: "kid", Name: "Alice's son", BirthDate: "2015-03-01", ChatID: 1},
+		{
+			ID: "alice", Name: "Alice", BirthDate: "1990-06-20", ChatID: 1,
+			Links:           []models.ChatLink{{ChatID: -100}},
+			GiftCollections: []models.GiftCollection{{ChatID: -100, Date: "2025-06-20", MessageID: 7}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+	bob := models.GiftParticipant{UserID: 2, Name: "Bob"}
+	ref := giftRef{recordID: "alice"}
+
+	joined, record, collection, err := toggleGiftParticipant(ref, -100, "2025-06-20", bob)
+	if err != nil || !joined || record.Name != "Alice" || len(collection.Participants) != 1 {
+		t.Fatalf("expected Bob to join, got %t, %+v, %v", joined, collection, err)
+	}
+
+	// Buttons of earlier versions name the owner chat
+	joined, _, collection, err = toggleGiftParticipant(giftRef{ownerID: 1}, -100, "2025-06-20", bob)
+	if err != nil || joined || len(collection.Participants) != 0 {
+		t.Fatalf("pressing again should leave the collection, got %t, %+v, %v", joined, collection, err)
+	}
+
+	alice := models.GiftParticipant{UserID: 1, Name: "Alice"}
+	if _, _, _, err := toggleGiftParticipant(ref, -100, "2025-06-20", alice); !errors.Is(err, errOwnGiftCollection) {
+		t.Errorf("expected errOwnGiftCollection for the birthday person, got %v", err)
+	}
+
+	if _, _, _, err := toggleGiftParticipant(ref, -100, "2025-07-01", bob); !errors.Is(err, errCollectionNotFound) {
+		t.Errorf("expected errCollectionNotFound for an unknown collection, got %v", err)
+	}
+
+	// Collections are closed once the summary is sent
+	err = storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+		bs[1].GiftCollections[0].SummarySent = true
+		return bs, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to close collection: %v", err)
+	}
+	if _, _, _, err := toggleGiftParticipant(ref, -100, "2025-06-20", bob); !errors.Is(err, errCollectionNotFound) {
+		t.Errorf("expected errCollectionNotFound for a closed collection, got %v", err)
+	}
+}
+
+func TestProcessGiftCollectionsSendsOutsideTheLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.yaml")
+	t.Setenv("YAML_PATH", path)
+
+	err := storage.SaveBirthdays([]models.Birthday{
+		{Name: "Alice", BirthDate: "1990-06-20", ChatID: 1, Links: []models.ChatLink{{ChatID: -100}}},
+		{Name: "Bob", BirthDate: "1990-06-11", ChatID: 2, Links: []models.ChatLink{{ChatID: -100}},
+			GiftCollections: []models.GiftCollection{{ChatID: -100, Date: "2025-06-11", MessageID: 7}}},
+		{Name: "Carol", BirthDate: "1990-01-01", ChatID: 3},
+	})
+	if err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+
+	// Every message changes another record; this would block if the storage lock were held while sending
+	b := newFakeTelegramBot(t, func(int64) {
+		done := make(chan error, 1)
+		go func() {
+			done <- storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+				bs[2].Name += "!"
+				return bs, nil
+			})
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("concurrent update failed: %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Error("messages should be sent without holding the storage lock")
+		}
+	})
+	settings := []models.ChatSettings{{ChatID: -100, GiftOffsetDays: 14}}
+	now := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	b.processGiftCollections(now, settings)
+
+	bs, err := storage.LoadBirthdays()
+	if err != nil {
+		t.Fatalf("failed to load birthdays: %v", err)
+	}
+	if len(bs[0].GiftCollections) != 1 || bs[0].GiftCollections[0].MessageID != 1 {
+		t.Errorf("expected a started collection for Alice, got %+v", bs[0].GiftCollections)
+	}
+	if !bs[1].GiftCollections[0].SummarySent {
+		t.Error("Bob's collection should be closed after the summary")
+	}
+	if !strings.HasPrefix(bs[2].Name, "Carol!") {
+		t.Errorf("concurrent changes should be kept, got %q", bs[2].Name)
+	}
+
+	// Nothing is due any more, so the file is left alone
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	b.processGiftCollections(now, settings)
+	if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("the birthday file should not be rewritten without changes: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"time"
+
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+// recordHistory adds a notification attempt to the notification history. err is the error of a
+// failed attempt, or nil if the notification was sent. The record may be nil, e.g. for digests.
+func recordHistory(notificationType string, record *models.Birthday, chatID int64, message string, err error) {
+	entry := models.NotificationEntry{
+		Time:    time.Now().UTC(),
+		Type:    notificationType,
+		ChatID:  chatID,
+		Message: message,
+		Outcome: models.OutcomeSent,
+	}
+	if record != nil {
+		entry.RecordID = record.ID
+		entry.RecordName = record.Name
+	}
+	if err != nil {
+		entry.Outcome = models.OutcomeFailed
+		entry.Error = err.Error()
+	}
+	if err := storage.AppendNotificationHistory(entry); err != nil {
+		logger.LogNotification("ERROR", "Failed to record %s notification to ChatID %d in the history: %v", notificationType, chatID, err)
+	}
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// userError is an error whose text is a message catalog key,
+// so it can be shown to the user in the language of the chat.
+type userError string
+
+func (e userError) Error() string {
+	return string(e)
+}
+
+// chatLanguage returns the language configured for the chat with /language, or an empty string.
+func chatLanguage(chatID int64) string {
+	settings, err := storage.GetChatSettings(chatID)
+	if err != nil {
+		logger.Error("STORAGE", "Failed to load settings of chat %d: %v", chatID, err)
+		return ""
+	}
+	return i18n.Normalize(settings.Language)
+}
+
+// languageFor returns the language for replies in the chat: the language configured for the chat,
+// otherwise the Telegram client language of the user, otherwise the default language.
+func languageFor(chat *tgbotapi.Chat, from *tgbotapi.User) string {
+	if lang := chatLanguage(chat.ID); lang != "" {
+		return lang
+	}
+	if from != nil {
+		return i18n.Resolve(from.LanguageCode)
+	}
+	return i18n.DefaultLanguage
+}
+
+// dateLocale returns the locale that decides the order of day and month in dates typed in the chat:
+// the language configured for the chat, otherwise the Telegram client language of the user.
+func dateLocale(chat *tgbotapi.Chat, from *tgbotapi.User) string {
+	if lang := chatLanguage(chat.ID); lang != "" {
+		return lang
+	}
+	if from != nil {
+		return from.LanguageCode
+	}
+	return ""
+}
+
+// describeDate returns an unambiguous description of the date with the month spelled out,
+// e.g. "December 31, 1999" or "31 декабря 1999".
+func describeDate(lang string, d dateparse.Date) string {
+	month := i18n.T(lang, fmt.Sprintf("month.of.%d", d.Month))
+	if d.Year == 0 {
+		return i18n.T(lang, "date.describe_year_unknown", d.Day, month)
+	}
+	return i18n.T(lang, "date.describe", d.Day, month, d.Year)
+}
+
+// languageList returns the supported languages as "code (name)" pairs for replies.
+func languageList() string {
+	var langs []string
+	for _, code := range i18n.Supported() {
+		langs = append(langs, fmt.Sprintf("%s (%s)", code, i18n.T(code, "language.name")))
+	}
+	return strings.Join(langs, ", ")
+}
+
+func (b *Bot) handleLanguageCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+
+	if args == "" {
+		b.sendText(message.Chat.ID, i18n.T(lang, "language.current", i18n.T(lang, "language.name"), languageList()))
+		return
+	}
+
+	newLang := i18n.Normalize(args)
+	if newLang == "" {
+		b.sendText(message.Chat.ID, i18n.T(lang, "language.unsupported", args, languageList()))
+		return
+	}
+
+	if err := storage.UpdateChatSettings(message.Chat.ID, func(s *models.ChatSettings) { s.Language = newLang }); err != nil {
+		logger.Error("STORAGE", "Failed to save language of chat %d: %v", message.Chat.ID, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_save"))
+		return
+	}
+
+	logger.Info("BOT", "Language of chat %d set to %s", message.Chat.ID, newLang)
+	b.sendText(message.Chat.ID, i18n.T(newLang, "language.set", i18n.T(newLang, "language.name")))
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestLanguageFor(t *testing.T) {
+	os.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	chat := &tgbotapi.Chat{ID: 100, Type: "group"}
+	russian := &tgbotapi.User{ID: 1, LanguageCode: "ru"}
+
+	if got := languageFor(chat, russian); got != "ru" {
+		t.Errorf("expected the user's client language, got %q", got)
+	}
+	if got := languageFor(chat, &tgbotapi.User{ID: 2, LanguageCode: "pt-BR"}); got != "en" {
+		t.Errorf("expected the default language for unsupported client languages, got %q", got)
+	}
+	if got := languageFor(chat, nil); got != "en" {
+		t.Errorf("expected the default language without a user, got %q", got)
+	}
+
+	if err := storage.UpdateChatSettings(chat.ID, func(s *models.ChatSettings) { s.Language = "en" }); err != nil {
+		t.Fatalf("failed to save chat settings: %v", err)
+	}
+	if got := languageFor(chat, russian); got != "en" {
+		t.Errorf("the chat language should take precedence over the client language, got %q", got)
+	}
+}
+
+func TestDateLocaleFollowsChatLanguage(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+
+	chat := &tgbotapi.Chat{ID: 100, Type: "group"}
+	american := &tgbotapi.User{ID: 1, LanguageCode: "en"}
+
+	if got := dateparse.OrderForLocale(dateLocale(chat, american)); got != dateparse.MonthFirst {
+		t.Errorf("expected the client locale without a chat language, got order %v", got)
+	}
+
+	if err := storage.UpdateChatSettings(chat.ID, func(s *models.ChatSettings) { s.Language = "ru" }); err != nil {
+		t.Fatalf("failed to save chat settings: %v", err)
+	}
+	if got := dateparse.OrderForLocale(dateLocale(chat, american)); got != dateparse.DayFirst {
+		t.Errorf("the chat language should decide the date order, got order %v", got)
+	}
+}
+
+func TestDescribeDate(t *testing.T) {
+	tests := []struct {
+		lang string
+		date dateparse.Date
+		want string
+	}{
+		{"en", dateparse.Date{Year: 1999, Month: 12, Day: 31}, "December 31, 1999"},
+		{"en", dateparse.Date{Month: 2, Day: 29}, "February 29 (year unknown)"},
+		{"ru", dateparse.Date{Year: 1999, Month: 12, Day: 31}, "31 декабря 1999"},
+		{"ru", dateparse.Date{Month: 2, Day: 29}, "29 февраля (год неизвестен)"},
+	}
+
+	for _, tt := range tests {
+		if got := describeDate(tt.lang, tt.date); got != tt.want {
+			t.Errorf("describeDate(%q, %+v) = %q; want %q", tt.lang, tt.date, got, tt.want)
+		}
+	}
+}
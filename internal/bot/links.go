@@ -0,0 +1,232 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// followCallbackPrefix routes inline keyboard presses of follow consent requests.
+const followCallbackPrefix = "fw"
+
+// notificationTarget is a chat that announces a birthday, with its own preferences and dedup state.
+type notificationTarget struct {
+	// chatID is the chat to notify.
+	chatID int64
+	// notify is the notification preference (models.NotifyAll, NotifyGreeting or NotifyMuted).
+	notify string
+	// lastNotification points at the dedup timestamp stored in the record.
+	lastNotification *time.Time
+}
+
+// wants reports whether the target should receive a notification of the given type.
+func (t notificationTarget) wants(notificationType string) bool {
+	switch t.notify {
+	case models.NotifyMuted:
+		return false
+	case models.NotifyGreeting:
+		return isGreeting(notificationType)
+	default:
+		return true
+	}
+}
+
+// notificationTargets returns the owner chat of the record followed by its linked chats.
+func notificationTargets(b *models.Birthday) []notificationTarget {
+	var targets []notificationTarget
+	if b.ChatID != 0 {
+		targets = append(targets, notificationTarget{chatID: b.ChatID, lastNotification: &b.LastNotification})
+	}
+	for i := range b.Links {
+		l := &b.Links[i]
+		if l.ChatID == 0 || l.ChatID == b.ChatID {
+			continue
+		}
+		targets = append(targets, notificationTarget{chatID: l.ChatID, notify: l.Notify, lastNotification: &l.LastNotification})
+	}
+	return targets
+}
+
+// followTarget returns the user whose birthday the /follow or /unfollow command refers to:
+// the author of the replied-to message, or the sender.
+func followTarget(message *tgbotapi.Message) *tgbotapi.User {
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil && !message.ReplyToMessage.From.IsBot {
+		return message.ReplyToMessage.From
+	}
+	return message.From
+}
+
+// userDisplayName returns the full name of the user, or the username if no name is set.
+func userDisplayName(u *tgbotapi.User) string {
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.UserName
+}
+
+// linkChat links the birthday record of the user's private chat to the group.
+// It reports whether the user has a record at all.
+func linkChat(userID int64, chat *tgbotapi.Chat) (bool, error) {
+	var before, after models.Birthday
+	found := false
+	err := storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		for i := range birthdays {
+			if birthdays[i].ChatID != userID || !birthdays[i].IsBirthday() {
+				continue
+			}
+			found = true
+			before = birthdays[i].Clone()
+			if idx := birthdays[i].LinkIndex(chat.ID); idx >= 0 {
+				birthdays[i].Links[idx].Title = chat.Title
+			} else {
+				birthdays[i].Links = append(birthdays[i].Links, models.ChatLink{ChatID: chat.ID, Title: chat.Title})
+			}
+			after = birthdays[i]
+			return birthdays, nil
+		}
+		return nil, errNoChange
+	})
+	if errors.Is(err, errNoChange) {
+		return false, nil
+	}
+	if err != nil {
+		return found, fmt.Errorf("failed to save birthdays: %w", err)
+	}
+	audit.Updated(audit.TelegramActor(userID), models.SourceBot, before, after)
+	logger.Info("BOT", "Linked birthday of '%s' (Chat ID: %d) to chat %d", after.Name, userID, chat.ID)
+	return true, nil
+}
+
+// unlinkChat removes the links of the user's birthday record to the chat on behalf of the actor.
+// It reports whether a link was removed.
+func unlinkChat(userID, chatID int64, actor string) (bool, error) {
+	var before, after []models.Birthday
+	err := storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		for i := range birthdays {
+			if birthdays[i].ChatID != userID || !birthdays[i].IsBirthday() {
+				continue
+			}
+			if idx := birthdays[i].LinkIndex(chatID); idx >= 0 {
+				before = append(before, birthdays[i].Clone())
+				birthdays[i].Links = append(birthdays[i].Links[:idx], birthdays[i].Links[idx+1:]...)
+				after = append(after, birthdays[i])
+			}
+		}
+		if len(before) == 0 {
+			return nil, errNoChange
+		}
+		return birthdays, nil
+	})
+	if errors.Is(err, errNoChange) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save birthdays: %w", err)
+	}
+	for i := range before {
+		audit.Updated(actor, models.SourceBot, before[i], after[i])
+	}
+	return true, nil
+}
+
+// handleFollowCommand asks a group member to share their birthday with the group.
+// Members can follow themselves; replying to someone else's message asks them for consent.
+func (b *Bot) handleFollowCommand(message *tgbotapi.Message) {
+	lang := languageFor(message.Chat, message.From)
+	target := followTarget(message)
+	if target == nil {
+		return
+	}
+
+	userData := strconv.FormatInt(target.ID, 10)
+	msg := tgbotapi.NewMessage(message.Chat.ID, i18n.T(lang, "follow.consent", userDisplayName(target), message.Chat.Title))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "follow.accept"), followCallbackPrefix+":ok:"+userData),
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "follow.decline"), followCallbackPrefix+":no:"+userData),
+	))
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Error("BOT", "Failed to send follow request: %v", err)
+	}
+}
+
+// handleFollowCallback records or declines the consent of the member asked by /follow.
+func (b *Bot) handleFollowCallback(query *tgbotapi.CallbackQuery, data string) {
+	chat := query.Message.Chat
+	messageID := query.Message.MessageID
+	lang := languageFor(chat, query.From)
+
+	action, userData, _ := strings.Cut(data, ":")
+	userID, err := strconv.ParseInt(userData, 10, 64)
+	if err != nil || query.From == nil || query.From.ID != userID {
+		b.answerCallback(query.ID, i18n.T(lang, "follow.not_owner"), true)
+		return
+	}
+
+	switch action {
+	case "no":
+		b.answerCallback(query.ID, "", false)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "follow.declined", userDisplayName(query.From)), nil)
+
+	case "ok":
+		found, err := linkChat(userID, chat)
+		if err != nil {
+			logger.Error("STORAGE", "Failed to link birthday of user %d to chat %d: %v", userID, chat.ID, err)
+			b.answerCallback(query.ID, "", false)
+			b.editMessage(chat.ID, messageID, i18n.T(lang, "bot.error_save"), nil)
+			return
+		}
+		if !found {
+			b.answerCallback(query.ID, i18n.T(lang, "follow.no_record", b.GetUsername()), true)
+			return
+		}
+
+		logger.LogAudit("CHAT_LINKED", userID, "shared birthday with chat %d (%s)", chat.ID, chat.Title)
+		b.answerCallback(query.ID, "", false)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "follow.accepted", userDisplayName(query.From), chat.Title), nil)
+
+	default:
+		logger.Warn("BOT", "Unknown follow action: %s", data)
+		b.answerCallback(query.ID, "", false)
+	}
+}
+
+// handleUnfollowCommand stops announcing a member's birthday in the group.
+// Members can unfollow themselves; chat administrators can reply to a member's message to unfollow them.
+func (b *Bot) handleUnfollowCommand(message *tgbotapi.Message) {
+	lang := languageFor(message.Chat, message.From)
+	target := followTarget(message)
+	if target == nil {
+		return
+	}
+
+	if target.ID != message.From.ID && !b.canManageChat(message.Chat, message.From.ID) {
+		logger.LogAudit("PERMISSION_DENIED", message.From.ID, "user @%s tried to unfollow user %d in chat %d",
+			message.From.UserName, target.ID, message.Chat.ID)
+		b.sendText(message.Chat.ID, i18n.T(lang, "follow.unfollow_not_admin"))
+		return
+	}
+
+	removed, err := unlinkChat(target.ID, message.Chat.ID, audit.TelegramActor(message.From.ID))
+	if err != nil {
+		logger.Error("STORAGE", "Failed to unlink birthday of user %d from chat %d: %v", target.ID, message.Chat.ID, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_save"))
+		return
+	}
+	if !removed {
+		b.sendText(message.Chat.ID, i18n.T(lang, "follow.not_following", userDisplayName(target)))
+		return
+	}
+
+	logger.LogAudit("CHAT_UNLINKED", message.From.ID, "unlinked birthday of user %d from chat %d", target.ID, message.Chat.ID)
+	b.sendText(message.Chat.ID, i18n.T(lang, "follow.unfollowed", userDisplayName(target)))
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestNotificationTargets(t *testing.T) {
+	b := models.Birthday{Name: "Alice", ChatID: 1, Links: []models.ChatLink{
+		{ChatID: -100},
+		{ChatID: -200, Notify: models.NotifyGreeting},
+		{ChatID: -300, Notify: models.NotifyMuted},
+		{ChatID: 1}, // duplicate of the owner chat is ignored
+	}}
+
+	targets := notificationTargets(&b)
+	if len(targets) != 4 {
+		t.Fatalf("expected 4 targets, got %d", len(targets))
+	}
+
+	wants := map[int64][2]bool{ // chat -> {greeting, reminder}
+		1:    {true, true},
+		-100: {true, true},
+		-200: {true, false},
+		-300: {false, false},
+	}
+	for _, target := range targets {
+		want := wants[target.chatID]
+		if got := target.wants("BIRTHDAY_TODAY"); got != want[0] {
+			t.Errorf("chat %d wants greeting = %t; want %t", target.chatID, got, want[0])
+		}
+		if got := target.wants("REMINDER_2_WEEKS"); got != want[1] {
+			t.Errorf("chat %d wants reminder = %t; want %t", target.chatID, got, want[1])
+		}
+	}
+
+	// Dedup state is written back to the record
+	now := time.Now()
+	*targets[1].lastNotification = now
+	if !b.Links[0].LastNotification.Equal(now) {
+		t.Error("target dedup state should point into the record's link")
+	}
+}
+
+func TestVisibleBirthdaysIncludesLinkedRecords(t *testing.T) {
+	birthdays := []models.Birthday{
+		{Name: "Alice", ChatID: 1, Links: []models.ChatLink{{ChatID: -100}}},
+		{Name: "Bob", ChatID: 2, Links: []models.ChatLink{{ChatID: -100, Notify: models.NotifyMuted}}},
+		{Name: "Team", ChatID: -100},
+	}
+
+	visible := visibleBirthdays(birthdays, -100, tagRoutes{})
+	if len(visible) != 2 || visible[0].Name != "Alice" || visible[1].Name != "Team" {
+		t.Errorf("unexpected visible birthdays: %+v", visible)
+	}
+}
+
+func TestLinkAndUnlinkChat(t *testing.T) {
+	os.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	if err := storage.SaveBirthdays([]models.Birthday{{Name: "Alice", BirthDate: "1990-05-01", ChatID: 1}}); err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+	group := &tgbotapi.Chat{ID: -100, Type: "group", Title: "Team"}
+
+	if found, err := linkChat(2, group); err != nil || found {
+		t.Errorf("linking a user without a record should report not found, got %t, %v", found, err)
+	}
+	for i := 0; i < 2; i++ {
+		if found, err := linkChat(1, group); err != nil || !found {
+			t.Fatalf("link failed: %t, %v", found, err)
+		}
+	}
+
+	birthdays, _ := storage.LoadBirthdays()
+	if len(birthdays[0].Links) != 1 || birthdays[0].Links[0].Title != "Team" {
+		t.Fatalf("expected a single link to the group, got %+v", birthdays[0].Links)
+	}
+
+	if removed, err := unlinkChat(1, -100, "telegram:1"); err != nil || !removed {
+		t.Fatalf("unlink failed: %t, %v", removed, err)
+	}
+	if removed, _ := unlinkChat(1, -100, "telegram:1"); removed {
+		t.Error("unlinking twice should report nothing removed")
+	}
+}
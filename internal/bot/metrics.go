@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	"5mdt/bd_bot/internal/metrics"
+)
+
+// Reasons for skipping records in a birthday check pass, used as metric labels.
+const (
+	skipNoChat        = "no_chat"
+	skipInvalidFormat = "invalid_format"
+	skipInvalidDate   = "invalid_date"
+	skipNotSent       = "not_sent"
+)
+
+// instrumentedClient records the latency and errors of Telegram Bot API requests.
+type instrumentedClient struct {
+	client *http.Client
+}
+
+// Do sends the request and observes its duration by API method, the last element of the URL path.
+// Transport errors and HTTP error statuses count as errors.
+func (c *instrumentedClient) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	metrics.TelegramRequestDuration.ObserveSince(start, method)
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		metrics.TelegramErrors.Inc(method)
+	}
+	return resp, err
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/metrics"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+func TestInstrumentedClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/botTOKEN/sendMessage" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	client := &instrumentedClient{client: server.Client()}
+	calls := metrics.TelegramRequestDuration.Count("getMe")
+	errors := metrics.TelegramErrors.Value("sendMessage")
+
+	for _, method := range []string{"getMe", "sendMessage"} {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/botTOKEN/"+method, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if got := metrics.TelegramRequestDuration.Count("getMe"); got != calls+1 {
+		t.Errorf("getMe latency observations = %d, want %d", got, calls+1)
+	}
+	if got := metrics.TelegramErrors.Value("sendMessage"); got != errors+1 {
+		t.Errorf("sendMessage errors = %v, want %v", got, errors+1)
+	}
+	if got := metrics.TelegramErrors.Value("getMe"); got != 0 {
+		t.Errorf("getMe errors = %v, want 0", got)
+	}
+}
+
+func TestCheckPassRecordsMetrics(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	err := storage.SaveBirthdays([]models.Birthday{
+		{ID: "a", Name: "No chat", BirthDate: "1990-01-01"},
+		{ID: "b", Name: "Bad format", BirthDate: "someday", ChatID: 1},
+		{ID: "c", Name: "Bad date", BirthDate: "1990-13-45", ChatID: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := map[string]float64{}
+	for _, reason := range []string{skipNoChat, skipInvalidFormat, skipInvalidDate} {
+		skipped[reason] = metrics.RecordsSkipped.Value(reason)
+	}
+
+	b := newFakeTelegramBot(t, nil)
+	b.notificationStartHour, b.notificationEndHour = 0, 23
+	b.processBirthdays()
+
+	if got := metrics.RecordsLoaded.Value(); got != 3 {
+		t.Errorf("records loaded = %v, want 3", got)
+	}
+	for reason, before := range skipped {
+		if got := metrics.RecordsSkipped.Value(reason); got != before+1 {
+			t.Errorf("records skipped for %s = %v, want %v", reason, got, before+1)
+		}
+	}
+}
+
+func TestCheckPassSummaryCountsSentNotifications(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	today := time.Now().UTC()
+	err := storage.SaveBirthdays([]models.Birthday{
+		{ID: "a", Name: "Today", BirthDate: today.AddDate(-30, 0, 0).Format("2006-01-02"), ChatID: 1},
+		{ID: "b", Name: "Not due", BirthDate: today.AddDate(-30, 0, 100).Format("2006-01-02"), ChatID: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	b := newFakeTelegramBot(t, nil)
+	b.notificationStartHour, b.notificationEndHour = 0, 23
+	b.processBirthdays()
+
+	if want := "SUMMARY: Processed=2, Sent=1, Skipped=0"; !strings.Contains(logs.String(), want) {
+		t.Errorf("summary missing %q in logs:\n%s", want, logs.String())
+	}
+}
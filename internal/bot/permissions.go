@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// adminCacheTTL is how long the administrator list of a chat is reused before it is fetched again.
+const adminCacheTTL = 10 * time.Minute
+
+// adminCacheEntry is a cached administrator list of a single chat.
+type adminCacheEntry struct {
+	// admins is the set of user IDs that administer the chat.
+	admins map[int64]bool
+	// fetchedAt is when the list was retrieved from Telegram.
+	fetchedAt time.Time
+}
+
+// adminCache caches chat administrator lists fetched with getChatAdministrators.
+type adminCache struct {
+	mu      sync.Mutex
+	entries map[int64]adminCacheEntry
+}
+
+func newAdminCache() *adminCache {
+	return &adminCache{entries: make(map[int64]adminCacheEntry)}
+}
+
+// get returns the cached administrators of the chat if they are still fresh.
+func (c *adminCache) get(chatID int64, now time.Time) (map[int64]bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[chatID]
+	if !ok || now.Sub(entry.fetchedAt) > adminCacheTTL {
+		return nil, false
+	}
+	return entry.admins, true
+}
+
+// set stores the administrators of the chat.
+func (c *adminCache) set(chatID int64, admins map[int64]bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[chatID] = adminCacheEntry{admins: admins, fetchedAt: now}
+}
+
+// parseUserIDs parses a comma-separated list of Telegram user IDs.
+// Invalid entries are logged and skipped.
+func parseUserIDs(value string) map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			logger.Warn("BOT", "Ignoring invalid user ID in BOT_SUPER_ADMINS: %s", part)
+			continue
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+// superAdminsFromEnv reads the super-admin user IDs from the BOT_SUPER_ADMINS environment variable.
+func superAdminsFromEnv() map[int64]bool {
+	return parseUserIDs(os.Getenv("BOT_SUPER_ADMINS"))
+}
+
+// isSuperAdmin reports whether the user may manage any chat regardless of chat membership.
+func (b *Bot) isSuperAdmin(userID int64) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.superAdmins[userID]
+}
+
+// chatAdmins returns the administrators of the chat, using the cache when possible.
+func (b *Bot) chatAdmins(chatID int64) (map[int64]bool, error) {
+	now := time.Now()
+	if admins, ok := b.adminCache.get(chatID, now); ok {
+		return admins, nil
+	}
+
+	members, err := b.api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	admins := make(map[int64]bool, len(members))
+	for _, member := range members {
+		if member.User != nil {
+			admins[member.User.ID] = true
+		}
+	}
+	b.adminCache.set(chatID, admins, now)
+	logger.Debug("BOT", "Cached %d administrators for chat ID: %d", len(admins), chatID)
+	return admins, nil
+}
+
+// canManageChat reports whether the user may change the data of the chat.
+// Super-admins may manage every chat, users always manage their private chat with the bot,
+// and in groups the user must be a chat administrator.
+func (b *Bot) canManageChat(chat *tgbotapi.Chat, userID int64) bool {
+	if b.isSuperAdmin(userID) {
+		return true
+	}
+	if scopeOf(chat) == scopePrivate {
+		return chat.ID == userID
+	}
+
+	admins, err := b.chatAdmins(chat.ID)
+	if err != nil {
+		logger.Error("BOT", "Failed to get administrators of chat %d: %v", chat.ID, err)
+		return false
+	}
+	return admins[userID]
+}
+
+// IsChatAdmin reports whether the user may manage the records of the chat in the web interface:
+// their private chat with the bot, any chat for super-admins, and groups they administer.
+// Returns false if the bot is nil.
+func (b *Bot) IsChatAdmin(chatID, userID int64) bool {
+	if b == nil {
+		return false
+	}
+	if b.isSuperAdmin(userID) || chatID == userID {
+		return true
+	}
+	if chatID > 0 {
+		return false
+	}
+
+	admins, err := b.chatAdmins(chatID)
+	if err != nil {
+		logger.Error("BOT", "Failed to get administrators of chat %d: %v", chatID, err)
+		return false
+	}
+	return admins[userID]
+}
+
+// hasPermission reports whether the sender of the message may run the command.
+func (b *Bot) hasPermission(cmd *command, message *tgbotapi.Message) bool {
+	if cmd.permission == permAnyone {
+		return true
+	}
+	if message.From == nil {
+		return false
+	}
+	return b.canManageChat(message.Chat, message.From.ID)
+}
+
+// denyPermission replies to a rejected command and records the attempt in the audit log.
+func (b *Bot) denyPermission(cmd *command, message *tgbotapi.Message) {
+	var userID int64
+	var username string
+	if message.From != nil {
+		userID = message.From.ID
+		username = message.From.UserName
+	}
+	logger.LogAudit("PERMISSION_DENIED", userID, "user @%s tried /%s in chat %d (%s)",
+		username, cmd.name, message.Chat.ID, message.Chat.Type)
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, i18n.T(languageFor(message.Chat, message.From), "bot.permission_denied", cmd.name))
+	msg.ReplyToMessageID = message.MessageID
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Error("BOT", "Failed to send message: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParseUserIDs(t *testing.T) {
+	ids := parseUserIDs(" 123, 456 ,not-a-number,,789")
+
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 IDs, got %d: %v", len(ids), ids)
+	}
+	for _, id := range []int64{123, 456, 789} {
+		if !ids[id] {
+			t.Errorf("expected ID %d to be parsed", id)
+		}
+	}
+}
+
+func TestAdminCacheExpiry(t *testing.T) {
+	cache := newAdminCache()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache.set(-100, map[int64]bool{1: true}, now)
+
+	if admins, ok := cache.get(-100, now.Add(adminCacheTTL-time.Second)); !ok || !admins[1] {
+		t.Error("expected fresh cache entry to be returned")
+	}
+	if _, ok := cache.get(-100, now.Add(adminCacheTTL+time.Second)); ok {
+		t.Error("expected stale cache entry to be ignored")
+	}
+	if _, ok := cache.get(-200, now); ok {
+		t.Error("expected unknown chat to miss the cache")
+	}
+}
+
+func TestCanManageChat(t *testing.T) {
+	bot := &Bot{
+		superAdmins: map[int64]bool{999: true},
+		adminCache:  newAdminCache(),
+	}
+	bot.adminCache.set(-100, map[int64]bool{1: true}, time.Now())
+
+	group := &tgbotapi.Chat{ID: -100, Type: "supergroup"}
+	private := &tgbotapi.Chat{ID: 2, Type: "private"}
+
+	tests := []struct {
+		name   string
+		chat   *tgbotapi.Chat
+		userID int64
+		want   bool
+	}{
+		{"group admin", group, 1, true},
+		{"group member", group, 2, false},
+		{"super-admin in foreign group", group, 999, true},
+		{"private chat owner", private, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bot.canManageChat(tt.chat, tt.userID); got != tt.want {
+				t.Errorf("canManageChat() = %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsChatAdmin(t *testing.T) {
+	bot := &Bot{
+		superAdmins: map[int64]bool{999: true},
+		adminCache:  newAdminCache(),
+	}
+	bot.adminCache.set(-100, map[int64]bool{1: true}, time.Now())
+
+	tests := []struct {
+		name           string
+		chatID, userID int64
+		want           bool
+	}{
+		{"group admin", -100, 1, true},
+		{"group member", -100, 2, false},
+		{"own private chat", 2, 2, true},
+		{"someone else's private chat", 1, 2, false},
+		{"super-admin", 1, 999, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bot.IsChatAdmin(tt.chatID, tt.userID); got != tt.want {
+				t.Errorf("IsChatAdmin() = %t; want %t", got, tt.want)
+			}
+		})
+	}
+
+	var nilBot *Bot
+	if nilBot.IsChatAdmin(2, 2) {
+		t.Error("nil bot should not grant access")
+	}
+}
+
+func TestHasPermissionForMutatingCommands(t *testing.T) {
+	bot := &Bot{adminCache: newAdminCache()}
+	bot.adminCache.set(-100, map[int64]bool{1: true}, time.Now())
+
+	cmd, ok := defaultCommands().lookup("update_birth_date")
+	if !ok {
+		t.Fatal("update_birth_date is not registered")
+	}
+
+	member := &tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: -100, Type: "group"},
+		From: &tgbotapi.User{ID: 2},
+	}
+	if bot.hasPermission(cmd, member) {
+		t.Error("regular group members must not update the group's birth date")
+	}
+
+	admin := &tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: -100, Type: "group"},
+		From: &tgbotapi.User{ID: 1},
+	}
+	if !bot.hasPermission(cmd, admin) {
+		t.Error("group administrators must be able to update the group's birth date")
+	}
+}
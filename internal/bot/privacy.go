@@ -0,0 +1,366 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// forgetCallbackPrefix routes inline keyboard presses of the /forget_me confirmation.
+const forgetCallbackPrefix = "fm"
+
+// chatDataArg is the argument of /export_my_data and /forget_me that selects the data of the whole
+// group instead of the caller's own data; only chat administrators may use it.
+const chatDataArg = "chat"
+
+// dataExport is the JSON document sent by /export_my_data.
+type dataExport struct {
+	// ExportedAt is when the export was generated.
+	ExportedAt time.Time `json:"exported_at"`
+	// ChatID is the chat the exported data belongs to. Personal exports are about the caller's
+	// private chat with the bot, whose ID is the caller's user ID.
+	ChatID int64 `json:"chat_id"`
+	// Birthdays are the birthday records tied to the chat.
+	Birthdays []models.Birthday `json:"birthdays"`
+	// LinkedRecords are the records of other chats that are also announced in the chat.
+	LinkedRecords []linkedRecord `json:"linked_records,omitempty"`
+	// GiftCollections are the gift collections the user joined.
+	GiftCollections []giftParticipation `json:"gift_collections,omitempty"`
+	// Settings are the chat settings, if any were configured.
+	Settings *models.ChatSettings `json:"settings,omitempty"`
+}
+
+// linkedRecord is a record of another chat that is announced in the exported chat,
+// because it follows the record or subscribes to one of its tags.
+type linkedRecord struct {
+	// RecordID is the ID of the record.
+	RecordID string `json:"record_id"`
+	// Name is the name of the record.
+	Name string `json:"name"`
+	// Link is the link to the chat, with its preferences and dedup state.
+	Link models.ChatLink `json:"link"`
+	// Routed is true for records announced through a tag subscription rather than a link.
+	Routed bool `json:"routed,omitempty"`
+}
+
+// giftParticipation is the participation of the user in the gift collection of another record.
+type giftParticipation struct {
+	// RecordID is the ID of the record the collection is for.
+	RecordID string `json:"record_id"`
+	// ChatID is the group chat the collection runs in.
+	ChatID int64 `json:"chat_id"`
+	// Date is the birthday the collection is for.
+	Date string `json:"date"`
+	// Participant is how the user joined the collection.
+	Participant models.GiftParticipant `json:"participant"`
+}
+
+// buildDataExport collects everything stored about the chat. For private chats, whose ID is the ID
+// of the user, it includes the gift collections the user joined in groups.
+func buildDataExport(chatID int64, now time.Time) (dataExport, error) {
+	export := dataExport{ExportedAt: now.UTC(), ChatID: chatID, Birthdays: []models.Birthday{}}
+
+	birthdays, err := storage.LoadBirthdays()
+	if err != nil {
+		return export, fmt.Errorf("failed to load birthdays: %w", err)
+	}
+	for _, b := range birthdays {
+		if b.ChatID == chatID {
+			export.Birthdays = append(export.Birthdays, b)
+			continue
+		}
+		if idx := b.LinkIndex(chatID); idx >= 0 {
+			export.LinkedRecords = append(export.LinkedRecords, linkedRecord{RecordID: b.ID, Name: b.Name, Link: b.Links[idx]})
+		}
+		for _, route := range b.Routes {
+			if route.ChatID == chatID {
+				export.LinkedRecords = append(export.LinkedRecords, linkedRecord{RecordID: b.ID, Name: b.Name, Link: route, Routed: true})
+			}
+		}
+		for _, c := range b.GiftCollections {
+			for _, p := range c.Participants {
+				if p.UserID == chatID {
+					export.GiftCollections = append(export.GiftCollections,
+						giftParticipation{RecordID: b.ID, ChatID: c.ChatID, Date: c.Date, Participant: p})
+				}
+			}
+		}
+	}
+
+	settings, err := storage.LoadChatSettings()
+	if err != nil {
+		return export, fmt.Errorf("failed to load chat settings: %w", err)
+	}
+	for i := range settings {
+		if settings[i].ChatID == chatID {
+			export.Settings = &settings[i]
+			break
+		}
+	}
+	return export, nil
+}
+
+// forgetChat deletes all birthday records, deleted records in the trash and settings tied to the chat,
+// and the links, tag routes and gift collections of other records in the chat, on behalf of the actor.
+// For private chats, whose ID is the ID of the user, it also removes the user from gift collections.
+// It returns the number of deleted birthday records.
+func forgetChat(chatID int64, actor string) (int, error) {
+	var deletedRecords, before, after []models.Birthday
+	err := storage.UpdateBirthdays(func(birthdays []models.Birthday) ([]models.Birthday, error) {
+		kept := birthdays[:0]
+		for _, b := range birthdays {
+			if b.ChatID == chatID {
+				deletedRecords = append(deletedRecords, b)
+				continue
+			}
+			if original, changed := forgetInRecord(&b, chatID); changed {
+				before, after = append(before, original), append(after, b)
+			}
+			kept = append(kept, b)
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save birthdays: %w", err)
+	}
+	for _, b := range deletedRecords {
+		audit.Deleted(actor, models.SourceBot, b)
+	}
+	for i := range before {
+		audit.Updated(actor, models.SourceBot, before[i], after[i])
+	}
+
+	// Deleted records of the chat must not linger in the trash either
+	err = storage.UpdateTrash(func(ts []models.TrashedRecord) ([]models.TrashedRecord, error) {
+		kept := ts[:0]
+		for _, t := range ts {
+			if t.ChatID != chatID {
+				kept = append(kept, t)
+			}
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return len(deletedRecords), fmt.Errorf("failed to purge the trash: %w", err)
+	}
+
+	// So must the notifications sent to the chat or about its records
+	deletedIDs := make(map[string]bool, len(deletedRecords))
+	for _, b := range deletedRecords {
+		deletedIDs[b.ID] = true
+	}
+	_, err = storage.DeleteNotificationHistory(func(e models.NotificationEntry) bool {
+		return e.ChatID == chatID || (e.RecordID != "" && deletedIDs[e.RecordID])
+	})
+	if err != nil {
+		return len(deletedRecords), fmt.Errorf("failed to delete notification history: %w", err)
+	}
+
+	if _, err := storage.DeleteChatSettings(chatID); err != nil {
+		return len(deletedRecords), fmt.Errorf("failed to delete chat settings: %w", err)
+	}
+
+	// The audit log keeps that the records were deleted, but no longer what they contained
+	recordIDs := make([]string, 0, len(deletedIDs))
+	for id := range deletedIDs {
+		recordIDs = append(recordIDs, id)
+	}
+	if _, err := audit.Forget(chatID, recordIDs); err != nil {
+		return len(deletedRecords), fmt.Errorf("failed to redact the audit log: %w", err)
+	}
+	return len(deletedRecords), nil
+}
+
+// forgetInRecord removes the link, the tag route and the gift collections of the chat from a record of
+// another chat, and the user with the chat's ID from its gift collections. It returns the record as it
+// was before and whether it changed.
+func forgetInRecord(b *models.Birthday, chatID int64) (models.Birthday, bool) {
+	original := b.Clone()
+	changed := false
+	if idx := b.LinkIndex(chatID); idx >= 0 {
+		b.Links = append(b.Links[:idx], b.Links[idx+1:]...)
+		changed = true
+	}
+	for j, route := range b.Routes {
+		if route.ChatID == chatID {
+			b.Routes = append(b.Routes[:j], b.Routes[j+1:]...)
+			changed = true
+			break
+		}
+	}
+
+	var collections []models.GiftCollection
+	for _, c := range b.GiftCollections {
+		if c.ChatID == chatID {
+			changed = true
+			continue
+		}
+		participants := c.Participants[:0]
+		for _, p := range c.Participants {
+			if p.UserID == chatID {
+				changed = true
+				continue
+			}
+			participants = append(participants, p)
+		}
+		c.Participants = participants
+		collections = append(collections, c)
+	}
+	b.GiftCollections = collections
+	return original, changed
+}
+
+// privacySubject returns the chat whose data /export_my_data or /forget_me acts on: the caller's private
+// chat, whose ID is the caller's user ID, or the current group with the chat argument. It reports false
+// and replies if the caller may not act on the group.
+func (b *Bot) privacySubject(message *tgbotapi.Message, args, command string) (int64, bool) {
+	if message.From == nil {
+		return 0, false
+	}
+	if strings.TrimSpace(strings.ToLower(args)) != chatDataArg || scopeOf(message.Chat) == scopePrivate {
+		return message.From.ID, true
+	}
+	if !b.canManageChat(message.Chat, message.From.ID) {
+		b.denyPermission(b.commands.byName[command], message)
+		return 0, false
+	}
+	return message.Chat.ID, true
+}
+
+func (b *Bot) handleExportMyDataCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+	subject, ok := b.privacySubject(message, args, "export_my_data")
+	if !ok {
+		return
+	}
+
+	export, err := buildDataExport(subject, time.Now())
+	if err != nil {
+		logger.Error("STORAGE", "Failed to export data of chat %d: %v", subject, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_database"))
+		return
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		logger.Error("BOT", "Failed to encode data export: %v", err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_database"))
+		return
+	}
+
+	// Personal data is only ever sent to the private chat with the caller
+	caption := i18n.N(lang, "privacy.export_caption_chat", len(export.Birthdays))
+	if subject == message.From.ID {
+		caption = i18n.N(lang, "privacy.export_caption", len(export.Birthdays))
+	}
+	doc := tgbotapi.NewDocument(subject, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("bd_bot_export_%d.json", subject),
+		Bytes: data,
+	})
+	doc.Caption = caption
+	if _, err := b.api.Send(doc); err != nil {
+		logger.Error("BOT", "Failed to send data export to chat %d: %v", subject, err)
+		if subject != message.Chat.ID {
+			b.sendText(message.Chat.ID, i18n.T(lang, "privacy.export_start_private"))
+		}
+		return
+	}
+	if subject != message.Chat.ID {
+		b.sendText(message.Chat.ID, i18n.T(lang, "privacy.export_sent_private"))
+	}
+
+	actor := audit.TelegramActor(message.From.ID)
+	for _, record := range export.Birthdays {
+		audit.Exported(actor, models.SourceBot, record)
+	}
+	logger.LogAudit("DATA_EXPORTED", message.From.ID, "exported %d records of chat %d from chat %d (%s)",
+		len(export.Birthdays), subject, message.Chat.ID, message.Chat.Type)
+}
+
+func (b *Bot) handleForgetMeCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+	subject, ok := b.privacySubject(message, args, "forget_me")
+	if !ok {
+		return
+	}
+
+	// The caller's user ID is embedded so only they can confirm the deletion,
+	// and the scope so that the confirmation deletes what was asked for
+	text, scope := i18n.T(lang, "privacy.forget_confirm"), "me"
+	if subject != message.From.ID {
+		text, scope = i18n.T(lang, "privacy.forget_confirm_chat"), chatDataArg
+	}
+	userData := strconv.FormatInt(message.From.ID, 10) + ":" + scope
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.delete"), forgetCallbackPrefix+":confirm:"+userData),
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "button.cancel"), forgetCallbackPrefix+":cancel:"+userData),
+	))
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Error("BOT", "Failed to send deletion confirmation: %v", err)
+	}
+}
+
+// handleForgetCallback completes or cancels a /forget_me request.
+func (b *Bot) handleForgetCallback(query *tgbotapi.CallbackQuery, data string) {
+	chat := query.Message.Chat
+	messageID := query.Message.MessageID
+	lang := languageFor(chat, query.From)
+
+	action, userData, _ := strings.Cut(data, ":")
+	userData, scope, _ := strings.Cut(userData, ":")
+	userID, err := strconv.ParseInt(userData, 10, 64)
+	if err != nil || query.From == nil || query.From.ID != userID {
+		b.answerCallback(query.ID, i18n.T(lang, "privacy.not_owner"), true)
+		return
+	}
+
+	switch action {
+	case "cancel":
+		b.answerCallback(query.ID, "", false)
+		b.editMessage(chat.ID, messageID, i18n.T(lang, "privacy.forget_cancelled"), nil)
+
+	case "confirm":
+		subject, done := userID, "privacy.forget_done"
+		if scope == chatDataArg {
+			// Admin rights may have changed since the command was sent
+			if !b.canManageChat(chat, query.From.ID) {
+				logger.LogAudit("PERMISSION_DENIED", query.From.ID, "user @%s tried to delete the data of chat %d",
+					query.From.UserName, chat.ID)
+				b.answerCallback(query.ID, i18n.T(lang, "privacy.not_admin"), true)
+				return
+			}
+			subject, done = chat.ID, "privacy.forget_done_chat"
+		}
+
+		deleted, err := forgetChat(subject, audit.TelegramActor(query.From.ID))
+		if err != nil {
+			logger.Error("STORAGE", "Failed to delete data of chat %d: %v", subject, err)
+			b.answerCallback(query.ID, "", false)
+			b.editMessage(chat.ID, messageID, i18n.T(lang, "bot.error_save"), nil)
+			return
+		}
+		b.conversations.finish(subject)
+
+		logger.LogAudit("DATA_DELETED", query.From.ID, "deleted %d records and settings of chat %d from chat %d (%s)",
+			deleted, subject, chat.ID, chat.Type)
+		b.answerCallback(query.ID, "", false)
+		// Settings may be gone, so the reply may fall back to the user's client language
+		lang = languageFor(chat, query.From)
+		b.editMessage(chat.ID, messageID, i18n.N(lang, done, deleted), nil)
+
+	default:
+		logger.Warn("BOT", "Unknown forget_me action: %s", data)
+		b.answerCallback(query.ID, "", false)
+	}
+}
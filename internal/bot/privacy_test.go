@@ -0,0 +1,189 @@
+package bot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func setupPrivacyStorage(t *testing.T) {
+	t.Helper()
+	os.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	t.Cleanup(func() { os.Unsetenv("YAML_PATH") })
+
+	if err := storage.SaveBirthdays([]models.Birthday{
+		{Name: "Alice", BirthDate: "1990-05-01", ChatID: 1},
+		{Name: "Team", BirthDate: "0000-03-15", ChatID: -100},
+		{Name: "Alice again", BirthDate: "1990-05-01", ChatID: 1},
+	}); err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+	if err := storage.SaveChatSettings([]models.ChatSettings{{ChatID: 1, Language: "ru"}, {ChatID: -100, Language: "en"}}); err != nil {
+		t.Fatalf("failed to save chat settings: %v", err)
+	}
+}
+
+func TestBuildDataExport(t *testing.T) {
+	setupPrivacyStorage(t)
+
+	export, err := buildDataExport(1, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if len(export.Birthdays) != 2 {
+		t.Fatalf("expected 2 records of the chat, got %d", len(export.Birthdays))
+	}
+	if export.Settings == nil || export.Settings.Language != "ru" {
+		t.Errorf("expected chat settings in the export, got %+v", export.Settings)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("failed to encode export: %v", err)
+	}
+	for _, field := range []string{`"exported_at":"2025-01-02T03:04:05Z"`, `"birth_date":"1990-05-01"`, `"language":"ru"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("export JSON is missing %s: %s", field, data)
+		}
+	}
+	if strings.Contains(string(data), "Team") {
+		t.Error("export must not contain records of other chats")
+	}
+}
+
+func TestExportMyDataIsAudited(t *testing.T) {
+	setupPrivacyStorage(t)
+	b := newFakeTelegramBot(t, nil)
+
+	b.handleExportMyDataCommand(&tgbotapi.Message{
+		From: &tgbotapi.User{ID: 1, FirstName: "Alice"},
+		Chat: &tgbotapi.Chat{ID: 1, Type: "private"},
+	}, "")
+
+	entries, err := storage.LoadAuditLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected an audit entry per exported record, got %+v", entries)
+	}
+	for _, e := range entries {
+		if e.Action != models.AuditExport || e.Actor != "telegram:1" || e.Source != models.SourceBot || !strings.HasPrefix(e.RecordName, "Alice") {
+			t.Errorf("unexpected audit entry %+v", e)
+		}
+	}
+}
+
+func TestForgetChat(t *testing.T) {
+	setupPrivacyStorage(t)
+	if err := storage.AddToTrash("telegram:1", models.Birthday{ID: "x", Name: "Old", ChatID: 1}, models.Birthday{ID: "y", Name: "Kept", ChatID: -100}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := storage.LoadBirthdays()
+	err := storage.AppendNotificationHistory(
+		models.NotificationEntry{Type: "BIRTHDAY_TODAY", RecordID: before[0].ID, ChatID: 1},
+		models.NotificationEntry{Type: "BIRTHDAY_TODAY", RecordID: before[0].ID, ChatID: -100},
+		models.NotificationEntry{Type: "DIGEST_WEEKLY", ChatID: 1},
+		models.NotificationEntry{Type: "BIRTHDAY_TODAY", RecordID: before[1].ID, ChatID: -100},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := forgetChat(1, "telegram:1")
+	if err != nil {
+		t.Fatalf("forget failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted records, got %d", deleted)
+	}
+
+	birthdays, _ := storage.LoadBirthdays()
+	if len(birthdays) != 1 || birthdays[0].ChatID != -100 {
+		t.Errorf("records of other chats should be kept, got %+v", birthdays)
+	}
+	settings, _ := storage.LoadChatSettings()
+	if len(settings) != 1 || settings[0].ChatID != -100 {
+		t.Errorf("settings of other chats should be kept, got %+v", settings)
+	}
+	trash, _ := storage.LoadTrash()
+	if len(trash) != 1 || trash[0].ChatID != -100 {
+		t.Errorf("deleted records of the chat should be purged from the trash, got %+v", trash)
+	}
+	history, _ := storage.LoadNotificationHistory()
+	if len(history) != 1 || history[0].RecordID != before[1].ID {
+		t.Errorf("notifications sent to the chat or about its records should be removed, got %+v", history)
+	}
+	entries, _ := storage.LoadAuditLog()
+	for _, e := range entries {
+		if strings.Contains(e.RecordName, "Alice") || e.Actor == "telegram:1" {
+			t.Errorf("the audit log should not keep data of the forgotten chat, got %+v", e)
+		}
+	}
+
+	if deleted, err := forgetChat(1, "telegram:1"); err != nil || deleted != 0 {
+		t.Errorf("forgetting an unknown chat should be a no-op, got %d, %v", deleted, err)
+	}
+}
+
+func TestForgetGroupRemovesLinks(t *testing.T) {
+	setupPrivacyStorage(t)
+	if err := storage.SaveBirthdays([]models.Birthday{
+		{Name: "Alice", BirthDate: "1990-05-01", ChatID: 1, Links: []models.ChatLink{{ChatID: -100}, {ChatID: -200}}},
+		{Name: "Team", BirthDate: "0000-03-15", ChatID: -100},
+	}); err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+
+	if _, err := forgetChat(-100, "telegram:1"); err != nil {
+		t.Fatalf("forget failed: %v", err)
+	}
+
+	birthdays, _ := storage.LoadBirthdays()
+	if len(birthdays) != 1 || len(birthdays[0].Links) != 1 || birthdays[0].Links[0].ChatID != -200 {
+		t.Errorf("links to the forgotten group should be removed, got %+v", birthdays)
+	}
+}
+
+func TestUserDataOnOtherRecords(t *testing.T) {
+	setupPrivacyStorage(t)
+	if err := storage.SaveBirthdays([]models.Birthday{
+		{Name: "Alice", BirthDate: "1990-05-01", ChatID: 1},
+		{Name: "Bob", BirthDate: "1991-06-01", ChatID: 2, Links: []models.ChatLink{{ChatID: 1, Notify: models.NotifyGreeting}},
+			GiftCollections: []models.GiftCollection{{ChatID: -100, Date: "2025-06-01",
+				Participants: []models.GiftParticipant{{UserID: 1, Name: "Alice"}, {UserID: 3, Name: "Carol"}}}}},
+	}); err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+
+	export, err := buildDataExport(1, time.Now())
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if len(export.LinkedRecords) != 1 || export.LinkedRecords[0].Name != "Bob" {
+		t.Errorf("expected the record linked to the user's chat in the export, got %+v", export.LinkedRecords)
+	}
+	if len(export.GiftCollections) != 1 || export.GiftCollections[0].ChatID != -100 {
+		t.Errorf("expected the user's gift participation in the export, got %+v", export.GiftCollections)
+	}
+
+	if _, err := forgetChat(1, "telegram:1"); err != nil {
+		t.Fatalf("forget failed: %v", err)
+	}
+	birthdays, _ := storage.LoadBirthdays()
+	if len(birthdays) != 1 || len(birthdays[0].Links) != 0 {
+		t.Fatalf("the link to the user's chat should be removed, got %+v", birthdays)
+	}
+	participants := birthdays[0].GiftCollections[0].Participants
+	if len(participants) != 1 || participants[0].UserID != 3 {
+		t.Errorf("the user should be removed from gift collections, got %+v", participants)
+	}
+}
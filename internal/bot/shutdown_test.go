@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownWaitsForCurrentIteration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bot := &Bot{ctx: ctx, cancel: cancel}
+
+	finished := false
+	bot.wg.Add(1)
+	go func() {
+		defer bot.wg.Done()
+		<-bot.ctx.Done()
+		// Simulate finishing a pass that was in progress when shutdown started
+		time.Sleep(20 * time.Millisecond)
+		finished = true
+	}()
+
+	if err := bot.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if !finished {
+		t.Error("Shutdown should wait for running goroutines")
+	}
+	if got := bot.GetStatus(); got != "stopped" {
+		t.Errorf("status = %q, want stopped", got)
+	}
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bot := &Bot{ctx: ctx, cancel: cancel}
+
+	release := make(chan struct{})
+	defer close(release)
+	bot.wg.Add(1)
+	go func() {
+		defer bot.wg.Done()
+		<-release
+	}()
+
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelTimeout()
+	if err := bot.Shutdown(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/metrics"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+// loadStats loads the lifetime statistics and counts the start of the bot.
+// If the stats file can't be read, statistics are only kept in memory so that the file is not overwritten.
+func (b *Bot) loadStats() {
+	stats, err := storage.LoadBotStats()
+	if err != nil {
+		logger.Error("STORAGE", "Failed to load bot statistics, they won't be saved: %v", err)
+	}
+
+	b.mu.Lock()
+	b.persistStats = err == nil
+	b.mu.Unlock()
+
+	b.updateStats(func(st *models.BotStats) {
+		*st = stats
+		if st.FirstStart.IsZero() {
+			st.FirstStart = time.Now().UTC()
+		}
+		st.Starts++
+	})
+}
+
+// updateStats applies update to the statistics and saves them.
+// The file is written outside mu, so that status queries don't wait for the disk.
+func (b *Bot) updateStats(update func(st *models.BotStats)) {
+	b.mu.Lock()
+	update(&b.stats)
+	persist := b.persistStats
+	b.mu.Unlock()
+	if !persist {
+		return
+	}
+
+	// The copy is taken after statsSaveMu is acquired, so that concurrent updates can't save an older state last
+	b.statsSaveMu.Lock()
+	defer b.statsSaveMu.Unlock()
+	b.mu.RLock()
+	stats := b.stats
+	stats.SentByType = make(map[string]int64, len(b.stats.SentByType))
+	for t, n := range b.stats.SentByType {
+		stats.SentByType[t] = n
+	}
+	b.mu.RUnlock()
+
+	if err := storage.SaveBotStats(stats); err != nil {
+		logger.Error("STORAGE", "Failed to save bot statistics: %v", err)
+	}
+}
+
+// countNotification records a sent notification of the given type and returns the total number sent.
+func (b *Bot) countNotification(notificationType string) int64 {
+	metrics.NotificationsSent.Inc(notificationType)
+	var total int64
+	b.updateStats(func(st *models.BotStats) {
+		st.NotificationsSent++
+		if st.SentByType == nil {
+			st.SentByType = make(map[string]int64)
+		}
+		st.SentByType[notificationType]++
+		st.LastSent = time.Now().UTC()
+		total = st.NotificationsSent
+	})
+	return total
+}
+
+// countFailedNotification records a notification of the given type to the chat that could not be sent.
+func (b *Bot) countFailedNotification(notificationType string, chatID int64, err error) {
+	metrics.NotificationsFailed.Inc(notificationType)
+	b.updateStats(func(st *models.BotStats) {
+		st.NotificationsFailed++
+		st.LastError = fmt.Sprintf("%s to chat %d: %v", notificationType, chatID, err)
+		st.LastErrorAt = time.Now().UTC()
+	})
+}
+
+// GetNotificationsFailed returns the total number of notifications that could not be sent, across restarts.
+// Returns 0 if the bot is nil.
+func (b *Bot) GetNotificationsFailed() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.stats.NotificationsFailed
+}
+
+// GetNotificationsByType returns the number of notifications sent per notification type, across restarts.
+// Returns nil if the bot is nil.
+func (b *Bot) GetNotificationsByType() map[string]int64 {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	byType := make(map[string]int64, len(b.stats.SentByType))
+	for t, n := range b.stats.SentByType {
+		byType[t] = n
+	}
+	return byType
+}
+
+// GetLastNotification returns when the last notification was sent, or the zero time if none was.
+// Returns the zero time if the bot is nil.
+func (b *Bot) GetLastNotification() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.stats.LastSent
+}
+
+// GetLastError returns the error of the last notification that could not be sent and when it happened.
+// Returns an empty string and the zero time if no notification failed or the bot is nil.
+func (b *Bot) GetLastError() (string, time.Time) {
+	if b == nil {
+		return "", time.Time{}
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.stats.LastError, b.stats.LastErrorAt
+}
+
+// GetFirstStart returns when the bot was started for the first time, i.e. since when statistics are counted.
+// Returns the zero time if the bot is nil.
+func (b *Bot) GetFirstStart() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.stats.FirstStart
+}
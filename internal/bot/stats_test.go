@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"5mdt/bd_bot/internal/storage"
+)
+
+func TestStatsPersistAcrossRestarts(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	first := &Bot{}
+	first.loadStats()
+	first.countNotification("BIRTHDAY_TODAY")
+	first.countNotification("REMINDER_2_WEEKS")
+	first.countNotification("BIRTHDAY_TODAY")
+	first.countFailedNotification("DIGEST_WEEKLY", -100, errors.New("Forbidden: bot was kicked"))
+
+	// A restarted bot continues counting
+	second := &Bot{}
+	second.loadStats()
+	if got := second.GetNotificationsSent(); got != 3 {
+		t.Errorf("sent = %d, want 3", got)
+	}
+	if got := second.GetNotificationsFailed(); got != 1 {
+		t.Errorf("failed = %d, want 1", got)
+	}
+	if got := second.GetNotificationsByType()["BIRTHDAY_TODAY"]; got != 2 {
+		t.Errorf("BIRTHDAY_TODAY = %d, want 2", got)
+	}
+	if second.GetLastNotification().IsZero() {
+		t.Error("last notification time should be kept")
+	}
+	lastError, at := second.GetLastError()
+	if !strings.Contains(lastError, "DIGEST_WEEKLY to chat -100: Forbidden") || at.IsZero() {
+		t.Errorf("last error = %q at %v", lastError, at)
+	}
+	if !second.GetFirstStart().Equal(first.GetFirstStart()) {
+		t.Error("first start should be kept")
+	}
+
+	stats, err := storage.LoadBotStats()
+	if err != nil {
+		t.Fatalf("LoadBotStats() error = %v", err)
+	}
+	if stats.Starts != 2 {
+		t.Errorf("starts = %d, want 2", stats.Starts)
+	}
+}
+
+func TestConcurrentStatsUpdatesSaveTheLatestState(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+
+	b := &Bot{}
+	b.loadStats()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.countNotification("BIRTHDAY_TODAY")
+			b.GetNotificationsByType()
+		}()
+	}
+	wg.Wait()
+
+	stats, err := storage.LoadBotStats()
+	if err != nil {
+		t.Fatalf("LoadBotStats() error = %v", err)
+	}
+	if stats.NotificationsSent != 20 || stats.SentByType["BIRTHDAY_TODAY"] != 20 {
+		t.Errorf("saved stats = %+v, want 20 notifications", stats)
+	}
+}
+
+func TestStatsNotSavedIfFileUnreadable(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	path := filepath.Join(tmp, "bot_stats.yaml")
+	if err := os.WriteFile(path, []byte("not: [valid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Bot{}
+	b.loadStats()
+	if got := b.countNotification("BIRTHDAY_TODAY"); got != 1 {
+		t.Errorf("counted %d, want 1", got)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "not: [valid" {
+		t.Error("unreadable stats file should not be overwritten")
+	}
+}
+
+func TestStatsGettersOnNilBot(t *testing.T) {
+	var b *Bot
+	if b.GetNotificationsFailed() != 0 || b.GetNotificationsByType() != nil || !b.GetFirstStart().IsZero() {
+		t.Error("nil bot should report empty statistics")
+	}
+}
@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// upcomingDays is how far ahead /upcoming looks.
+const upcomingDays = 30
+
+// tagRoutes holds the tag subscriptions of the chats.
+type tagRoutes struct {
+	// chats maps each tag to the chats subscribed to it with /tags.
+	chats map[string][]int64
+	// subscribers maps each subscribed chat to the user who set its tags.
+	subscribers map[int64]int64
+	// manages reports whether the user manages the chat.
+	manages func(chatID, userID int64) bool
+}
+
+// tagChats collects the tag subscriptions of the chats. Records are only routed to a chat if the
+// user who subscribed it manages the record's own chat or one of its linked chats, so that a tag
+// can't be used to read the records of chats the user has no say in.
+func (b *Bot) tagChats(settings []models.ChatSettings) tagRoutes {
+	routes := tagRoutes{
+		chats:       make(map[string][]int64),
+		subscribers: make(map[int64]int64),
+		manages:     b.IsChatAdmin,
+	}
+	for _, s := range settings {
+		if s.TagsSetBy == 0 {
+			continue
+		}
+		routes.subscribers[s.ChatID] = s.TagsSetBy
+		for _, tag := range s.Tags {
+			routes.chats[tag] = append(routes.chats[tag], s.ChatID)
+		}
+	}
+	return routes
+}
+
+// allows reports whether the record may be routed to the chat: the user who subscribed the chat
+// must manage the record's chat or one of its linked chats.
+func (r tagRoutes) allows(b *models.Birthday, chatID int64) bool {
+	userID := r.subscribers[chatID]
+	if userID == 0 || r.manages == nil {
+		return false
+	}
+	if b.ChatID != 0 && r.manages(b.ChatID, userID) {
+		return true
+	}
+	for _, l := range b.Links {
+		if r.manages(l.ChatID, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// routedChats returns the chats subscribed to any of the record's tags that may receive it,
+// in tag order without duplicates.
+func routedChats(b *models.Birthday, routes tagRoutes) []int64 {
+	var routed []int64
+	seen := make(map[int64]bool)
+	for _, tag := range b.Tags {
+		for _, chatID := range routes.chats[tag] {
+			if !seen[chatID] {
+				seen[chatID] = true
+				if routes.allows(b, chatID) {
+					routed = append(routed, chatID)
+				}
+			}
+		}
+	}
+	return routed
+}
+
+// routedTargets returns the chats that announce the record because they subscribe to one of its tags,
+// skipping chats already among the targets (so a muted link is never overridden). The record's routes
+// are rebuilt to hold the dedup state of exactly these chats.
+func routedTargets(b *models.Birthday, routes tagRoutes, targets []notificationTarget) []notificationTarget {
+	existing := make(map[int64]bool)
+	for _, target := range targets {
+		existing[target.chatID] = true
+	}
+
+	var kept []models.ChatLink
+	for _, chatID := range routedChats(b, routes) {
+		if existing[chatID] {
+			continue
+		}
+		route := models.ChatLink{ChatID: chatID}
+		for _, old := range b.Routes {
+			if old.ChatID == chatID {
+				route = old
+				break
+			}
+		}
+		kept = append(kept, route)
+	}
+	b.Routes = kept
+
+	routed := make([]notificationTarget, 0, len(b.Routes))
+	for i := range b.Routes {
+		routed = append(routed, notificationTarget{chatID: b.Routes[i].ChatID, lastNotification: &b.Routes[i].LastNotification})
+	}
+	return routed
+}
+
+// chatEvents returns the records announced in the chat, including those routed to it by tag.
+func (b *Bot) chatEvents(chatID int64) ([]models.Birthday, error) {
+	birthdays, err := storage.LoadBirthdays()
+	if err != nil {
+		return nil, err
+	}
+	settings, err := storage.LoadChatSettings()
+	if err != nil {
+		return nil, err
+	}
+	return visibleBirthdays(birthdays, chatID, b.tagChats(settings)), nil
+}
+
+// filterTagged returns the records labelled with the tag, or all records for an empty tag.
+func filterTagged(birthdays []models.Birthday, tag string) []models.Birthday {
+	if tag == "" {
+		return birthdays
+	}
+	var filtered []models.Birthday
+	for _, b := range birthdays {
+		if b.HasTag(tag) {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered
+}
+
+// upcomingEvents returns the records whose next occurrence is within the given number of days.
+func upcomingEvents(birthdays []models.Birthday, now time.Time, days int) []models.Birthday {
+	last := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+	var upcoming []models.Birthday
+	for _, b := range birthdays {
+		if date, ok := nextOccurrence(b.BirthDate, now); ok && !date.After(last) {
+			upcoming = append(upcoming, b)
+		}
+	}
+	return upcoming
+}
+
+// handleUpcomingCommand lists the events of the next days announced in the chat, optionally only
+// those with a tag: /upcoming or /upcoming team-backend.
+func (b *Bot) handleUpcomingCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+
+	tag := ""
+	if tags := models.ParseTags(args); len(tags) > 0 {
+		tag = tags[0]
+	}
+
+	events, err := b.chatEvents(message.Chat.ID)
+	if err != nil {
+		logger.Error("STORAGE", "Failed to load events of chat %d: %v", message.Chat.ID, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_database"))
+		return
+	}
+
+	now := time.Now().UTC()
+	upcoming := upcomingEvents(filterTagged(events, tag), now, upcomingDays)
+	if len(upcoming) == 0 {
+		if tag != "" {
+			b.sendText(message.Chat.ID, i18n.N(lang, "upcoming.none_tagged", upcomingDays, tag))
+		} else {
+			b.sendText(message.Chat.ID, i18n.N(lang, "upcoming.none", upcomingDays))
+		}
+		return
+	}
+	b.sendText(message.Chat.ID, formatEventList(lang, upcoming, now))
+}
+
+// describeTags returns the tags as "#tag" words for replies.
+func describeTags(tags []string) string {
+	words := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		words = append(words, "#"+tag)
+	}
+	return strings.Join(words, " ")
+}
+
+// handleTagsCommand sets the tags routed to the chat: records with any of them are announced here.
+// Without arguments it shows the current tags; "off" clears them.
+func (b *Bot) handleTagsCommand(message *tgbotapi.Message, args string) {
+	lang := languageFor(message.Chat, message.From)
+	args = strings.TrimSpace(args)
+
+	if args == "" {
+		settings, err := storage.GetChatSettings(message.Chat.ID)
+		if err != nil {
+			logger.Error("STORAGE", "Failed to load settings of chat %d: %v", message.Chat.ID, err)
+			b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_database"))
+			return
+		}
+		b.sendText(message.Chat.ID, tagsStatus(lang, settings.Tags))
+		return
+	}
+
+	var tags []string
+	var setBy int64
+	if strings.ToLower(args) != "off" {
+		tags = models.ParseTags(args)
+		setBy = message.From.ID
+	}
+	err := storage.UpdateChatSettings(message.Chat.ID, func(s *models.ChatSettings) {
+		s.Tags, s.TagsSetBy = tags, setBy
+	})
+	if err != nil {
+		logger.Error("STORAGE", "Failed to save tags of chat %d: %v", message.Chat.ID, err)
+		b.sendText(message.Chat.ID, i18n.T(lang, "bot.error_save"))
+		return
+	}
+
+	logger.Info("BOT", "Tags routed to chat %d set to %v", message.Chat.ID, tags)
+	b.sendText(message.Chat.ID, tagsStatus(lang, tags))
+}
+
+// tagsStatus describes the tags routed to the chat.
+func tagsStatus(lang string, tags []string) string {
+	if len(tags) == 0 {
+		return i18n.T(lang, "tags.none")
+	}
+	return i18n.T(lang, "tags.current", describeTags(tags))
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+)
+
+// testTagRoutes returns the tag subscriptions of the chats, all set by user 1 who manages only their own chat.
+func testTagRoutes(settings []models.ChatSettings) tagRoutes {
+	b := &Bot{}
+	routes := b.tagChats(settings)
+	routes.manages = func(chatID, userID int64) bool { return chatID == userID }
+	return routes
+}
+
+func TestRoutedTargets(t *testing.T) {
+	settings := []models.ChatSettings{
+		{ChatID: -100, Tags: []string{"team-backend"}, TagsSetBy: 1},
+		{ChatID: -200, Tags: []string{"team-backend", "family"}, TagsSetBy: 1},
+		{ChatID: -300, Tags: []string{"family"}, TagsSetBy: 1},
+	}
+	routes := testTagRoutes(settings)
+
+	sent := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	b := models.Birthday{
+		Name: "Alice", ChatID: 1, Tags: []string{"team-backend"},
+		Links:  []models.ChatLink{{ChatID: -100, Notify: models.NotifyMuted}},
+		Routes: []models.ChatLink{{ChatID: -200, LastNotification: sent}, {ChatID: -300}},
+	}
+
+	targets := notificationTargets(&b)
+	routed := routedTargets(&b, routes, targets)
+
+	// The muted link wins over the tag route, and the stale route to -300 is dropped
+	if len(routed) != 1 || routed[0].chatID != -200 {
+		t.Fatalf("expected a single route to -200, got %+v", routed)
+	}
+	if len(b.Routes) != 1 || !b.Routes[0].LastNotification.Equal(sent) {
+		t.Errorf("routes should keep the dedup state of remaining chats, got %+v", b.Routes)
+	}
+
+	now := sent.Add(24 * time.Hour)
+	*routed[0].lastNotification = now
+	if !b.Routes[0].LastNotification.Equal(now) {
+		t.Error("route dedup state should point into the record")
+	}
+}
+
+func TestVisibleBirthdaysIncludesTaggedRecords(t *testing.T) {
+	birthdays := []models.Birthday{
+		{Name: "Alice", ChatID: 1, Tags: []string{"team-backend"}},
+		{Name: "Bob", ChatID: 2, Tags: []string{"team-backend"}, Links: []models.ChatLink{{ChatID: -100, Notify: models.NotifyMuted}}},
+		{Name: "Carol", ChatID: 3, Tags: []string{"family"}},
+	}
+
+	routes := testTagRoutes([]models.ChatSettings{{ChatID: -100, Tags: []string{"team-backend"}, TagsSetBy: 1}})
+	routes.manages = func(chatID, userID int64) bool { return chatID > 0 }
+
+	visible := visibleBirthdays(birthdays, -100, routes)
+	if len(visible) != 1 || visible[0].Name != "Alice" {
+		t.Errorf("expected only Alice to be routed to the chat, got %+v", visible)
+	}
+}
+
+func TestTagsOnlyRouteRecordsOfManagedChats(t *testing.T) {
+	birthdays := []models.Birthday{
+		{Name: "Alice", ChatID: 1, Tags: []string{"family"}},
+		{Name: "Bob", ChatID: 2, Tags: []string{"family"}},
+		{Name: "Carol", ChatID: 3, Tags: []string{"family"}, Links: []models.ChatLink{{ChatID: 1}}},
+	}
+
+	// User 1 subscribed their chat to "family": they only get their own record and the one linked to their chat
+	routes := testTagRoutes([]models.ChatSettings{{ChatID: 1, Tags: []string{"family"}, TagsSetBy: 1}})
+	visible := visibleBirthdays(birthdays, 1, routes)
+	if len(visible) != 2 || visible[0].Name != "Alice" || visible[1].Name != "Carol" {
+		t.Errorf("records of unmanaged chats should not be routed, got %+v", visible)
+	}
+	if got := routedChats(&birthdays[1], routes); len(got) != 0 {
+		t.Errorf("Bob's record should not be routed, got %v", got)
+	}
+
+	// Subscriptions without a known subscriber route nothing
+	routes = testTagRoutes([]models.ChatSettings{{ChatID: 1, Tags: []string{"family"}}})
+	if got := routedChats(&birthdays[0], routes); len(got) != 0 {
+		t.Errorf("legacy subscriptions should route nothing, got %v", got)
+	}
+}
+
+func TestUpcomingEventsByTag(t *testing.T) {
+	now := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	birthdays := []models.Birthday{
+		{Name: "Alice", BirthDate: "1990-05-20", Tags: []string{"family"}},
+		{Name: "Bob", BirthDate: "1990-05-25", Tags: []string{"team-backend"}},
+		{Name: "Carol", BirthDate: "1990-08-01", Tags: []string{"family"}},
+	}
+
+	upcoming := upcomingEvents(filterTagged(birthdays, "family"), now, upcomingDays)
+	if len(upcoming) != 1 || upcoming[0].Name != "Alice" {
+		t.Errorf("expected only Alice within %d days, got %+v", upcomingDays, upcoming)
+	}
+	if got := upcomingEvents(filterTagged(birthdays, ""), now, upcomingDays); len(got) != 2 {
+		t.Errorf("expected two upcoming events without a tag, got %+v", got)
+	}
+}
@@ -0,0 +1,266 @@
+// Package dateparse parses birth dates typed by people in the many formats they actually use:
+// ISO dates, numeric dates with dots or slashes, and month names in several languages.
+// Ambiguous day/month orders are resolved using the reader's locale.
+package dateparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Order is the preferred order of day and month in numeric dates.
+type Order int
+
+const (
+	// DayFirst reads 01/02 as 1 February (most of the world).
+	DayFirst Order = iota
+	// MonthFirst reads 01/02 as January 2 (United States and a few others).
+	MonthFirst
+)
+
+// ErrUnrecognized is returned when the input does not look like a date in any supported format.
+var ErrUnrecognized = errors.New("unrecognized date format")
+
+// ErrInvalidDate is returned when the input has a supported format but is not a valid calendar date.
+var ErrInvalidDate = errors.New("invalid date")
+
+// ErrYearOutOfRange is returned when the year of the date is before MinYear or in the future.
+var ErrYearOutOfRange = errors.New("year out of range")
+
+// MinYear is the earliest year accepted in a date.
+const MinYear = 1900
+
+// Date is a parsed birth date. Year is 0 when the year is unknown.
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// Result is the outcome of parsing a date.
+type Result struct {
+	Date
+	// Canonical is true when the input was already in the YYYY-MM-DD or MM-DD format.
+	Canonical bool
+	// Ambiguous is true when the day and month could be swapped and the locale decided the order.
+	Ambiguous bool
+}
+
+var (
+	isoDateRegex  = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	isoMMDDRegex  = regexp.MustCompile(`^(\d{2})-(\d{2})$`)
+	numericRegex  = regexp.MustCompile(`^(\d{1,4})([./\- ])(\d{1,2})(?:([./\- ])(\d{1,4}))?$`)
+	ordinalRegex  = regexp.MustCompile(`(\d+)(?:st|nd|rd|th|er|º|ª|-го|-е)(\s|,|$)`)
+	separatorsRun = regexp.MustCompile(`[\s,]+`)
+)
+
+// monthFirstRegions lists regions that write numeric dates month first.
+var monthFirstRegions = map[string]bool{"us": true, "ph": true, "fm": true, "mh": true, "pw": true}
+
+// OrderForLocale returns the numeric date order for a language tag such as "en", "en-GB" or "ru".
+// Telegram reports plain "en" for English users, which is treated as US English.
+func OrderForLocale(tag string) Order {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	lang, region, _ := strings.Cut(tag, "-")
+	if region != "" {
+		if monthFirstRegions[region] {
+			return MonthFirst
+		}
+		return DayFirst
+	}
+	if lang == "en" {
+		return MonthFirst
+	}
+	return DayFirst
+}
+
+// Parse interprets the input as a date, with or without a year.
+// ISO formats (YYYY-MM-DD and MM-DD) always take precedence; other numeric dates use the given order
+// unless only one reading is valid. Dates written with dots are always read day first.
+func Parse(input string, order Order) (Result, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	if s == "" {
+		return Result{}, ErrUnrecognized
+	}
+
+	if m := isoDateRegex.FindStringSubmatch(s); m != nil {
+		return build(atoi(m[1]), atoi(m[2]), atoi(m[3]), true, false)
+	}
+	if m := isoMMDDRegex.FindStringSubmatch(s); m != nil {
+		return build(0, atoi(m[1]), atoi(m[2]), true, false)
+	}
+
+	s = cleanup(s)
+	if m := numericRegex.FindStringSubmatch(s); m != nil {
+		return parseNumeric(m, order)
+	}
+	return parseWords(s)
+}
+
+// cleanup removes decorations that carry no date information, such as ordinal suffixes,
+// filler words and Russian year abbreviations.
+func cleanup(s string) string {
+	s = ordinalRegex.ReplaceAllString(s, "$1$2")
+	s = separatorsRun.ReplaceAllString(s, " ")
+
+	var words []string
+	for _, w := range strings.Split(s, " ") {
+		w = strings.TrimSuffix(w, ".")
+		switch w {
+		case "", "of", "de", "del", "the", "г", "год", "года", "р", "року":
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " ")
+}
+
+func parseNumeric(m []string, order Order) (Result, error) {
+	first, sep, second, third := m[1], m[2], m[3], m[5]
+	if m[4] != "" && m[4] != sep {
+		return Result{}, ErrUnrecognized
+	}
+
+	// Year first: YYYY.MM.DD or YYYY/MM/DD
+	if len(first) == 4 {
+		if third == "" {
+			return Result{}, ErrUnrecognized
+		}
+		return build(atoi(first), atoi(second), atoi(third), false, false)
+	}
+	if len(first) > 2 {
+		return Result{}, ErrUnrecognized
+	}
+
+	year := 0
+	if third != "" {
+		switch len(third) {
+		case 2:
+			year = expandYear(atoi(third))
+		case 4:
+			year = atoi(third)
+		default:
+			return Result{}, ErrUnrecognized
+		}
+	}
+
+	a, b := atoi(first), atoi(second)
+	if sep == "." {
+		// Dotted dates are written day first everywhere they are common
+		order = DayFirst
+	}
+
+	dayFirstValid := validDate(year, b, a)
+	monthFirstValid := validDate(year, a, b)
+	switch {
+	case dayFirstValid && monthFirstValid:
+		ambiguous := a != b
+		if order == MonthFirst {
+			return build(year, a, b, false, ambiguous)
+		}
+		return build(year, b, a, false, ambiguous)
+	case dayFirstValid:
+		return build(year, b, a, false, false)
+	case monthFirstValid:
+		return build(year, a, b, false, false)
+	default:
+		return Result{}, ErrInvalidDate
+	}
+}
+
+func parseWords(s string) (Result, error) {
+	month := 0
+	var numbers []string
+	for _, w := range strings.Split(s, " ") {
+		if m, ok := lookupMonth(w); ok {
+			if month != 0 {
+				return Result{}, ErrUnrecognized
+			}
+			month = m
+			continue
+		}
+		if _, err := strconv.Atoi(w); err == nil {
+			numbers = append(numbers, w)
+			continue
+		}
+		return Result{}, ErrUnrecognized
+	}
+	if month == 0 || len(numbers) == 0 || len(numbers) > 2 {
+		return Result{}, ErrUnrecognized
+	}
+
+	day, year := 0, 0
+	for _, n := range numbers {
+		switch {
+		case len(n) == 4 && year == 0:
+			year = atoi(n)
+		case len(n) <= 2 && day == 0:
+			day = atoi(n)
+		default:
+			return Result{}, ErrUnrecognized
+		}
+	}
+	if day == 0 {
+		return Result{}, ErrUnrecognized
+	}
+	return build(year, month, day, false, false)
+}
+
+func build(year, month, day int, canonical, ambiguous bool) (Result, error) {
+	if !validDate(year, month, day) {
+		return Result{}, ErrInvalidDate
+	}
+	if !ValidYear(year) {
+		return Result{}, ErrYearOutOfRange
+	}
+	return Result{Date: Date{Year: year, Month: month, Day: day}, Canonical: canonical, Ambiguous: ambiguous}, nil
+}
+
+// validDate checks the calendar date; year 0 (unknown) accepts Feb 29.
+func validDate(year, month, day int) bool {
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	checkYear := year
+	if checkYear == 0 {
+		checkYear = 2000
+	}
+	return day <= time.Date(checkYear, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// ValidYear reports whether the year is unknown (0) or between MinYear and the current year.
+func ValidYear(year int) bool {
+	return year == 0 || (year >= MinYear && year <= time.Now().Year())
+}
+
+// expandYear turns a two-digit year into a full year, preferring the past century for future years.
+func expandYear(yy int) int {
+	current := time.Now().Year()
+	year := current - current%100 + yy
+	if year > current {
+		year -= 100
+	}
+	return year
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// String returns the date in storage format: YYYY-MM-DD, or 0000-MM-DD when the year is unknown.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Input returns the date in the bot's command input format: YYYY-MM-DD, or MM-DD when the year is unknown.
+func (d Date) Input() string {
+	if d.Year == 0 {
+		return fmt.Sprintf("%02d-%02d", d.Month, d.Day)
+	}
+	return d.String()
+}
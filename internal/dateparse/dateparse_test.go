@@ -0,0 +1,153 @@
+package dateparse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		order     Order
+		want      string
+		canonical bool
+		ambiguous bool
+	}{
+		{"1999-12-31", DayFirst, "1999-12-31", true, false},
+		{"12-31", DayFirst, "0000-12-31", true, false},
+		{"0000-02-29", DayFirst, "0000-02-29", true, false},
+		{"31.12.1999", MonthFirst, "1999-12-31", false, false},
+		{"31.12", DayFirst, "0000-12-31", false, false},
+		{"01.02.1990", MonthFirst, "1990-02-01", false, true},
+		{"12/31", MonthFirst, "0000-12-31", false, false},
+		{"12/31", DayFirst, "0000-12-31", false, false},
+		{"03/04/1985", MonthFirst, "1985-03-04", false, true},
+		{"03/04/1985", DayFirst, "1985-04-03", false, true},
+		{"05/05", MonthFirst, "0000-05-05", false, false},
+		{"1999/12/31", DayFirst, "1999-12-31", false, false},
+		{"Dec 31", DayFirst, "0000-12-31", false, false},
+		{"31 Dec", MonthFirst, "0000-12-31", false, false},
+		{"December 31st, 1999", DayFirst, "1999-12-31", false, false},
+		{"the 1st of March", DayFirst, "0000-03-01", false, false},
+		{"31 декабря", DayFirst, "0000-12-31", false, false},
+		{"31 декабря 1999 г.", DayFirst, "1999-12-31", false, false},
+		{"1-го мая", DayFirst, "0000-05-01", false, false},
+		{"15. März 1980", DayFirst, "1980-03-15", false, false},
+		{"1er janvier", DayFirst, "0000-01-01", false, false},
+		{"31 de diciembre de 1999", DayFirst, "1999-12-31", false, false},
+		{"29 Feb", DayFirst, "0000-02-29", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.order)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Parse(%q) = %s; want %s", tt.input, got.String(), tt.want)
+			}
+			if got.Canonical != tt.canonical {
+				t.Errorf("Parse(%q).Canonical = %t; want %t", tt.input, got.Canonical, tt.canonical)
+			}
+			if got.Ambiguous != tt.ambiguous {
+				t.Errorf("Parse(%q).Ambiguous = %t; want %t", tt.input, got.Ambiguous, tt.ambiguous)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"", ErrUnrecognized},
+		{"tomorrow", ErrUnrecognized},
+		{"31 foo", ErrUnrecognized},
+		{"Dec Jan 3", ErrUnrecognized},
+		{"1999-02-30", ErrInvalidDate},
+		{"13-45", ErrInvalidDate},
+		{"31.02", ErrInvalidDate},
+		{"29 Feb 2023", ErrInvalidDate},
+		{"31/31", ErrInvalidDate},
+		{"1.2/1999", ErrUnrecognized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := Parse(tt.input, DayFirst); err != tt.want {
+				t.Errorf("Parse(%q) error = %v; want %v", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsYearsOutOfRange(t *testing.T) {
+	next := time.Now().Year() + 1
+	for _, input := range []string{
+		"1899-12-31",
+		"31.12.1850",
+		"December 31, 1899",
+		fmt.Sprintf("%d-01-01", next),
+		fmt.Sprintf("1 January %d", next),
+	} {
+		if _, err := Parse(input, DayFirst); err != ErrYearOutOfRange {
+			t.Errorf("Parse(%q) error = %v; want %v", input, err, ErrYearOutOfRange)
+		}
+	}
+
+	for _, input := range []string{"1900-01-01", fmt.Sprintf("%d-01-01", next-1), "0000-12-31"} {
+		if _, err := Parse(input, DayFirst); err != nil {
+			t.Errorf("Parse(%q) error = %v; want nil", input, err)
+		}
+	}
+}
+
+func TestParseTwoDigitYear(t *testing.T) {
+	got, err := Parse("31.12.99", DayFirst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Year != 1999 {
+		t.Errorf("two-digit year 99 expanded to %d; want 1999", got.Year)
+	}
+
+	yy := time.Now().Year() % 100
+	got, err = Parse(fmt.Sprintf("01.01.%02d", yy), DayFirst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Year != time.Now().Year() {
+		t.Errorf("two-digit current year expanded to %d; want %d", got.Year, time.Now().Year())
+	}
+}
+
+func TestOrderForLocale(t *testing.T) {
+	tests := map[string]Order{
+		"en":    MonthFirst,
+		"en-US": MonthFirst,
+		"en-GB": DayFirst,
+		"ru":    DayFirst,
+		"de-DE": DayFirst,
+		"":      DayFirst,
+	}
+	for tag, want := range tests {
+		if got := OrderForLocale(tag); got != want {
+			t.Errorf("OrderForLocale(%q) = %d; want %d", tag, got, want)
+		}
+	}
+}
+
+func TestDateFormatting(t *testing.T) {
+	known := Date{Year: 1999, Month: 12, Day: 31}
+	unknown := Date{Month: 2, Day: 29}
+
+	if known.Input() != "1999-12-31" || unknown.Input() != "02-29" {
+		t.Errorf("unexpected input format: %s, %s", known.Input(), unknown.Input())
+	}
+	if known.String() != "1999-12-31" || unknown.String() != "0000-02-29" {
+		t.Errorf("unexpected storage format: %s, %s", known.String(), unknown.String())
+	}
+}
@@ -0,0 +1,41 @@
+package dateparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// monthNames maps lowercase month names and abbreviations to month numbers.
+// It covers English, Russian (nominative and genitive), Ukrainian, German, French, Spanish and Italian.
+var monthNames = map[string]int{}
+
+func init() {
+	months := [12][]string{
+		{"january", "jan", "январь", "января", "янв", "січень", "січня", "січ", "januar", "jänner", "janvier", "janv", "enero", "ene", "gennaio", "gen"},
+		{"february", "feb", "февраль", "февраля", "фев", "февр", "лютий", "лютого", "лют", "februar", "février", "fevrier", "févr", "fevr", "febrero", "febbraio"},
+		{"march", "mar", "март", "марта", "мар", "березень", "березня", "бер", "märz", "marz", "mars", "marzo"},
+		{"april", "apr", "апрель", "апреля", "апр", "квітень", "квітня", "кві", "avril", "avr", "abril", "abr", "aprile"},
+		{"may", "май", "мая", "травень", "травня", "тра", "mai", "mayo", "maggio", "mag"},
+		{"june", "jun", "июнь", "июня", "июн", "червень", "червня", "чер", "juni", "juin", "junio", "giugno", "giu"},
+		{"july", "jul", "июль", "июля", "июл", "липень", "липня", "лип", "juli", "juillet", "juil", "julio", "luglio", "lug"},
+		{"august", "aug", "август", "августа", "авг", "серпень", "серпня", "сер", "août", "aout", "agosto", "ago"},
+		{"september", "sep", "sept", "сентябрь", "сентября", "сен", "сент", "вересень", "вересня", "вер", "septembre", "septiembre", "setiembre", "set", "settembre"},
+		{"october", "oct", "октябрь", "октября", "окт", "жовтень", "жовтня", "жов", "oktober", "okt", "octobre", "octubre", "ottobre", "ott"},
+		{"november", "nov", "ноябрь", "ноября", "ноя", "нояб", "листопад", "листопада", "лис", "novembre", "noviembre"},
+		{"december", "dec", "декабрь", "декабря", "дек", "грудень", "грудня", "гру", "dezember", "dez", "décembre", "decembre", "déc", "diciembre", "dic", "dicembre"},
+	}
+	for i, names := range months {
+		for _, name := range names {
+			if existing, ok := monthNames[name]; ok && existing != i+1 {
+				panic(fmt.Sprintf("dateparse: month name %q maps to both %d and %d", name, existing, i+1))
+			}
+			monthNames[name] = i + 1
+		}
+	}
+}
+
+// lookupMonth returns the month number for a month name or abbreviation in any supported language.
+func lookupMonth(word string) (int, bool) {
+	month, ok := monthNames[strings.TrimSuffix(word, ".")]
+	return month, ok
+}
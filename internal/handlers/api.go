@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+// APIBirthdaysPath is the collection path of the JSON API; single records live below it.
+const APIBirthdaysPath = "/api/v1/birthdays"
+
+const (
+	// defaultPageLimit is the page size of list requests without a limit.
+	defaultPageLimit = 50
+	// maxPageLimit is the largest accepted page size.
+	maxPageLimit = 200
+	// maxAPIBodyBytes limits the size of request bodies.
+	maxAPIBodyBytes = 1 << 20
+	// maxNameLength limits the length of record names.
+	maxNameLength = 200
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+var (
+	// errNotFound aborts a storage update when no record has the requested ID.
+	errNotFound = errors.New("record not found")
+	// errValidation aborts a storage update when the submitted record is invalid.
+	errValidation = errors.New("validation failed")
+	// errForbidden aborts a storage update when the user may not change the record.
+	errForbidden = errors.New("forbidden")
+)
+
+// apiBirthday is the JSON representation of a record in the API.
+type apiBirthday struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	BirthDate        string     `json:"birth_date"`
+	ChatID           int64      `json:"chat_id"`
+	Tags             []string   `json:"tags"`
+	Notes            string     `json:"notes"`
+	LastNotification *time.Time `json:"last_notification"`
+}
+
+// apiBirthdayInput is the request body of create and update requests.
+type apiBirthdayInput struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	BirthDate string   `json:"birth_date"`
+	ChatID    int64    `json:"chat_id"`
+	Tags      []string `json:"tags"`
+	Notes     string   `json:"notes"`
+}
+
+// apiList is the response of list requests.
+type apiList struct {
+	Items  []apiBirthday `json:"items"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// apiError is the body of error responses.
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+// apiErrorDetail describes what went wrong; Fields maps invalid fields or parameters to their problem.
+type apiErrorDetail struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// toAPIBirthday converts a stored record to its API representation.
+func toAPIBirthday(b models.Birthday) apiBirthday {
+	out := apiBirthday{
+		ID:        b.ID,
+		Name:      b.Name,
+		Type:      b.EventType(),
+		BirthDate: b.BirthDate,
+		ChatID:    b.ChatID,
+		Tags:      b.Tags,
+		Notes:     b.Notes,
+	}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+	if !b.LastNotification.IsZero() {
+		t := b.LastNotification.UTC()
+		out.LastNotification = &t
+	}
+	return out
+}
+
+// apply validates the input and copies it to the record. Dates are accepted in the formats of the
+// web form and stored as YYYY-MM-DD or 0000-MM-DD; invalid fields are reported together.
+func (in apiBirthdayInput) apply(b *models.Birthday) map[string]string {
+	problems := make(map[string]string)
+
+	name := strings.TrimSpace(in.Name)
+	switch {
+	case name == "":
+		problems["name"] = "is required"
+	case len(name) > maxNameLength:
+		problems["name"] = fmt.Sprintf("must be at most %d characters", maxNameLength)
+	}
+
+	eventType := models.EventBirthday
+	if in.Type != "" {
+		var ok bool
+		if eventType, ok = models.ParseEventType(in.Type); !ok {
+			problems["type"] = "must be one of " + strings.Join(models.EventTypes, ", ")
+		}
+	}
+
+	date := ""
+	if strings.TrimSpace(in.BirthDate) == "" {
+		problems["birth_date"] = "is required"
+	} else if d, err := normalizeDateWithOriginal(strings.TrimSpace(in.BirthDate), b.BirthDate, dateparse.DayFirst); err != nil {
+		problems["birth_date"] = "is not a valid date"
+	} else {
+		date = d
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+
+	b.Name = name
+	b.SetEventType(eventType)
+	b.BirthDate = date
+	b.ChatID = in.ChatID
+	b.Tags = models.ParseTags(strings.Join(in.Tags, ","))
+	b.Notes = strings.TrimSpace(in.Notes)
+	return nil
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("API", "Failed to encode response: %v", err)
+	}
+}
+
+// writeAPIError writes an error response.
+func writeAPIError(w http.ResponseWriter, status int, code, message string, fields map[string]string) {
+	writeJSON(w, status, apiError{Error: apiErrorDetail{Code: code, Message: message, Fields: fields}})
+}
+
+// APIBirthdaysHandler returns the handler of the JSON API for birthday records:
+//
+//	GET    /api/v1/birthdays       list records (filters: type, tag, chat_id, q; pagination: limit, offset)
+//	POST   /api/v1/birthdays       create a record
+//	GET    /api/v1/birthdays/{id}  get a record
+//	PUT    /api/v1/birthdays/{id}  replace the editable fields of a record
+//	DELETE /api/v1/birthdays/{id}  delete a record
+//
+// It must be registered for both the collection path and the subtree below it.
+func APIBirthdaysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, APIBirthdaysPath), "/")
+
+		switch {
+		case id == "" && r.Method == http.MethodGet:
+			listBirthdays(w, r)
+		case id == "" && r.Method == http.MethodPost:
+			createBirthday(w, r)
+		case id != "" && strings.Contains(id, "/"):
+			writeAPIError(w, http.StatusNotFound, "not_found", "unknown path", nil)
+		case id != "" && r.Method == http.MethodGet:
+			getBirthday(w, r, id)
+		case id != "" && r.Method == http.MethodPut:
+			updateBirthday(w, r, id)
+		case id != "" && r.Method == http.MethodDelete:
+			deleteBirthday(w, r, id)
+		default:
+			allowed := "GET, POST"
+			if id != "" {
+				allowed = "GET, PUT, DELETE"
+			}
+			w.Header().Set("Allow", allowed)
+			writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not supported here", nil)
+		}
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document describing the JSON API.
+func OpenAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(openAPIDocument); err != nil {
+			logger.ErrorContext(r.Context(), "API", "Failed to write OpenAPI document: %v", err)
+		}
+	}
+}
+
+// listQuery holds the parsed filters and pagination of a list request.
+type listQuery struct {
+	eventType, tag, q string
+	chatID            *int64
+	limit, offset     int
+}
+
+// parseListQuery validates the query parameters of a list request.
+func parseListQuery(r *http.Request) (listQuery, map[string]string) {
+	values := r.URL.Query()
+	query := listQuery{limit: defaultPageLimit, q: strings.ToLower(strings.TrimSpace(values.Get("q")))}
+	problems := make(map[string]string)
+
+	if v := values.Get("type"); v != "" {
+		var ok bool
+		if query.eventType, ok = models.ParseEventType(v); !ok {
+			problems["type"] = "must be one of " + strings.Join(models.EventTypes, ", ")
+		}
+	}
+	if tags := models.ParseTags(values.Get("tag")); len(tags) > 0 {
+		query.tag = tags[0]
+	}
+	if v := values.Get("chat_id"); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err != nil {
+			problems["chat_id"] = "must be an integer"
+		} else {
+			query.chatID = &id
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 1 || n > maxPageLimit {
+			problems["limit"] = fmt.Sprintf("must be between 1 and %d", maxPageLimit)
+		} else {
+			query.limit = n
+		}
+	}
+	if v := values.Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			problems["offset"] = "must be a non-negative integer"
+		} else {
+			query.offset = n
+		}
+	}
+	return query, problems
+}
+
+// matches reports whether the record passes the filters of the query.
+func (q listQuery) matches(b models.Birthday) bool {
+	return (q.eventType == "" || b.EventType() == q.eventType) &&
+		(q.tag == "" || b.HasTag(q.tag)) &&
+		(q.chatID == nil || b.ChatID == *q.chatID) &&
+		(q.q == "" || strings.Contains(strings.ToLower(b.Name), q.q))
+}
+
+func listBirthdays(w http.ResponseWriter, r *http.Request) {
+	query, problems := parseListQuery(r)
+	if len(problems) > 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_query", "invalid query parameters", problems)
+		return
+	}
+
+	bs, err := storage.LoadBirthdays()
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API", "LoadBirthdays error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "failed to load records", nil)
+		return
+	}
+
+	list := apiList{Items: []apiBirthday{}, Limit: query.limit, Offset: query.offset}
+	for _, b := range bs {
+		if !query.matches(b) || !canView(r, &b) {
+			continue
+		}
+		if list.Total >= query.offset && len(list.Items) < query.limit {
+			list.Items = append(list.Items, toAPIBirthday(b))
+		}
+		list.Total++
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Records of chats the signed-in user may not access are reported as not found.
+func getBirthday(w http.ResponseWriter, r *http.Request, id string) {
+	bs, err := storage.LoadBirthdays()
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API", "LoadBirthdays error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "failed to load records", nil)
+		return
+	}
+	for _, b := range bs {
+		if b.ID == id && canView(r, &b) {
+			writeJSON(w, http.StatusOK, toAPIBirthday(b))
+			return
+		}
+	}
+	writeAPIError(w, http.StatusNotFound, "not_found", "record not found", nil)
+}
+
+// decodeInput reads the JSON body of create and update requests, rejecting unknown fields.
+func decodeInput(w http.ResponseWriter, r *http.Request) (apiBirthdayInput, bool) {
+	var in apiBirthdayInput
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&in); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", "invalid request body: "+err.Error(), nil)
+		return in, false
+	}
+	return in, true
+}
+
+func createBirthday(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeInput(w, r)
+	if !ok {
+		return
+	}
+
+	b := models.Birthday{ID: storage.NewID()}
+	if problems := in.apply(&b); problems != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "the record is invalid", problems)
+		return
+	}
+	if !canEdit(r, &b) {
+		writeAPIError(w, http.StatusForbidden, "forbidden", "you may not manage records of this chat", nil)
+		return
+	}
+
+	err := storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+		return append(bs, b), nil
+	})
+	if err != nil {
+		logger.ErrorContext(r.Context(), "API", "Failed to create record: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "failed to save the record", nil)
+		return
+	}
+
+	audit.Created(auditActor(r), models.SourceAPI, b)
+	logger.Info("API", "Created record %s ('%s')", b.ID, b.Name)
+	w.Header().Set("Location", APIBirthdaysPath+"/"+b.ID)
+	writeJSON(w, http.StatusCreated, toAPIBirthday(b))
+}
+
+func updateBirthday(w http.ResponseWriter, r *http.Request, id string) {
+	in, ok := decodeInput(w, r)
+	if !ok {
+		return
+	}
+
+	var before, updated models.Birthday
+	var problems map[string]string
+	err := storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+		for i := range bs {
+			if bs[i].ID != id {
+				continue
+			}
+			if !canView(r, &bs[i]) {
+				return nil, errNotFound
+			}
+			if !canEdit(r, &bs[i]) {
+				return nil, errForbidden
+			}
+			before = bs[i].Clone()
+			record := bs[i]
+			if problems = in.apply(&record); problems != nil {
+				return nil, errValidation
+			}
+			if !canEdit(r, &record) {
+				return nil, errForbidden
+			}
+			bs[i], updated = record, record
+			return bs, nil
+		}
+		return nil, errNotFound
+	})
+
+	switch {
+	case errors.Is(err, errNotFound):
+		writeAPIError(w, http.StatusNotFound, "not_found", "record not found", nil)
+	case errors.Is(err, errValidation):
+		writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "the record is invalid", problems)
+	case errors.Is(err, errForbidden):
+		writeAPIError(w, http.StatusForbidden, "forbidden", "you may not manage records of this chat", nil)
+	case err != nil:
+		logger.ErrorContext(r.Context(), "API", "Failed to update record %s: %v", id, err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "failed to save the record", nil)
+	default:
+		audit.Updated(auditActor(r), models.SourceAPI, before, updated)
+		logger.Info("API", "Updated record %s ('%s')", id, updated.Name)
+		writeJSON(w, http.StatusOK, toAPIBirthday(updated))
+	}
+}
+
+func deleteBirthday(w http.ResponseWriter, r *http.Request, id string) {
+	var deleted models.Birthday
+	err := storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+		for i := range bs {
+			if bs[i].ID != id || !canView(r, &bs[i]) {
+				continue
+			}
+			if !canEdit(r, &bs[i]) {
+				return nil, errForbidden
+			}
+			deleted = bs[i].Clone()
+			if err := trashRecord(r, &deleted); err != nil {
+				return nil, err
+			}
+			return append(bs[:i], bs[i+1:]...), nil
+		}
+		return nil, errNotFound
+	})
+
+	switch {
+	case errors.Is(err, errNotFound):
+		writeAPIError(w, http.StatusNotFound, "not_found", "record not found", nil)
+	case errors.Is(err, errForbidden):
+		writeAPIError(w, http.StatusForbidden, "forbidden", "you may not manage records of this chat", nil)
+	case err != nil:
+		logger.ErrorContext(r.Context(), "API", "Failed to delete record %s: %v", id, err)
+		writeAPIError(w, http.StatusInternalServerError, "storage_error", "failed to delete the record", nil)
+	default:
+		audit.Deleted(auditActor(r), models.SourceAPI, deleted)
+		logger.Info("API", "Deleted record %s", id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
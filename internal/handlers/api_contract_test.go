@@ -0,0 +1,407 @@
+// internal/handlers/api_contract_test.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/logger"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// openAPISpec is the part of the OpenAPI document the contract tests check responses against.
+type openAPISpec struct {
+	Security   []map[string][]string                 `json:"security"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas         map[string]map[string]interface{} `json:"schemas"`
+		Responses       map[string]map[string]interface{} `json:"responses"`
+		SecuritySchemes map[string]map[string]interface{} `json:"securitySchemes"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T) openAPISpec {
+	t.Helper()
+	w := httptest.NewRecorder()
+	OpenAPIHandler()(w, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("OpenAPI document not served: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var spec openAPISpec
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("OpenAPI document is not valid JSON: %v", err)
+	}
+	return spec
+}
+
+// resolve follows a local $ref of the document.
+func (s openAPISpec) resolve(node map[string]interface{}) map[string]interface{} {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	if strings.HasPrefix(ref, "#/components/responses/") {
+		return s.resolve(s.Components.Responses[name])
+	}
+	return s.resolve(s.Components.Schemas[name])
+}
+
+// validate checks the value against the schema: types, required properties, enums and nested schemas.
+func (s openAPISpec) validate(path string, schema map[string]interface{}, value interface{}) []string {
+	schema = s.resolve(schema)
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{path + ": null is not allowed"}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{path + ": expected an object"}
+		}
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := obj[name.(string)]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, name))
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, v := range obj {
+			if prop, ok := props[name].(map[string]interface{}); ok {
+				problems = append(problems, s.validate(path+"."+name, prop, v)...)
+			} else if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				problems = append(problems, s.validate(path+"."+name, additional, v)...)
+			} else {
+				problems = append(problems, fmt.Sprintf("%s: undocumented property %q", path, name))
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{path + ": expected an array"}
+		}
+		for i, item := range items {
+			problems = append(problems, s.validate(fmt.Sprintf("%s[%d]", path, i), schema["items"].(map[string]interface{}), item)...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{path + ": expected a string"}
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok {
+			found := false
+			for _, e := range enum {
+				found = found || e == str
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("%s: %q is not in the enum", path, str))
+			}
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return []string{path + ": expected an integer"}
+		}
+	}
+	return problems
+}
+
+// checkResponse verifies that the status is documented for the operation and the body matches its schema.
+func (s openAPISpec) checkResponse(t *testing.T, path, method string, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var op struct {
+		Responses map[string]map[string]interface{} `json:"responses"`
+	}
+	if err := json.Unmarshal(s.Paths[path][strings.ToLower(method)], &op); err != nil {
+		t.Fatalf("%s %s is not documented: %v", method, path, err)
+	}
+	response, ok := op.Responses[strconv.Itoa(w.Code)]
+	if !ok {
+		t.Fatalf("%s %s returned undocumented status %d: %s", method, path, w.Code, w.Body.String())
+	}
+	response = s.resolve(response)
+
+	content, ok := response["content"].(map[string]interface{})
+	if !ok {
+		if w.Body.Len() > 0 {
+			t.Errorf("%s %s %d should have no body, got %s", method, path, w.Code, w.Body.String())
+		}
+		return nil
+	}
+	schema := content["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+
+	var body interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s returned invalid JSON: %v", method, path, err)
+	}
+	for _, problem := range s.validate("body", schema, body) {
+		t.Errorf("%s %s %d: %s", method, path, w.Code, problem)
+	}
+	obj, _ := body.(map[string]interface{})
+	return obj
+}
+
+func apiRequest(method, target string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if s, ok := body.(string); ok {
+		reader = bytes.NewReader([]byte(s))
+	} else {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	APIBirthdaysHandler()(w, req)
+	return w
+}
+
+func TestAPIContract(t *testing.T) {
+	os.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	spec := loadSpec(t)
+	const collection, item = "/birthdays", "/birthdays/{id}"
+
+	// Create
+	w := apiRequest("POST", APIBirthdaysPath, map[string]interface{}{
+		"name": "Alice & Bob", "type": "wedding", "birth_date": "2015-06-01", "chat_id": -100, "tags": []string{"Family"},
+	})
+	created := spec.checkResponse(t, collection, "POST", w)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: want 201, got %d: %s", w.Code, w.Body.String())
+	}
+	id, _ := created["id"].(string)
+	if w.Header().Get("Location") != APIBirthdaysPath+"/"+id {
+		t.Errorf("create: unexpected Location %q", w.Header().Get("Location"))
+	}
+	if created["type"] != "anniversary" || created["tags"].([]interface{})[0] != "family" {
+		t.Errorf("create: input should be normalised, got %v", created)
+	}
+	apiRequest("POST", APIBirthdaysPath, map[string]interface{}{"name": "Carol", "birth_date": "12-31", "chat_id": 3})
+	apiRequest("POST", APIBirthdaysPath, map[string]interface{}{"name": "Dave", "birth_date": "1985-02-03", "chat_id": 4, "tags": []string{"family"}})
+
+	// Validation errors name the invalid fields
+	w = apiRequest("POST", APIBirthdaysPath, map[string]interface{}{"name": " ", "birth_date": "someday", "type": "holiday"})
+	invalid := spec.checkResponse(t, collection, "POST", w)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid create: want 422, got %d", w.Code)
+	}
+	fields := invalid["error"].(map[string]interface{})["fields"].(map[string]interface{})
+	for _, field := range []string{"name", "birth_date", "type"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("invalid create: missing problem for %s in %v", field, fields)
+		}
+	}
+	w = apiRequest("POST", APIBirthdaysPath, `{"name": "Eve", "birth_date": "01-01", "unknown": 1}`)
+	spec.checkResponse(t, collection, "POST", w)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown field: want 400, got %d", w.Code)
+	}
+
+	// List with filters and pagination
+	w = apiRequest("GET", APIBirthdaysPath+"?tag=family&limit=1&offset=1", nil)
+	list := spec.checkResponse(t, collection, "GET", w)
+	if list["total"] != float64(2) || len(list["items"].([]interface{})) != 1 {
+		t.Errorf("list: want 1 of 2 family records, got %v", list)
+	}
+	if name := list["items"].([]interface{})[0].(map[string]interface{})["name"]; name != "Dave" {
+		t.Errorf("list: second family record should be Dave, got %v", name)
+	}
+	w = apiRequest("GET", APIBirthdaysPath+"?type=birthday&q=car", nil)
+	if list := spec.checkResponse(t, collection, "GET", w); list["total"] != float64(1) {
+		t.Errorf("list: want only Carol, got %v", list)
+	}
+	w = apiRequest("GET", APIBirthdaysPath+"?limit=1000&chat_id=x", nil)
+	spec.checkResponse(t, collection, "GET", w)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid query: want 400, got %d", w.Code)
+	}
+
+	// Get
+	w = apiRequest("GET", APIBirthdaysPath+"/"+id, nil)
+	if got := spec.checkResponse(t, item, "GET", w); got["name"] != "Alice & Bob" {
+		t.Errorf("get: unexpected record %v", got)
+	}
+	w = apiRequest("GET", APIBirthdaysPath+"/missing", nil)
+	spec.checkResponse(t, item, "GET", w)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get missing: want 404, got %d", w.Code)
+	}
+
+	// Update
+	w = apiRequest("PUT", APIBirthdaysPath+"/"+id, map[string]interface{}{"name": "Alice and Bob", "type": "anniversary", "birth_date": "2015-06-02", "notes": "Silver"})
+	if got := spec.checkResponse(t, item, "PUT", w); got["birth_date"] != "2015-06-02" || got["notes"] != "Silver" || got["chat_id"] != float64(0) {
+		t.Errorf("update: unexpected record %v", got)
+	}
+	w = apiRequest("PUT", APIBirthdaysPath+"/"+id, map[string]interface{}{"name": "Alice and Bob", "birth_date": "02-30"})
+	spec.checkResponse(t, item, "PUT", w)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid update: want 422, got %d", w.Code)
+	}
+
+	// Delete
+	w = apiRequest("DELETE", APIBirthdaysPath+"/"+id, nil)
+	spec.checkResponse(t, item, "DELETE", w)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("delete: want 204, got %d", w.Code)
+	}
+	w = apiRequest("DELETE", APIBirthdaysPath+"/"+id, nil)
+	spec.checkResponse(t, item, "DELETE", w)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("delete again: want 404, got %d", w.Code)
+	}
+}
+
+func TestAPIRoutesMatchDocument(t *testing.T) {
+	os.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	spec := loadSpec(t)
+	for path, operations := range spec.Paths {
+		target := "/api/v1" + strings.ReplaceAll(path, "{id}", "missing")
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			w := apiRequest(strings.ToUpper(method), target, map[string]interface{}{})
+			if w.Code == http.StatusMethodNotAllowed {
+				t.Errorf("%s %s is documented but not served", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	w := apiRequest("PATCH", APIBirthdaysPath, nil)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("undocumented method: want 405 with Allow header, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestAPIDocumentsAuthenticationAndErrors(t *testing.T) {
+	spec := loadSpec(t)
+
+	if len(spec.Security) == 0 {
+		t.Fatal("the document should require authentication")
+	}
+	for _, requirement := range spec.Security {
+		for name := range requirement {
+			if _, ok := spec.Components.SecuritySchemes[name]; !ok {
+				t.Errorf("security requirement %q has no scheme", name)
+			}
+		}
+	}
+	if scheme := spec.Components.SecuritySchemes["basicAuth"]; scheme["type"] != "http" || scheme["scheme"] != "basic" {
+		t.Errorf("API clients should be able to use HTTP Basic authentication, got %v", scheme)
+	}
+	if scheme := spec.Components.SecuritySchemes["sessionCookie"]; scheme["in"] != "cookie" || scheme["name"] != auth.SessionCookieName {
+		t.Errorf("the session cookie scheme should name %q, got %v", auth.SessionCookieName, scheme)
+	}
+
+	for path, operations := range spec.Paths {
+		for method, raw := range operations {
+			if method == "parameters" {
+				continue
+			}
+			var op struct {
+				Responses map[string]interface{} `json:"responses"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+			for _, status := range []string{"401", "500"} {
+				if _, ok := op.Responses[status]; !ok {
+					t.Errorf("%s %s should document %s", strings.ToUpper(method), path, status)
+				}
+			}
+		}
+	}
+}
+
+func TestAPIContractUnauthorized(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	spec := loadSpec(t)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := auth.New(auth.Config{Users: []auth.User{{Username: "alice", PasswordHash: string(hash)}}})
+	handler := a.Middleware(APIBirthdaysHandler())
+
+	req := httptest.NewRequest("GET", APIBirthdaysPath, nil)
+	req.SetBasicAuth("alice", "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	spec.checkResponse(t, "/birthdays", "GET", w)
+	if w.Code != http.StatusOK {
+		t.Errorf("request with Basic credentials: want 200, got %d", w.Code)
+	}
+
+	for _, tt := range []struct{ method, target, path string }{
+		{"GET", APIBirthdaysPath, "/birthdays"},
+		{"POST", APIBirthdaysPath, "/birthdays"},
+		{"GET", APIBirthdaysPath + "/x", "/birthdays/{id}"},
+		{"DELETE", APIBirthdaysPath + "/x", "/birthdays/{id}"},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader("{}")))
+		spec.checkResponse(t, tt.path, tt.method, w)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous %s %s: want 401, got %d", tt.method, tt.target, w.Code)
+		}
+	}
+}
+
+func TestAPIContractStorageErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.yaml")
+	t.Setenv("YAML_PATH", path)
+	if err := os.WriteFile(path, []byte("not: [a list"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec := loadSpec(t)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	req := httptest.NewRequest("GET", APIBirthdaysPath, nil)
+	w := httptest.NewRecorder()
+	APIBirthdaysHandler()(w, req.WithContext(logger.WithRequestID(req.Context(), "req-500")))
+	spec.checkResponse(t, "/birthdays", "GET", w)
+	if !strings.Contains(logs.String(), "[API] [req-500] LoadBirthdays error") {
+		t.Errorf("the storage error should be logged with the request ID, got %q", logs.String())
+	}
+
+	input := map[string]interface{}{"name": "Alice", "birth_date": "1990-05-10"}
+	for _, tt := range []struct {
+		method, target, path string
+		body                 interface{}
+	}{
+		{"GET", APIBirthdaysPath, "/birthdays", nil},
+		{"POST", APIBirthdaysPath, "/birthdays", input},
+		{"GET", APIBirthdaysPath + "/x", "/birthdays/{id}", nil},
+		{"PUT", APIBirthdaysPath + "/x", "/birthdays/{id}", input},
+		{"DELETE", APIBirthdaysPath + "/x", "/birthdays/{id}", nil},
+	} {
+		w := apiRequest(tt.method, tt.target, tt.body)
+		spec.checkResponse(t, tt.path, tt.method, w)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s %s with unreadable storage: want 500, got %d", tt.method, tt.target, w.Code)
+		}
+	}
+}
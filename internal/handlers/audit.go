@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+const (
+	// AuditPath is the path of the audit log page.
+	AuditPath = "/admin/audit"
+
+	// auditPageSize is the number of entries per page of the audit log.
+	auditPageSize = 50
+)
+
+// AuditFilter holds the filters of the audit log page.
+type AuditFilter struct {
+	// Record matches the record ID exactly or the record name, case-insensitively.
+	Record string
+	// Actor matches the actor, case-insensitively.
+	Actor string
+}
+
+// AuditPageData contains the data passed to the audit log templates.
+type AuditPageData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// User is the signed-in user.
+	User string
+	// Entries are the entries of the current page, newest first.
+	Entries []models.AuditEntry
+	// Filter holds the active filters.
+	Filter AuditFilter
+	// Page is the current page, starting at 1.
+	Page int
+	// Pages is the number of pages.
+	Pages int
+	// Total is the number of entries matching the filters.
+	Total int
+	// PrevURL and NextURL link to the newer and older pages, empty on the first and last page.
+	PrevURL, NextURL string
+}
+
+// parseAuditFilter reads the filters from the query string.
+func parseAuditFilter(r *http.Request) AuditFilter {
+	q := r.URL.Query()
+	return AuditFilter{
+		Record: strings.TrimSpace(q.Get("record")),
+		Actor:  strings.TrimSpace(q.Get("actor")),
+	}
+}
+
+// matches reports whether the entry passes the filters.
+func (f AuditFilter) matches(e models.AuditEntry) bool {
+	if f.Record != "" && e.RecordID != f.Record &&
+		!strings.Contains(strings.ToLower(e.RecordName), strings.ToLower(f.Record)) {
+		return false
+	}
+	if f.Actor != "" && !strings.Contains(strings.ToLower(e.Actor), strings.ToLower(f.Actor)) {
+		return false
+	}
+	return true
+}
+
+// url returns the URL of the given page of the audit log with the filters.
+func (f AuditFilter) url(page int) string {
+	q := url.Values{}
+	if f.Record != "" {
+		q.Set("record", f.Record)
+	}
+	if f.Actor != "" {
+		q.Set("actor", f.Actor)
+	}
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if len(q) == 0 {
+		return AuditPath
+	}
+	return AuditPath + "?" + q.Encode()
+}
+
+// newAuditPageData filters and paginates the audit log, given oldest entry first.
+func newAuditPageData(r *http.Request, es []models.AuditEntry) AuditPageData {
+	data := AuditPageData{
+		Lang:   requestLanguage(r),
+		User:   auth.UserFromContext(r.Context()),
+		Filter: parseAuditFilter(r),
+	}
+
+	var matching []models.AuditEntry
+	for i := len(es) - 1; i >= 0; i-- {
+		if data.Filter.matches(es[i]) {
+			matching = append(matching, es[i])
+		}
+	}
+
+	data.Total = len(matching)
+	var start, end int
+	data.Page, data.Pages, start, end = paginate(r, len(matching), auditPageSize)
+	data.Entries = matching[start:end]
+	if data.Page > 1 {
+		data.PrevURL = data.Filter.url(data.Page - 1)
+	}
+	if data.Page < data.Pages {
+		data.NextURL = data.Filter.url(data.Page + 1)
+	}
+	return data
+}
+
+// AuditHandler returns an HTTP handler for the audit log page, filtered by record and actor and
+// paginated with the page query parameter. Only admins may use it. HTMX requests get the table alone.
+func AuditHandler(tpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IdentityFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		es, err := storage.LoadAuditLog()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to load audit log: %v", err)
+			http.Error(w, "Load error", http.StatusInternalServerError)
+			return
+		}
+
+		name := "audit-page"
+		if r.Header.Get("HX-Request") == "true" {
+			name = "audit-table"
+		}
+		if err := tpl.ExecuteTemplate(w, name, newAuditPageData(r, es)); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Audit template execute error: %v", err)
+			http.Error(w, "Render error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+	"5mdt/bd_bot/internal/templates"
+)
+
+func TestAuditHandlerFilters(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	err := storage.AppendAuditLog(
+		models.AuditEntry{Time: start, Actor: "admin", Source: models.SourceWeb, Action: models.AuditCreate,
+			RecordID: "a1", RecordName: "Alice", Changes: []models.FieldChange{{Field: "birth_date", After: "1990-05-10"}}},
+		models.AuditEntry{Time: start.Add(time.Minute), Actor: "telegram:42", Source: models.SourceBot, Action: models.AuditUpdate,
+			RecordID: "b2", RecordName: "Bob", Changes: []models.FieldChange{{Field: "notes", Before: "old note", After: "new note"}}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	AuditHandler(tpl)(w, httptest.NewRequest(http.MethodGet, AuditPath, nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	if strings.Index(body, "Bob") > strings.Index(body, "Alice") {
+		t.Error("newest entries should come first")
+	}
+	if !strings.Contains(body, "<del>old note</del> → <ins>new note</ins>") {
+		t.Error("changes should show the values before and after")
+	}
+
+	for target, want := range map[string]string{
+		AuditPath + "?record=a1":      "Alice",
+		AuditPath + "?record=bo":      "Bob",
+		AuditPath + "?actor=TELEGRAM": "Bob",
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("HX-Request", "true")
+		AuditHandler(tpl)(w, req)
+		body := w.Body.String()
+		if strings.Contains(body, "<html") {
+			t.Errorf("%s: HTMX requests should get the table alone", target)
+		}
+		if !strings.Contains(body, want) || strings.Count(body, "<tr>") != 2 {
+			t.Errorf("%s: want only %s, got %s", target, want, body)
+		}
+	}
+}
+
+func TestAuditHandlerRequiresAdmin(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+
+	w := httptest.NewRecorder()
+	AuditHandler(templates.LoadTemplates())(w, requestAsTelegramUser(AuditPath, 42))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("want 403 for non-admins, got %d", w.Code)
+	}
+}
+
+func TestSaveRowRecordsAudit(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	if err := storage.SaveBirthdays([]models.Birthday{{ID: "alice", Name: "Alice", BirthDate: "1990-05-10", ChatID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	form := "id=alice&name=Alice&birth_date=1990-05-11&chat_id=1"
+	req := httptest.NewRequest(http.MethodPost, "/save", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	SaveRowHandler(templates.LoadTemplates())(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	es, err := storage.LoadAuditLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].Source != models.SourceWeb || es[0].Action != models.AuditUpdate ||
+		es[0].Changes[0] != (models.FieldChange{Field: "birth_date", Before: "1990-05-10", After: "1990-05-11"}) {
+		t.Errorf("unexpected audit log: %+v", es)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/logger"
+)
+
+// LoginPageData contains the data passed to the login template.
+type LoginPageData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// Next is the local path to return to after signing in.
+	Next string
+	// Username is the submitted user name, kept after a failed attempt.
+	Username string
+	// Error is the catalog key of the error message, empty if there is none.
+	Error string
+	// LocalLogin indicates whether local users can sign in with a password.
+	LocalLogin bool
+	// TelegramBot is the username of the bot of the Telegram Login Widget, empty if it is not offered.
+	TelegramBot string
+	// CSRFToken is the token the login form must send.
+	CSRFToken string
+}
+
+// newLoginPageData returns the login page data of the request.
+func newLoginPageData(r *http.Request, a *auth.Authenticator) LoginPageData {
+	return LoginPageData{
+		Lang:        requestLanguage(r),
+		Next:        auth.SafeRedirect(r.FormValue("next")),
+		LocalLogin:  a.HasLocalUsers(),
+		TelegramBot: a.TelegramBot(),
+		CSRFToken:   auth.CSRFToken(r.Context()),
+	}
+}
+
+// renderLogin renders the login page.
+func renderLogin(w http.ResponseWriter, r *http.Request, tpl *template.Template, data LoginPageData) {
+	if err := tpl.ExecuteTemplate(w, "login", data); err != nil {
+		logger.ErrorContext(r.Context(), "HANDLERS", "Login template execute error: %v", err)
+		http.Error(w, "Render error", http.StatusInternalServerError)
+	}
+}
+
+// LoginHandler returns an HTTP handler that shows the login page and signs in local users.
+// After signing in, users are sent back to the local path in the next parameter.
+func LoginHandler(tpl *template.Template, a *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := newLoginPageData(r, a)
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if !a.Enabled() || a.Authenticate(r) != nil {
+				http.Redirect(w, r, data.Next, http.StatusSeeOther)
+				return
+			}
+		case http.MethodPost:
+			username := r.PostFormValue("username")
+			if a.HasLocalUsers() && a.CheckPassword(username, r.PostFormValue("password")) {
+				a.StartSession(w, r, auth.Identity{Name: username})
+				logger.Info("AUTH", "User '%s' signed in from %s", username, r.RemoteAddr)
+				http.Redirect(w, r, data.Next, http.StatusSeeOther)
+				return
+			}
+			logger.WarnContext(r.Context(), "AUTH", "Failed sign-in for user '%s' from %s", username, r.RemoteAddr)
+			data.Username = username
+			data.Error = "web.login.invalid"
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		renderLogin(w, r, tpl, data)
+	}
+}
+
+// TelegramLoginHandler returns an HTTP handler that signs in users confirmed by the Telegram Login Widget.
+// The widget redirects here with the user data signed by Telegram; invalid or expired data shows the login page again.
+func TelegramLoginHandler(tpl *template.Template, a *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := newLoginPageData(r, a)
+
+		identity, err := a.VerifyTelegramLogin(r.URL.Query())
+		if err != nil {
+			logger.WarnContext(r.Context(), "AUTH", "Rejected Telegram sign-in from %s: %v", r.RemoteAddr, err)
+			data.Error = "web.login.telegram_invalid"
+			w.WriteHeader(http.StatusUnauthorized)
+			renderLogin(w, r, tpl, data)
+			return
+		}
+
+		a.StartSession(w, r, identity)
+		logger.LogAudit("WEB_LOGIN", identity.TelegramID, "user %s signed in to the web interface from %s", identity.Name, r.RemoteAddr)
+		http.Redirect(w, r, data.Next, http.StatusSeeOther)
+	}
+}
+
+// LogoutHandler returns an HTTP handler that ends the session and returns to the login page.
+func LogoutHandler(a *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if user := auth.UserFromContext(r.Context()); user != "" {
+			logger.Info("AUTH", "User '%s' signed out", user)
+		}
+		a.EndSession(w, r)
+		http.Redirect(w, r, auth.LoginPath, http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+	"5mdt/bd_bot/internal/templates"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginHandler(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := auth.New(auth.Config{Users: []auth.User{{Username: "alice", PasswordHash: string(hash)}}})
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	LoginHandler(tpl, a)(w, httptest.NewRequest("GET", "/login?next=%2F%3Ftag%3Dfamily", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `name="next" value="/?tag=family"`) {
+		t.Fatalf("login page: got %d %q", w.Code, w.Body.String())
+	}
+
+	post := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"username": {"alice"}, "password": {password}, "next": {"/?tag=family"}}
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		LoginHandler(tpl, a)(w, req)
+		return w
+	}
+
+	w = post("wrong")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Invalid username or password") {
+		t.Errorf("wrong password: got %d", w.Code)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("wrong password should not start a session")
+	}
+
+	w = post("secret")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/?tag=family" {
+		t.Fatalf("correct password: got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 2 || cookies[0].Name != auth.SessionCookieName || cookies[1].Name != auth.CSRFCookieName {
+		t.Fatalf("session cookie = %+v", cookies)
+	}
+
+	req := httptest.NewRequest("POST", "/logout", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	LogoutHandler(a)(w, req)
+	if w.Code != http.StatusSeeOther || a.Authenticate(req) != nil {
+		t.Errorf("logout: got %d, session still valid: %t", w.Code, a.Authenticate(req) != nil)
+	}
+}
+
+func TestIndexHandlerShowsSignedInUser(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(auth.WithUser(req.Context(), "alice"))
+	w := httptest.NewRecorder()
+	IndexHandler(templates.LoadTemplates(), nil)(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "<strong>alice</strong>") || !strings.Contains(body, `action="/logout"`) {
+		t.Error("page should show the signed-in user and a sign-out button")
+	}
+}
+
+type fakeChatAdmins map[int64]int64
+
+func (f fakeChatAdmins) IsChatAdmin(chatID, userID int64) bool {
+	return f[chatID] == userID
+}
+
+// telegramUserRequest returns a request of Telegram user 42, who administers chat -100.
+func telegramUserRequest(t *testing.T, method, target string, body string) *http.Request {
+	t.Helper()
+	a := auth.New(auth.Config{TelegramBotToken: "123:abc", ChatAdmins: fakeChatAdmins{-100: 42}})
+	w := httptest.NewRecorder()
+	a.StartSession(w, httptest.NewRequest("GET", auth.TelegramLoginPath, nil), auth.Identity{Name: "@bob", TelegramID: 42})
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.AddCookie(w.Result().Cookies()[0])
+	if method == "POST" && !strings.HasPrefix(target, "/api/") {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	identity := a.Authenticate(req)
+	identity.Role = models.RoleEditor
+	return req.WithContext(auth.WithIdentity(req.Context(), identity))
+}
+
+func TestTelegramUsersSeeOnlyTheirChats(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	err := storage.SaveBirthdays([]models.Birthday{
+		{ID: "bob", Name: "Bob", BirthDate: "1990-05-10", ChatID: 42},
+		{ID: "carol", Name: "Carol", BirthDate: "1985-02-03", ChatID: 7},
+		{Name: "Team party", Type: models.EventAnniversary, BirthDate: "2015-06-01", ChatID: -100},
+		{Name: "Other group", BirthDate: "2000-01-01", ChatID: -200},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	IndexHandler(tpl, nil)(w, telegramUserRequest(t, "GET", "/", ""))
+	body := w.Body.String()
+	if !strings.Contains(body, `value="Bob"`) || !strings.Contains(body, `value="Team party"`) {
+		t.Error("own and administered records should be shown")
+	}
+	if strings.Contains(body, `value="Carol"`) || strings.Contains(body, `value="Other group"`) {
+		t.Error("records of other chats should be hidden")
+	}
+
+	w = httptest.NewRecorder()
+	DeleteRowHandler(tpl)(w, telegramUserRequest(t, "POST", "/delete-row", "id=carol"))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("deleting another user's record: got %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	SaveRowHandler(tpl)(w, telegramUserRequest(t, "POST", "/save-row", "id=bob&name=Bob&birth_date=1990-05-10&chat_id=-200"))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("moving a record to another group: got %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	APIBirthdaysHandler()(w, telegramUserRequest(t, "GET", APIBirthdaysPath, ""))
+	if !strings.Contains(w.Body.String(), `"total":2`) {
+		t.Errorf("API list should only contain accessible records, got %s", w.Body.String())
+	}
+
+	bs, _ := storage.LoadBirthdays()
+	w = httptest.NewRecorder()
+	APIBirthdaysHandler()(w, telegramUserRequest(t, "GET", APIBirthdaysPath+"/"+bs[1].ID, ""))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("API get of another user's record: got %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	APIBirthdaysHandler()(w, telegramUserRequest(t, "POST", APIBirthdaysPath, `{"name": "Dave", "birth_date": "1999-09-09", "chat_id": -200}`))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("API create in another group: got %d, want 403", w.Code)
+	}
+}
@@ -4,44 +4,30 @@ import (
 	"html/template"
 	"net/http"
 
+	"5mdt/bd_bot/internal/auth"
 	"5mdt/bd_bot/internal/logger"
 )
 
 // BotInfoHandler returns an HTTP handler that renders the bot status information as partial HTML.
-// It queries the bot provider for current status, uptime, and notification metrics.
+// It queries the bot provider for current status, uptime, and lifetime notification statistics.
+// Only admins may see the bot status.
 func BotInfoHandler(tpl *template.Template, botProvider BotStatusProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-
-		var botInfo BotInfo
-		if botProvider != nil && botProvider.GetStatus() != "not configured" {
-			startHour, endHour := botProvider.GetNotificationHours()
-			botInfo = BotInfo{
-				Status:              botProvider.GetStatus(),
-				Username:            botProvider.GetUsername(),
-				FirstName:           botProvider.GetFirstName(),
-				Uptime:              formatUptime(botProvider.GetUptime()),
-				NotificationsSent:   botProvider.GetNotificationsSent(),
-				NotificationHours:   formatNotificationHours(startHour, endHour),
-				NextCheckTime:       calculateNextCheckTime(),
-				CurrentHourInWindow: isCurrentlyInNotificationWindow(startHour, endHour),
-				Configured:          true,
-			}
-		} else {
-			botInfo = BotInfo{
-				Status:     "not configured",
-				Configured: false,
-			}
+		if !auth.IdentityFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
 		}
+		w.Header().Set("Content-Type", "text/html")
 
 		// Create context for the bot info template
 		data := map[string]interface{}{
-			"Bot": botInfo,
+			"Bot":  newBotInfo(botProvider),
+			"Lang": requestLanguage(r),
 		}
 
 		// Execute just the bot-info template
 		if err := tpl.ExecuteTemplate(w, "bot-info", data); err != nil {
-			logger.Error("HANDLERS", "Bot info template execute error: %v", err)
+			logger.ErrorContext(r.Context(), "HANDLERS", "Bot info template execute error: %v", err)
 			http.Error(w, "Template error", http.StatusInternalServerError)
 			return
 		}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/templates"
+)
+
+// fakeBot is a BotStatusProvider with fixed values.
+type fakeBot struct {
+	status     string
+	lastCheck  time.Time
+	sent       int64
+	failed     int64
+	byType     map[string]int64
+	lastSent   time.Time
+	lastError  string
+	errorAt    time.Time
+	firstStart time.Time
+}
+
+func (f fakeBot) GetStatus() string                        { return f.status }
+func (f fakeBot) GetUsername() string                      { return "test_bot" }
+func (f fakeBot) GetFirstName() string                     { return "Test" }
+func (f fakeBot) GetUptime() time.Duration                 { return time.Hour }
+func (f fakeBot) GetNotificationsSent() int64              { return f.sent }
+func (f fakeBot) GetNotificationsFailed() int64            { return f.failed }
+func (f fakeBot) GetNotificationsByType() map[string]int64 { return f.byType }
+func (f fakeBot) GetLastNotification() time.Time           { return f.lastSent }
+func (f fakeBot) GetLastError() (string, time.Time)        { return f.lastError, f.errorAt }
+func (f fakeBot) GetFirstStart() time.Time                 { return f.firstStart }
+func (f fakeBot) GetNotificationHours() (int, int)         { return 8, 20 }
+func (f fakeBot) GetLastCheck() time.Time                  { return f.lastCheck }
+
+func TestBotInfoHandlerShowsLifetimeStatistics(t *testing.T) {
+	provider := fakeBot{
+		status:     "running",
+		sent:       42,
+		failed:     3,
+		byType:     map[string]int64{"REMINDER_2_WEEKS": 30, "BIRTHDAY_TODAY": 12},
+		lastSent:   time.Date(2026, 5, 10, 9, 30, 0, 0, time.UTC),
+		lastError:  "BIRTHDAY_TODAY to chat 12345: Forbidden: bot was blocked by the user",
+		errorAt:    time.Date(2026, 5, 9, 8, 0, 0, 0, time.UTC),
+		firstStart: time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC),
+	}
+
+	w := httptest.NewRecorder()
+	BotInfoHandler(templates.LoadTemplates(), provider)(w, httptest.NewRequest(http.MethodGet, "/bot-info", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		">42<", ">3<",
+		"2026-05-10 09:30 UTC",
+		"2025-01-02 03:04 UTC",
+		"bot was blocked by the user",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("bot info is missing %q", want)
+		}
+	}
+	if strings.Index(body, "BIRTHDAY_TODAY</span>") > strings.Index(body, "REMINDER_2_WEEKS</span>") {
+		t.Error("notification types should be sorted")
+	}
+}
+
+func TestBotInfoHandlerWithoutNotifications(t *testing.T) {
+	w := httptest.NewRecorder()
+	BotInfoHandler(templates.LoadTemplates(), fakeBot{status: "running"})(w, httptest.NewRequest(http.MethodGet, "/bot-info", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Never") {
+		t.Error("missing last notification should be shown as never")
+	}
+	if strings.Contains(body, "Last Error:") {
+		t.Error("last error should be hidden if no notification failed")
+	}
+}
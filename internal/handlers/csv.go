@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/i18n"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+)
+
+// ExportCSVPath is the path of the CSV export of the records.
+const ExportCSVPath = "/export.csv"
+
+// csvColumns are the columns of exported CSV files. The ID is exported for reference only;
+// imports match records by chat ID and name.
+var csvColumns = []string{"id", "name", "type", "birth_date", "chat_id", "tags", "notes", "last_notification"}
+
+// importFields are the record fields that can be read from the columns of an imported CSV file.
+var importFields = []string{"name", "birth_date", "type", "chat_id", "tags", "notes", "last_notification"}
+
+// importFieldAliases maps normalized column headers to the field they are mapped to unless chosen otherwise.
+var importFieldAliases = map[string]string{
+	"name": "name", "full_name": "name", "имя": "name",
+	"birth_date": "birth_date", "birthday": "birth_date", "date": "birth_date", "date_of_birth": "birth_date", "дата": "birth_date",
+	"type": "type", "event_type": "type", "тип": "type",
+	"chat_id": "chat_id", "chat": "chat_id", "чат": "chat_id",
+	"tags": "tags", "tag": "tags", "теги": "tags",
+	"notes": "notes", "note": "notes", "заметки": "notes",
+	"last_notification": "last_notification",
+}
+
+// csvByteOrderMark starts exported files, so that spreadsheets read them as UTF-8.
+const csvByteOrderMark = "\ufeff"
+
+// csvFormulaPrefixes start cells that spreadsheets would evaluate as formulas.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvEscapeText protects a text cell from being evaluated as a formula by spreadsheets
+// by prefixing it with an apostrophe, which csvUnescapeText removes again on import.
+func csvEscapeText(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvUnescapeText removes the apostrophe added by csvEscapeText.
+func csvUnescapeText(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// csvRow returns the cells of the record in the order of csvColumns.
+func csvRow(b models.Birthday) []string {
+	chatID := ""
+	if b.ChatID != 0 {
+		chatID = strconv.FormatInt(b.ChatID, 10)
+	}
+	lastNotification := ""
+	if !b.LastNotification.IsZero() {
+		lastNotification = b.LastNotification.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		b.ID, csvEscapeText(b.Name), b.EventType(), b.BirthDate, chatID,
+		csvEscapeText(strings.Join(b.Tags, ", ")), csvEscapeText(b.Notes), lastNotification,
+	}
+}
+
+// ExportCSVHandler returns an HTTP handler that downloads the records the signed-in user may see
+// as a CSV file.
+func ExportCSVHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bs, ok := loadBirthdaysOrError(w, r)
+		if !ok {
+			return
+		}
+
+		filename := "birthdays-" + time.Now().UTC().Format("2006-01-02") + ".csv"
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Write([]byte(csvByteOrderMark))
+
+		cw := csv.NewWriter(w)
+		cw.Write(csvColumns)
+		exported := 0
+		for _, b := range bs {
+			if canView(r, &b) {
+				cw.Write(csvRow(b))
+				exported++
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "CSV export error: %v", err)
+			return
+		}
+		logger.Info("HANDLERS", "User '%s' exported %d records as CSV", auth.UserFromContext(r.Context()), exported)
+	}
+}
+
+// errEmptyCSV is returned for CSV files without a header row.
+var errEmptyCSV = errors.New("the CSV file is empty")
+
+// parseCSV reads the rows of a CSV file, the header first. Files separated by semicolons,
+// as written by spreadsheets in many locales, are detected by their header.
+func parseCSV(data string) ([][]string, error) {
+	data = strings.TrimPrefix(data, csvByteOrderMark)
+	header, _, _ := strings.Cut(data, "\n")
+
+	cr := csv.NewReader(strings.NewReader(data))
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errEmptyCSV
+	}
+	return rows, nil
+}
+
+// guessMapping maps the import fields to the columns whose header names them.
+// Fields without a matching column are mapped to -1.
+func guessMapping(header []string) map[string]int {
+	mapping := make(map[string]int, len(importFields))
+	for _, field := range importFields {
+		mapping[field] = -1
+	}
+	for i, h := range header {
+		h = strings.Join(strings.Fields(strings.ToLower(h)), "_")
+		if field, ok := importFieldAliases[h]; ok && mapping[field] < 0 {
+			mapping[field] = i
+		}
+	}
+	return mapping
+}
+
+// parseMapping reads the column chosen for each import field from the map_<field> form values,
+// or guesses the mapping from the header if none was submitted.
+func parseMapping(r *http.Request, header []string) map[string]int {
+	if _, ok := r.Form["map_name"]; !ok {
+		return guessMapping(header)
+	}
+	mapping := make(map[string]int, len(importFields))
+	for _, field := range importFields {
+		mapping[field] = -1
+		if i, err := strconv.Atoi(r.FormValue("map_" + field)); err == nil && i >= 0 && i < len(header) {
+			mapping[field] = i
+		}
+	}
+	return mapping
+}
+
+// Statuses of imported rows.
+const (
+	// importCreate rows create a new record.
+	importCreate = "create"
+	// importUpdate rows change an existing record.
+	importUpdate = "update"
+	// importUnchanged rows match an existing record without changing it.
+	importUnchanged = "unchanged"
+	// importConflict rows can't be matched to a single record.
+	importConflict = "conflict"
+	// importInvalid rows have invalid values or change chats the user may not change.
+	importInvalid = "invalid"
+)
+
+// ImportRow is the outcome of importing one row of a CSV file.
+type ImportRow struct {
+	// Number is the number of the row in the file, counting the header as row 1.
+	Number int
+	// Status is importCreate, importUpdate, importUnchanged, importConflict or importInvalid.
+	Status string
+	// Record is the record as it will be saved.
+	Record models.Birthday
+	// Changes are the changed fields of updated records.
+	Changes []models.FieldChange
+	// Problem explains conflicts and invalid rows.
+	Problem string
+	// target is the index of the matched record, -1 for new records.
+	target int
+}
+
+// ImportPlan is the dry run of an import: what each row of the file does to the records.
+type ImportPlan struct {
+	// Rows are the outcomes of the rows of the file.
+	Rows []ImportRow
+	// Counts holds the number of rows of each status.
+	Counts map[string]int
+}
+
+// CanCommit reports whether the import may be saved: it changes something and has no conflicts or invalid rows.
+func (p ImportPlan) CanCommit() bool {
+	return p.Counts[importConflict] == 0 && p.Counts[importInvalid] == 0 && p.Counts[importCreate]+p.Counts[importUpdate] > 0
+}
+
+// importCell returns the trimmed cell of the row mapped to the field, and whether the field is mapped.
+func importCell(row []string, mapping map[string]int, field string) (string, bool) {
+	i := mapping[field]
+	if i < 0 {
+		return "", false
+	}
+	if i >= len(row) {
+		return "", true
+	}
+	return strings.TrimSpace(row[i]), true
+}
+
+// matchRecord returns the index of the existing record the row refers to, or -1 for a new record.
+// Rows with a chat ID are matched to the records of the chat with the same type: the one with
+// the same name, or the only birthday of the chat. Other rows are matched by name and type;
+// a birthday of the same name in another chat is a conflict. A problem is returned if the row
+// can't be matched to a single record. Records the user may not view are ignored, so that
+// problems don't reveal them.
+func matchRecord(r *http.Request, bs []models.Birthday, name, eventType string, chatID int64) (int, string) {
+	lang := requestLanguage(r)
+	sameType := func(b models.Birthday) bool { return b.EventType() == eventType && canView(r, &b) }
+
+	if chatID != 0 {
+		var candidates []int
+		for i, b := range bs {
+			if b.ChatID == chatID && sameType(b) {
+				if strings.EqualFold(b.Name, name) {
+					return i, ""
+				}
+				candidates = append(candidates, i)
+			}
+		}
+		if eventType == models.EventBirthday && len(candidates) == 1 {
+			return candidates[0], ""
+		}
+		if eventType == models.EventBirthday && len(candidates) > 1 {
+			return -1, i18n.T(lang, "web.import.problem.ambiguous_chat", chatID)
+		}
+	}
+
+	match := -1
+	for i, b := range bs {
+		if !strings.EqualFold(b.Name, name) || !sameType(b) {
+			continue
+		}
+		// Events of other chats are different events with the same name
+		if eventType != models.EventBirthday && chatID != 0 && b.ChatID != 0 && b.ChatID != chatID {
+			continue
+		}
+		if match >= 0 {
+			return -1, i18n.T(lang, "web.import.problem.ambiguous_name", name)
+		}
+		match = i
+	}
+	if match >= 0 && chatID != 0 && bs[match].ChatID != 0 && bs[match].ChatID != chatID {
+		return -1, i18n.T(lang, "web.import.problem.other_chat", name, bs[match].ChatID)
+	}
+	return match, ""
+}
+
+// claimKey identifies the record a row imports: the matched record, or the name, type and chat of a new one.
+func claimKey(record models.Birthday, target int) string {
+	if target >= 0 {
+		return "record:" + strconv.Itoa(target)
+	}
+	return "new:" + strings.ToLower(record.Name) + ":" + record.EventType() + ":" + strconv.FormatInt(record.ChatID, 10)
+}
+
+// planImportRow works out what the row does to the records; claimed holds the numbers of earlier
+// rows by the records they import (see claimKey).
+func planImportRow(r *http.Request, bs []models.Birthday, row []string, mapping map[string]int, claimed map[string]int) ImportRow {
+	lang := requestLanguage(r)
+	result := ImportRow{target: -1}
+	invalid := func(key string, args ...interface{}) ImportRow {
+		result.Status, result.Problem = importInvalid, i18n.T(lang, key, args...)
+		return result
+	}
+
+	name, _ := importCell(row, mapping, "name")
+	name = csvUnescapeText(name)
+	result.Record.Name = name
+	if name == "" {
+		return invalid("web.import.problem.no_name")
+	}
+
+	eventType := models.EventBirthday
+	typeCell, typeMapped := importCell(row, mapping, "type")
+	if typeCell != "" {
+		parsed, ok := models.ParseEventType(typeCell)
+		if !ok {
+			return invalid("web.import.problem.type", typeCell)
+		}
+		eventType = parsed
+	}
+
+	var chatID int64
+	chatCell, _ := importCell(row, mapping, "chat_id")
+	if chatCell != "" {
+		id, err := strconv.ParseInt(chatCell, 10, 64)
+		if err != nil {
+			return invalid("web.import.problem.chat_id", chatCell)
+		}
+		chatID = id
+	}
+
+	var lastNotification time.Time
+	lastCell, _ := importCell(row, mapping, "last_notification")
+	if lastCell != "" {
+		t, err := time.Parse(time.RFC3339, lastCell)
+		if err != nil {
+			return invalid("web.import.problem.last_notification", lastCell)
+		}
+		lastNotification = t.UTC()
+	}
+
+	target, problem := matchRecord(r, bs, name, eventType, chatID)
+	if problem != "" {
+		result.Status, result.Problem = importConflict, problem
+		return result
+	}
+
+	// Unmapped fields keep the values of matched records
+	record, before := models.Birthday{}, models.Birthday{}
+	if target >= 0 {
+		before = bs[target].Clone()
+		record = bs[target].Clone()
+	}
+	record.Name = name
+	if typeMapped || target < 0 {
+		record.SetEventType(eventType)
+	}
+	if chatID != 0 {
+		record.ChatID = chatID
+	}
+
+	dateCell, _ := importCell(row, mapping, "birth_date")
+	date, err := normalizeDateWithOriginal(dateCell, before.BirthDate, requestDateOrder(r))
+	record.BirthDate = date
+	result.Record = record
+	if err != nil || date == "" {
+		return invalid("web.import.problem.date", dateCell)
+	}
+
+	if tags, ok := importCell(row, mapping, "tags"); ok {
+		record.Tags = models.ParseTags(csvUnescapeText(tags))
+	}
+	if notes, ok := importCell(row, mapping, "notes"); ok {
+		record.Notes = csvUnescapeText(notes)
+	}
+	if !lastNotification.IsZero() {
+		record.LastNotification = lastNotification
+	}
+	result.Record = record
+
+	if (target >= 0 && !canEdit(r, &bs[target])) || !canEdit(r, &record) {
+		return invalid("web.import.problem.forbidden", record.ChatID)
+	}
+
+	if number, ok := claimed[claimKey(record, target)]; ok {
+		result.Status, result.Problem = importConflict, i18n.T(lang, "web.import.problem.duplicate", number)
+		return result
+	}
+
+	result.target = target
+	switch {
+	case target < 0:
+		result.Status = importCreate
+	default:
+		result.Changes = audit.Changes(before, record)
+		result.Status = importUpdate
+		if len(result.Changes) == 0 {
+			result.Status = importUnchanged
+		}
+	}
+	return result
+}
+
+// planImport works out what importing the data rows into the records would do, without changing anything.
+func planImport(r *http.Request, bs []models.Birthday, rows [][]string, mapping map[string]int) ImportPlan {
+	plan := ImportPlan{Counts: make(map[string]int)}
+	claimed := make(map[string]int)
+	for i, row := range rows {
+		result := planImportRow(r, bs, row, mapping, claimed)
+		// The header is row 1
+		result.Number = i + 2
+		if result.Status != importInvalid && result.Status != importConflict {
+			claimed[claimKey(result.Record, result.target)] = result.Number
+		}
+		plan.Rows = append(plan.Rows, result)
+		plan.Counts[result.Status]++
+	}
+	return plan
+}
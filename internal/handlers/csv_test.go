@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+	"5mdt/bd_bot/internal/templates"
+)
+
+// saveImportRecords stores the records in a temporary data directory.
+func saveImportRecords(t *testing.T, bs []models.Birthday) {
+	t.Helper()
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	if err := storage.SaveBirthdays(bs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// uploadCSV returns a request uploading the CSV file to the import page.
+func uploadCSV(t *testing.T, data string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("action", "preview")
+	fw, err := mw.CreateFormFile("file", "birthdays.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(data))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, ImportPath, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestExportCSV(t *testing.T) {
+	saveImportRecords(t, []models.Birthday{
+		{Name: "=Alice", BirthDate: "1990-05-10", ChatID: 42, Tags: []string{"family", "friends"}, Notes: "Likes tea, not coffee"},
+		{Name: "Bob", BirthDate: "0000-02-03", ChatID: 7},
+	})
+
+	w := httptest.NewRecorder()
+	ExportCSVHandler()(w, requestAsTelegramUser(ExportCSVPath, 42))
+	body := w.Body.String()
+	if !strings.HasPrefix(body, csvByteOrderMark+"id,name,type,birth_date,chat_id,tags,notes,last_notification\n") {
+		t.Errorf("export should start with the byte order mark and the header, got %q", body)
+	}
+	if !strings.Contains(body, `,'=Alice,birthday,1990-05-10,42,"family, friends","Likes tea, not coffee",`) {
+		t.Errorf("export should contain the record with formulas escaped, got %q", body)
+	}
+	if strings.Contains(body, "Bob") {
+		t.Error("export should only contain records the user may see")
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+		t.Errorf("export should be downloaded, got %q", cd)
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	bs := []models.Birthday{
+		{Name: "Alice", BirthDate: "1990-05-10", ChatID: 1},
+		{Name: "Bob", BirthDate: "1985-02-03", ChatID: 2, Notes: "Keep me"},
+		{Name: "Carol", BirthDate: "1970-01-01", ChatID: 3},
+		{Name: "Carol", BirthDate: "1971-01-01", ChatID: 4},
+		{Name: "Team day", Type: models.EventAnniversary, BirthDate: "2015-06-01", ChatID: -100},
+	}
+	rows, err := parseCSV("Name;Birthday;Chat;Type\n" +
+		"Alicia;1990-05-10;1;\n" + // renamed birthday of chat 1
+		"Bob;03.02.1985;;\n" + // matched by name, unchanged
+		"Carol;1970-01-01;;\n" + // two records named Carol
+		"Dave;1999-02-30;5;\n" + // invalid date
+		"Erin;31.12;6;\n" + // new, year unknown
+		"Erin;1999-12-31;6;\n" + // same new record again
+		"Team day;2016-06-01;-200;anniversary\n" + // another chat's event of the same name
+		";2000-01-01;7;\n" + // no name
+		"Frank;2000-01-01;8;holiday\n") // unknown type
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := guessMapping(rows[0])
+	req := httptest.NewRequest(http.MethodPost, ImportPath, nil)
+	req.Header.Set("Accept-Language", "ru")
+	plan := planImport(req, bs, rows[1:], mapping)
+
+	want := []string{importUpdate, importUnchanged, importConflict, importInvalid, importCreate, importConflict, importCreate, importInvalid, importInvalid}
+	if len(plan.Rows) != len(want) {
+		t.Fatalf("want %d rows, got %+v", len(want), plan.Rows)
+	}
+	for i, status := range want {
+		if plan.Rows[i].Status != status {
+			t.Errorf("row %d: want %s, got %s (%s)", plan.Rows[i].Number, status, plan.Rows[i].Status, plan.Rows[i].Problem)
+		}
+	}
+	if r := plan.Rows[0]; r.target != 0 || len(r.Changes) != 1 || r.Changes[0].After != "Alicia" {
+		t.Errorf("renamed birthday should update the record of its chat, got %+v", r)
+	}
+	if r := plan.Rows[4]; r.Record.BirthDate != "0000-12-31" {
+		t.Errorf("dates should be validated like the web form, got %q", r.Record.BirthDate)
+	}
+	if !strings.Contains(plan.Rows[5].Problem, "6") {
+		t.Errorf("duplicate rows should name the earlier row, got %q", plan.Rows[5].Problem)
+	}
+	if plan.CanCommit() {
+		t.Error("plans with conflicts or invalid rows can't be committed")
+	}
+}
+
+func TestPlanImportIgnoresHiddenRecords(t *testing.T) {
+	bs := []models.Birthday{
+		{Name: "Carol", BirthDate: "1970-01-01", ChatID: 3},
+		{Name: "Carol", BirthDate: "1971-01-01", ChatID: 4},
+		{Name: "Bob", BirthDate: "1985-02-03", ChatID: 5},
+		{Name: "Dave", BirthDate: "1990-01-01", ChatID: 42},
+	}
+	rows, err := parseCSV("Name;Birthday;Chat\n" +
+		"Carol;1970-01-01;43\n" + // two hidden records named Carol
+		"Bob;1985-02-03;44\n" + // hidden record of another chat
+		"Dave;1990-01-02;42\n") // visible record
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, ImportPath, nil)
+	editor := &auth.Identity{Name: "Carol", TelegramID: 42, Role: models.RoleEditor, ChatIDs: []int64{43, 44}}
+	plan := planImport(req.WithContext(auth.WithIdentity(req.Context(), editor)), bs, rows[1:], guessMapping(rows[0]))
+
+	want := []string{importCreate, importCreate, importUpdate}
+	for i, status := range want {
+		if r := plan.Rows[i]; r.Status != status || r.Problem != "" {
+			t.Errorf("row %d: want %s, got %s (%s)", r.Number, status, r.Status, r.Problem)
+		}
+	}
+	if plan.Rows[2].target != 3 {
+		t.Errorf("the visible record should still be matched, got target %d", plan.Rows[2].target)
+	}
+}
+
+func TestImportRoundTrip(t *testing.T) {
+	saveImportRecords(t, []models.Birthday{
+		{Name: "-Alice", BirthDate: "0000-05-10", ChatID: 1, Tags: []string{"family"}, Notes: "Notes, with a comma"},
+		{Name: "Team day", Type: models.EventAnniversary, BirthDate: "2015-06-01", ChatID: -100},
+	})
+	w := httptest.NewRecorder()
+	ExportCSVHandler()(w, httptest.NewRequest(http.MethodGet, ExportCSVPath, nil))
+
+	rows, err := parseCSV(w.Body.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := storage.LoadBirthdays()
+	plan := planImport(httptest.NewRequest(http.MethodPost, ImportPath, nil), bs, rows[1:], guessMapping(rows[0]))
+	if plan.Counts[importUnchanged] != 2 {
+		t.Errorf("importing an export should change nothing, got %+v", plan.Rows)
+	}
+}
+
+func TestImportHandler(t *testing.T) {
+	saveImportRecords(t, []models.Birthday{{Name: "Alice", BirthDate: "1990-05-10", ChatID: 1}})
+	tpl := templates.LoadTemplates()
+	file := "name,birth_date,chat_id,tags\nAlice,1990-05-11,1,family\nBob,1985-02-03,2,\n"
+
+	w := httptest.NewRecorder()
+	ImportHandler(tpl)(w, uploadCSV(t, file))
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, "1 new, 1 changed, 0 unchanged, 0 conflicts, 0 invalid") {
+		t.Fatalf("upload should render the preview, got %d: %s", w.Code, body)
+	}
+	if !strings.Contains(body, `<option value="2" selected>chat_id</option>`) {
+		t.Error("columns should be mapped by their header")
+	}
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 1 || bs[0].BirthDate != "1990-05-10" {
+		t.Fatalf("preview must not change the records, got %+v", bs)
+	}
+
+	commit := url.Values{"csv": {file}, "action": {"commit"},
+		"map_name": {"0"}, "map_birth_date": {"1"}, "map_chat_id": {"2"}, "map_tags": {""}}
+	w = httptest.NewRecorder()
+	ImportHandler(tpl)(w, postForm(ImportPath, commit))
+	if !strings.Contains(w.Body.String(), "Imported 1 new and 1 changed records.") {
+		t.Fatalf("commit should report the imported records, got %s", w.Body.String())
+	}
+	bs, _ := storage.LoadBirthdays()
+	if len(bs) != 2 || bs[0].BirthDate != "1990-05-11" || bs[1].Name != "Bob" || bs[1].ID == "" {
+		t.Errorf("import should be saved, got %+v", bs)
+	}
+	if len(bs[0].Tags) != 0 {
+		t.Error("unmapped columns should not be imported")
+	}
+	es, _ := storage.LoadAuditLog()
+	if len(es) != 2 || es[0].Source != models.SourceImport {
+		t.Errorf("imported changes should be audited, got %+v", es)
+	}
+}
+
+func TestImportHandlerIsAtomic(t *testing.T) {
+	saveImportRecords(t, []models.Birthday{{Name: "Alice", BirthDate: "1990-05-10", ChatID: 1}})
+
+	commit := url.Values{"csv": {"name,birth_date\nBob,1985-02-03\nCarol,not a date\n"}, "action": {"commit"}}
+	w := httptest.NewRecorder()
+	ImportHandler(templates.LoadTemplates())(w, postForm(ImportPath, commit))
+	if !strings.Contains(w.Body.String(), "Nothing was imported") {
+		t.Errorf("imports with invalid rows should be rejected, got %s", w.Body.String())
+	}
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 1 {
+		t.Errorf("no row of a rejected import should be saved, got %+v", bs)
+	}
+}
+
+func TestImportHandlerRequiresEditor(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+
+	req := httptest.NewRequest(http.MethodGet, ImportPath, nil)
+	viewer := &auth.Identity{Name: "dave", Role: models.RoleViewer}
+	w := httptest.NewRecorder()
+	ImportHandler(templates.LoadTemplates())(w, req.WithContext(auth.WithIdentity(req.Context(), viewer)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("want 403 for viewers, got %d", w.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"5mdt/bd_bot/internal/storage"
 	"5mdt/bd_bot/internal/templates"
@@ -22,9 +24,8 @@ func TestDatePickerNewRowFunctionality(t *testing.T) {
 
 	// Test adding a new birthday with current year date (should normalize to 0000-MM-DD)
 	form := url.Values{}
-	form.Set("idx", "-1")
 	form.Set("name", "NewUser")
-	form.Set("birth_date", "2025-03-15") // Current year, should become 0000-03-15
+	form.Set("birth_date", fmt.Sprintf("%d-03-15", time.Now().Year())) // Current year, should become 0000-03-15
 	form.Set("last_notification", "2024-12-25T15:30:00Z")
 	form.Set("chat_id", "456")
 
@@ -91,7 +92,6 @@ func TestDatePickerPastYearFunctionality(t *testing.T) {
 
 	// Test adding a birthday with past year (should keep full date)
 	form := url.Values{}
-	form.Set("idx", "-1")
 	form.Set("name", "OldUser")
 	form.Set("birth_date", "1990-07-20") // Past year, should stay 1990-07-20
 	form.Set("last_notification", "")
@@ -25,7 +25,6 @@ func TestDatetimePickerIntegration(t *testing.T) {
 	testTime := "2024-12-25T15:30:00Z" // UTC timestamp from datetime picker
 
 	form := url.Values{}
-	form.Set("idx", "-1")
 	form.Set("name", "TestUser")
 	form.Set("birth_date", "0000-12-25")
 	form.Set("last_notification", testTime)
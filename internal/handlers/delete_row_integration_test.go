@@ -10,6 +10,8 @@ import (
 	"strings"
 	"testing"
 
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
 	"5mdt/bd_bot/internal/templates"
 )
 
@@ -22,7 +24,6 @@ func TestIntegration_DeleteRowHandler(t *testing.T) {
 
 	// first add a row
 	form := url.Values{}
-	form.Set("idx", "-1")
 	form.Set("name", "ToDelete")
 	form.Set("birth_date", "01-01")
 	form.Set("last_notification", "2025-01-01T12:00:00Z")
@@ -32,8 +33,12 @@ func TestIntegration_DeleteRowHandler(t *testing.T) {
 	SaveRowHandler(tpl)(httptest.NewRecorder(), req)
 
 	// now delete it
+	bs, err := storage.LoadBirthdays()
+	if err != nil || len(bs) != 1 {
+		t.Fatalf("expected the added row, got %+v, %v", bs, err)
+	}
 	del := url.Values{}
-	del.Set("idx", "0")
+	del.Set("id", bs[0].ID)
 	req = httptest.NewRequest("POST", "/delete-row", strings.NewReader(del.Encode()))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
@@ -42,7 +47,48 @@ func TestIntegration_DeleteRowHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("want 200, got %d", w.Code)
 	}
-	if strings.Contains(w.Body.String(), "ToDelete") {
+	// The table is followed by the toast offering to undo the deletion
+	table, toast, found := strings.Cut(w.Body.String(), `<div id="toast"`)
+	if strings.Contains(table, "ToDelete") {
 		t.Fatal("deleted row still present")
 	}
+	if !found || !strings.Contains(toast, "ToDelete") || !strings.Contains(toast, "Undo") {
+		t.Fatal("response should offer to undo the deletion")
+	}
+}
+
+func TestIntegration_DeleteRowHandlerSelectsRecordByID(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "test.yaml"))
+	err := storage.SaveBirthdays([]models.Birthday{
+		{ID: "alice", Name: "Alice", BirthDate: "1990-05-10", ChatID: 1},
+		{ID: "bob", Name: "Bob", BirthDate: "1985-02-03", ChatID: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := templates.LoadTemplates()
+
+	// A record added since the page was rendered must not shift the deletion to another record
+	err = storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+		return append([]models.Birthday{{ID: "carol", Name: "Carol", BirthDate: "2000-01-01", ChatID: 3}}, bs...), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	DeleteRowHandler(tpl)(w, postForm("/delete-row", url.Values{"id": {"alice"}}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	bs, _ := storage.LoadBirthdays()
+	if len(bs) != 2 || bs[0].ID != "carol" || bs[1].ID != "bob" {
+		t.Errorf("only Alice should be deleted, got %+v", bs)
+	}
+
+	w = httptest.NewRecorder()
+	DeleteRowHandler(tpl)(w, postForm("/delete-row", url.Values{"id": {"alice"}}))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("deleting a missing record: got %d, want 404", w.Code)
+	}
 }
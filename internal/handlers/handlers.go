@@ -4,13 +4,19 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/dateparse"
+	"5mdt/bd_bot/internal/i18n"
 	"5mdt/bd_bot/internal/logger"
 	"5mdt/bd_bot/internal/models"
 	"5mdt/bd_bot/internal/storage"
@@ -22,6 +28,90 @@ type PageData struct {
 	Birthdays []models.Birthday
 	// BotInfo contains Telegram bot status and statistics.
 	BotInfo BotInfo
+	// Lang is the language of the interface, negotiated from the Accept-Language header.
+	Lang string
+	// Table is the data of the birthday table.
+	Table TableData
+	// User is the signed-in user, empty if authentication is not configured.
+	User string
+	// IsAdmin indicates whether the user may see the bot status and manage roles.
+	IsAdmin bool
+	// CSRFToken is the token state-changing forms must send.
+	CSRFToken string
+}
+
+// TableData contains the data passed to the birthday table template.
+type TableData struct {
+	// Birthdays is the list of birthday records to display.
+	Birthdays []models.Birthday
+	// Lang is the language of the interface.
+	Lang string
+	// Filter is the event type shown in the table, empty for all types.
+	Filter string
+	// Tag is the tag shown in the table, empty for all records.
+	Tag string
+	// EventTypes are the event types to choose from.
+	EventTypes []string
+	// Tags are the tags used by any record, sorted.
+	Tags []string
+	// Shown is the number of records matching the filters.
+	Shown int
+	// Hidden marks the indexes of records the signed-in user may not see.
+	Hidden map[int]bool
+	// ReadOnly marks the indexes of records the signed-in user may see but not change.
+	ReadOnly map[int]bool
+	// CanAdd indicates whether the signed-in user may add records.
+	CanAdd bool
+	// CSRFToken is the token the forms of the table must send.
+	CSRFToken string
+}
+
+// newTableData returns the table data for the records, showing only events of the filter type
+// and with the tag if set, and only records the user may see. Records keep their index in
+// the full list, so the table is always rendered from all records.
+func newTableData(bs []models.Birthday, lang, filter, tag string, identity *auth.Identity) TableData {
+	data := TableData{Birthdays: bs, Lang: lang, EventTypes: models.EventTypes, CanAdd: identity.CanEditAny()}
+	if eventType, ok := models.ParseEventType(filter); ok {
+		data.Filter = eventType
+	}
+	if tags := models.ParseTags(tag); len(tags) > 0 {
+		data.Tag = tags[0]
+	}
+
+	seen := make(map[string]bool)
+	for i, b := range bs {
+		if !identity.CanView(b.ChatID) {
+			if data.Hidden == nil {
+				data.Hidden = make(map[int]bool)
+			}
+			data.Hidden[i] = true
+			continue
+		}
+		if !identity.CanEdit(b.ChatID) {
+			if data.ReadOnly == nil {
+				data.ReadOnly = make(map[int]bool)
+			}
+			data.ReadOnly[i] = true
+		}
+		for _, t := range b.Tags {
+			if !seen[t] {
+				seen[t] = true
+				data.Tags = append(data.Tags, t)
+			}
+		}
+		if (data.Filter == "" || b.EventType() == data.Filter) && (data.Tag == "" || b.HasTag(data.Tag)) {
+			data.Shown++
+		}
+	}
+	sort.Strings(data.Tags)
+	return data
+}
+
+// requestTableData returns the table data for the user, language and CSRF token of the request.
+func requestTableData(r *http.Request, bs []models.Birthday, filter, tag string) TableData {
+	data := newTableData(bs, requestLanguage(r), filter, tag, auth.IdentityFromContext(r.Context()))
+	data.CSRFToken = auth.CSRFToken(r.Context())
+	return data
 }
 
 // BotInfo represents the Telegram bot's current status and configuration.
@@ -34,8 +124,20 @@ type BotInfo struct {
 	FirstName string
 	// Uptime is the human-readable uptime duration.
 	Uptime string
-	// NotificationsSent is the total number of birthday notifications sent.
+	// NotificationsSent is the total number of notifications sent, across restarts.
 	NotificationsSent int64
+	// NotificationsFailed is the total number of notifications that could not be sent, across restarts.
+	NotificationsFailed int64
+	// NotificationsByType lists the number of notifications sent per type, sorted by type.
+	NotificationsByType []NotificationCount
+	// LastNotification is the time of the last notification sent (empty if none was).
+	LastNotification string
+	// LastError is the error of the last notification that could not be sent (empty if none failed).
+	LastError string
+	// LastErrorTime is the time of the last failed notification.
+	LastErrorTime string
+	// CountingSince is the date statistics are counted from.
+	CountingSince string
 	// NotificationHours is the configured notification time window (e.g., "08:00 - 20:00 UTC").
 	NotificationHours string
 	// NextCheckTime is the next scheduled birthday check time.
@@ -46,6 +148,14 @@ type BotInfo struct {
 	Configured bool
 }
 
+// NotificationCount is the number of notifications sent of one type.
+type NotificationCount struct {
+	// Type is the notification type (e.g. BIRTHDAY_TODAY).
+	Type string
+	// Count is the number of notifications sent.
+	Count int64
+}
+
 // BotStatusProvider defines the interface for querying bot status and metrics.
 type BotStatusProvider interface {
 	// GetStatus returns the current bot status.
@@ -58,8 +168,68 @@ type BotStatusProvider interface {
 	GetUptime() time.Duration
 	// GetNotificationsSent returns the total notifications sent.
 	GetNotificationsSent() int64
+	// GetNotificationsFailed returns the total notifications that could not be sent.
+	GetNotificationsFailed() int64
+	// GetNotificationsByType returns the notifications sent per notification type.
+	GetNotificationsByType() map[string]int64
+	// GetLastNotification returns when the last notification was sent.
+	GetLastNotification() time.Time
+	// GetLastError returns the error of the last failed notification and when it happened.
+	GetLastError() (string, time.Time)
+	// GetFirstStart returns since when statistics are counted.
+	GetFirstStart() time.Time
 	// GetNotificationHours returns start and end hours for notifications.
 	GetNotificationHours() (int, int)
+	// GetLastCheck returns when the last birthday check pass completed.
+	GetLastCheck() time.Time
+}
+
+// newBotInfo collects the status of the bot for the status panel.
+func newBotInfo(botProvider BotStatusProvider) BotInfo {
+	if botProvider == nil || botProvider.GetStatus() == "not configured" {
+		return BotInfo{
+			Status:     "not configured",
+			Configured: false,
+		}
+	}
+
+	startHour, endHour := botProvider.GetNotificationHours()
+	lastError, lastErrorAt := botProvider.GetLastError()
+	return BotInfo{
+		Status:              botProvider.GetStatus(),
+		Username:            botProvider.GetUsername(),
+		FirstName:           botProvider.GetFirstName(),
+		Uptime:              formatUptime(botProvider.GetUptime()),
+		NotificationsSent:   botProvider.GetNotificationsSent(),
+		NotificationsFailed: botProvider.GetNotificationsFailed(),
+		NotificationsByType: notificationCounts(botProvider.GetNotificationsByType()),
+		LastNotification:    formatStatsTime(botProvider.GetLastNotification()),
+		LastError:           lastError,
+		LastErrorTime:       formatStatsTime(lastErrorAt),
+		CountingSince:       formatStatsTime(botProvider.GetFirstStart()),
+		NotificationHours:   formatNotificationHours(startHour, endHour),
+		NextCheckTime:       calculateNextCheckTime(),
+		CurrentHourInWindow: isCurrentlyInNotificationWindow(startHour, endHour),
+		Configured:          true,
+	}
+}
+
+// notificationCounts converts the notifications per type into a list sorted by type.
+func notificationCounts(byType map[string]int64) []NotificationCount {
+	counts := make([]NotificationCount, 0, len(byType))
+	for t, n := range byType {
+		counts = append(counts, NotificationCount{Type: t, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Type < counts[j].Type })
+	return counts
+}
+
+// formatStatsTime formats a statistics timestamp, or returns "" for the zero time.
+func formatStatsTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
 }
 
 func formatUptime(d time.Duration) string {
@@ -104,23 +274,100 @@ func calculateNextCheckTime() string {
 	return nextMinute.Format("15:04:05 UTC")
 }
 
-func parseIdx(r *http.Request) (int, error) {
+// canView reports whether the signed-in user may see the record.
+func canView(r *http.Request, b *models.Birthday) bool {
+	return auth.IdentityFromContext(r.Context()).CanView(b.ChatID)
+}
+
+// canEdit reports whether the signed-in user may change the record.
+func canEdit(r *http.Request, b *models.Birthday) bool {
+	return auth.IdentityFromContext(r.Context()).CanEdit(b.ChatID)
+}
+
+// auditActor returns the signed-in user as recorded in the audit log; it is empty if authentication is disabled.
+func auditActor(r *http.Request) string {
+	if id := auth.IdentityFromContext(r.Context()); id != nil {
+		return id.Key()
+	}
+	return ""
+}
+
+// denyAccess rejects a change to a record the signed-in user may not change.
+func denyAccess(w http.ResponseWriter, r *http.Request, b *models.Birthday) {
+	logger.WarnContext(r.Context(), "HANDLERS", "User '%s' may not change records of chat %d", auth.UserFromContext(r.Context()), b.ChatID)
+	http.Error(w, "Forbidden", http.StatusForbidden)
+}
+
+// requirePost rejects requests that change data with any method but POST.
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodPost {
+		return true
+	}
+	w.Header().Set("Allow", http.MethodPost)
+	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// parseRecordID parses the form of a POST request and returns the submitted record ID, empty for new records.
+// Query parameters are dropped from the form, so that only the request body can change data.
+func parseRecordID(r *http.Request) (string, error) {
 	if err := r.ParseForm(); err != nil {
-		return 0, err
+		return "", err
 	}
-	return strconv.Atoi(r.FormValue("idx"))
+	r.Form = r.PostForm
+	return strings.TrimSpace(r.PostForm.Get("id")), nil
+}
+
+// dateConfirmation is returned by updateBirthdayFromForm for a birth date typed in a format other
+// than YYYY-MM-DD or MM-DD, until the user confirms how it was read.
+type dateConfirmation struct {
+	// Input is the birth date as typed.
+	Input string
+	// Date is how it was read, in storage format.
+	Date string
+}
+
+func (c *dateConfirmation) Error() string {
+	return fmt.Sprintf("birth date %q read as %s awaits confirmation", c.Input, c.Date)
 }
 
 func updateBirthdayFromForm(b *models.Birthday, r *http.Request) error {
 	originalBirthDate := b.BirthDate
 	b.Name = r.FormValue("name")
-	b.BirthDate = normalizeDateWithOriginal(r.FormValue("birth_date"), originalBirthDate)
+	input := strings.TrimSpace(r.FormValue("birth_date"))
+	date, err := normalizeDateWithOriginal(input, originalBirthDate, requestDateOrder(r))
+	if err != nil {
+		return err
+	}
+	// Free-form dates are only saved once the user confirmed how they were read
+	if result, _ := dateparse.Parse(input, requestDateOrder(r)); input != "" && !result.Canonical &&
+		r.FormValue("birth_date_confirmed") != date {
+		return &dateConfirmation{Input: input, Date: date}
+	}
+	b.BirthDate = date
+
+	if typeStr := r.FormValue("type"); typeStr != "" {
+		eventType, ok := models.ParseEventType(typeStr)
+		if !ok {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Unknown event type '%s'", typeStr)
+			return fmt.Errorf("invalid event type %q", typeStr)
+		}
+		b.SetEventType(eventType)
+	}
+
+	// Tags and notes are only changed when submitted with the card
+	if _, ok := r.Form["tags"]; ok {
+		b.Tags = models.ParseTags(r.FormValue("tags"))
+	}
+	if _, ok := r.Form["notes"]; ok {
+		b.Notes = strings.TrimSpace(r.FormValue("notes"))
+	}
 
 	// Parse timestamp from form
 	if timestampStr := r.FormValue("last_notification"); timestampStr != "" {
 		timestamp, err := time.Parse(time.RFC3339, timestampStr)
 		if err != nil {
-			logger.Error("HANDLERS", "Failed to parse last_notification '%s': %v", timestampStr, err)
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to parse last_notification '%s': %v", timestampStr, err)
 			return fmt.Errorf("invalid last_notification format: %w", err)
 		}
 		b.LastNotification = timestamp.UTC()
@@ -130,55 +377,138 @@ func updateBirthdayFromForm(b *models.Birthday, r *http.Request) error {
 	if chatIDStr != "" {
 		id, err := strconv.ParseInt(chatIDStr, 10, 64)
 		if err != nil {
-			logger.Error("HANDLERS", "Failed to parse chat_id '%s': %v", chatIDStr, err)
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to parse chat_id '%s': %v", chatIDStr, err)
 			return fmt.Errorf("invalid chat_id format: %w", err)
 		}
 		b.ChatID = id
 	}
 
+	return updateLinksFromForm(b, r)
+}
+
+// updateLinksFromForm applies the per-link notification preferences submitted with the card.
+// Links without a submitted value are left unchanged; the value "unlink" removes the link.
+func updateLinksFromForm(b *models.Birthday, r *http.Request) error {
+	links := b.Links[:0]
+	for _, link := range b.Links {
+		values, ok := r.Form[fmt.Sprintf("link_notify_%d", link.ChatID)]
+		if !ok || len(values) == 0 {
+			links = append(links, link)
+			continue
+		}
+		switch values[0] {
+		case models.NotifyAll, models.NotifyGreeting, models.NotifyMuted:
+			link.Notify = values[0]
+			links = append(links, link)
+		case "unlink":
+			logger.Info("HANDLERS", "Removed link of '%s' to chat %d", b.Name, link.ChatID)
+		default:
+			return fmt.Errorf("invalid notification preference %q for chat %d", values[0], link.ChatID)
+		}
+	}
+	if len(links) == 0 {
+		links = nil
+	}
+	b.Links = links
 	return nil
 }
 
-func normalizeDateWithOriginal(s string, originalBirthDate string) string {
+// requestLanguage returns the supported interface language preferred by the browser.
+func requestLanguage(r *http.Request) string {
+	return i18n.MatchAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// requestDateOrder returns the day/month order preferred by the browser's first Accept-Language entry.
+func requestDateOrder(r *http.Request) dateparse.Order {
+	lang, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	lang, _, _ = strings.Cut(lang, ";")
+	return dateparse.OrderForLocale(lang)
+}
+
+// normalizeDateWithOriginal converts a submitted birth date into storage format.
+// Besides the YYYY-MM-DD value sent by the date picker and MM-DD, it accepts the formats
+// understood by dateparse, using order for ambiguous numeric dates. Empty input yields "";
+// input that is not a valid date is an error.
+func normalizeDateWithOriginal(s string, originalBirthDate string, order dateparse.Order) (string, error) {
 	if s == "" {
-		return ""
+		return "", nil
 	}
 
-	// Handle "MM-DD" format - convert to "0000-MM-DD"
-	if len(s) == 5 && strings.Count(s, "-") == 1 {
-		return "0000-" + s
+	result, err := dateparse.Parse(s, order)
+	if err != nil {
+		logger.Debug("HANDLERS", "Failed to parse birth date '%s': %v", s, err)
+		return "", fmt.Errorf("invalid birth date %q: %w", s, err)
 	}
 
-	// Handle "YYYY-MM-DD" format
-	parsedDate, err := time.Parse("2006-01-02", s)
-	if err != nil {
-		return ""
+	// Dates without a year are stored as 0000-MM-DD
+	if result.Year == 0 {
+		return result.String(), nil
 	}
 
 	currentYear := time.Now().Year()
 
 	// If original was a 0000 date and user enters current year, keep it as 0000
-	if strings.HasPrefix(originalBirthDate, "0000-") && parsedDate.Year() == currentYear {
-		month := parsedDate.Format("01")
-		day := parsedDate.Format("02")
-		return "0000-" + month + "-" + day
+	if strings.HasPrefix(originalBirthDate, "0000-") && result.Year == currentYear {
+		result.Year = 0
+		return result.String(), nil
 	}
 
 	// For new birthdays (empty originalBirthDate) with current year, normalize to 0000
-	if originalBirthDate == "" && parsedDate.Year() == currentYear {
-		month := parsedDate.Format("01")
-		day := parsedDate.Format("02")
-		return "0000-" + month + "-" + day
+	if originalBirthDate == "" && result.Year == currentYear {
+		result.Year = 0
+		return result.String(), nil
 	}
 
-	// For any other case, keep the entered date as-is
-	return s
+	// For any other case, keep the entered date
+	return result.String(), nil
 }
 
-func loadBirthdaysOrError(w http.ResponseWriter) ([]models.Birthday, bool) {
+// DateConfirmData contains the data passed to the template asking to confirm how a typed birth date was read.
+type DateConfirmData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// Input is the birth date as typed.
+	Input string
+	// Date is how it was read, in storage format; it is submitted again with the form to confirm it.
+	Date string
+	// Description spells out the date with the month name, so that it can't be misread.
+	Description string
+}
+
+// describeDate spells out a date in storage format with the month name, e.g. "December 31, 1999".
+func describeDate(lang, date string) string {
+	d, err := dateparse.Parse(date, dateparse.DayFirst)
+	if err != nil {
+		return date
+	}
+	month := i18n.T(lang, fmt.Sprintf("month.of.%d", d.Month))
+	if d.Year == 0 {
+		return i18n.T(lang, "date.describe_year_unknown", d.Day, month)
+	}
+	return i18n.T(lang, "date.describe", d.Day, month, d.Year)
+}
+
+// askDateConfirmation renders the confirmation of a typed birth date into the form that submitted it,
+// leaving the table as it is. Confirming submits the form again with the date as read.
+func askDateConfirmation(w http.ResponseWriter, r *http.Request, tpl *template.Template, id string, c *dateConfirmation) {
+	target := "#date-confirm-new"
+	if id != "" {
+		target = "#date-confirm-" + id
+	}
+	w.Header().Set("HX-Retarget", target)
+	w.Header().Set("HX-Reswap", "innerHTML")
+	lang := requestLanguage(r)
+	data := DateConfirmData{Lang: lang, Input: c.Input, Date: c.Date, Description: describeDate(lang, c.Date)}
+	if err := tpl.ExecuteTemplate(w, "date-confirm", data); err != nil {
+		logger.ErrorContext(r.Context(), "HANDLERS", "Template execute error: %v", err)
+		http.Error(w, "Render error", 500)
+	}
+}
+
+func loadBirthdaysOrError(w http.ResponseWriter, r *http.Request) ([]models.Birthday, bool) {
 	bs, err := storage.LoadBirthdays()
 	if err != nil {
-		logger.Error("HANDLERS", "LoadBirthdays error: %v", err)
+		logger.ErrorContext(r.Context(), "HANDLERS", "LoadBirthdays error: %v", err)
 		http.Error(w, "Load error", 500)
 		return nil, false
 	}
@@ -186,125 +516,196 @@ func loadBirthdaysOrError(w http.ResponseWriter) ([]models.Birthday, bool) {
 }
 
 // IndexHandler returns an HTTP handler that renders the main birthday list page with bot status.
+// Every signed-in user may view it; the bot status is only shown to admins and the table only
+// offers the controls the user may use.
 func IndexHandler(tpl *template.Template, botProvider BotStatusProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		bs, ok := loadBirthdaysOrError(w)
+		bs, ok := loadBirthdaysOrError(w, r)
 		if !ok {
 			return
 		}
 
-		var botInfo BotInfo
-		if botProvider != nil {
-			startHour, endHour := botProvider.GetNotificationHours()
-			botInfo = BotInfo{
-				Status:              botProvider.GetStatus(),
-				Username:            botProvider.GetUsername(),
-				FirstName:           botProvider.GetFirstName(),
-				Uptime:              formatUptime(botProvider.GetUptime()),
-				NotificationsSent:   botProvider.GetNotificationsSent(),
-				NotificationHours:   formatNotificationHours(startHour, endHour),
-				NextCheckTime:       calculateNextCheckTime(),
-				CurrentHourInWindow: isCurrentlyInNotificationWindow(startHour, endHour),
-				Configured:          true,
-			}
-		} else {
-			botInfo = BotInfo{
-				Status:     "not configured",
-				Configured: false,
-			}
-		}
-
 		data := PageData{
 			Birthdays: bs,
-			BotInfo:   botInfo,
+			BotInfo:   newBotInfo(botProvider),
+			Lang:      requestLanguage(r),
+			Table:     requestTableData(r, bs, r.URL.Query().Get("type"), r.URL.Query().Get("tag")),
+			User:      auth.UserFromContext(r.Context()),
+			IsAdmin:   auth.IdentityFromContext(r.Context()).IsAdmin(),
+			CSRFToken: auth.CSRFToken(r.Context()),
 		}
 
 		if err := tpl.ExecuteTemplate(w, "page", data); err != nil {
-			logger.Error("HANDLERS", "Template execute error: %v", err)
+			logger.ErrorContext(r.Context(), "HANDLERS", "Template execute error: %v", err)
 			http.Error(w, "Render error", 500)
 		}
 	}
 }
 
+// formError answers a save whose form could not be applied: it asks to confirm a typed birth date,
+// or rejects invalid form data.
+func formError(w http.ResponseWriter, r *http.Request, tpl *template.Template, id string, err error) {
+	var confirm *dateConfirmation
+	if errors.As(err, &confirm) {
+		askDateConfirmation(w, r, tpl, id, confirm)
+		return
+	}
+	logger.ErrorContext(r.Context(), "HANDLERS", "updateBirthdayFromForm error: %v", err)
+	http.Error(w, "Invalid form data: "+err.Error(), 400)
+}
+
 // SaveRowHandler returns an HTTP handler that processes form submissions to add or update birthday records.
-// For idx==-1, it adds a new record; otherwise, it updates the record at the given index.
+// Without an ID, it adds a new record; otherwise, it updates the record with the given ID.
+// Only users who may change the chat of the record before and after the update may save it.
 func SaveRowHandler(tpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idx, err := parseIdx(r)
-		if err != nil {
-			logger.Error("HANDLERS", "parseIdx error: %v", err)
-			http.Error(w, "Invalid idx", 400)
+		if !requirePost(w, r) {
 			return
 		}
-
-		bs, ok := loadBirthdaysOrError(w)
-		if !ok {
+		id, err := parseRecordID(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "parseRecordID error: %v", err)
+			http.Error(w, "Invalid form data", 400)
 			return
 		}
 
-		if idx == -1 {
-			b := models.Birthday{}
-			if err := updateBirthdayFromForm(&b, r); err != nil {
-				logger.Error("HANDLERS", "updateBirthdayFromForm error: %v", err)
-				http.Error(w, "Invalid form data: "+err.Error(), 400)
-				return
-			}
-			bs = append(bs, b)
-		} else {
-			if idx < 0 || idx >= len(bs) {
-				logger.Error("HANDLERS", "SaveRowHandler invalid idx: %d", idx)
-				http.Error(w, "Invalid idx", 400)
-				return
+		// before and after are the record as it was before and after the update, for the audit log;
+		// formErr is set if the form could not be applied and denied is the record the user may not change
+		var before, after models.Birthday
+		var formErr error
+		var denied *models.Birthday
+		var saved []models.Birthday
+		err = storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+			if id == "" {
+				b := models.Birthday{ID: storage.NewID()}
+				if formErr = updateBirthdayFromForm(&b, r); formErr != nil {
+					return nil, errValidation
+				}
+				if !canEdit(r, &b) {
+					denied = &b
+					return nil, errForbidden
+				}
+				after = b
+				saved = append(bs, b)
+				return saved, nil
 			}
-			if err := updateBirthdayFromForm(&bs[idx], r); err != nil {
-				logger.Error("HANDLERS", "updateBirthdayFromForm error: %v", err)
-				http.Error(w, "Invalid form data: "+err.Error(), 400)
-				return
+
+			for i := range bs {
+				if bs[i].ID != id {
+					continue
+				}
+				if !canEdit(r, &bs[i]) {
+					denied = &bs[i]
+					return nil, errForbidden
+				}
+				record := bs[i].Clone()
+				if formErr = updateBirthdayFromForm(&record, r); formErr != nil {
+					return nil, errValidation
+				}
+				// Records can't be moved to a chat the user may not access
+				if !canEdit(r, &record) {
+					denied = &record
+					return nil, errForbidden
+				}
+				before, after = bs[i].Clone(), record
+				bs[i] = record
+				saved = bs
+				return bs, nil
 			}
-		}
+			return nil, errNotFound
+		})
 
-		if err := storage.SaveBirthdays(bs); err != nil {
-			logger.Error("HANDLERS", "SaveBirthdays error: %v", err)
+		switch {
+		case errors.Is(err, errValidation):
+			formError(w, r, tpl, id, formErr)
+			return
+		case errors.Is(err, errForbidden):
+			denyAccess(w, r, denied)
+			return
+		case errors.Is(err, errNotFound):
+			logger.ErrorContext(r.Context(), "HANDLERS", "SaveRowHandler unknown record: %s", id)
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		case err != nil:
+			logger.ErrorContext(r.Context(), "HANDLERS", "SaveBirthdays error: %v", err)
 			http.Error(w, "Save error", 500)
 			return
 		}
-		if err := tpl.ExecuteTemplate(w, "table", bs); err != nil {
-			logger.Error("HANDLERS", "Template execute error: %v", err)
+		if id == "" {
+			audit.Created(auditActor(r), models.SourceWeb, after)
+		} else {
+			audit.Updated(auditActor(r), models.SourceWeb, before, after)
+		}
+		if err := tpl.ExecuteTemplate(w, "table", requestTableData(r, saved, r.FormValue("filter"), r.FormValue("tag_filter"))); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Template execute error: %v", err)
 			http.Error(w, "Render error", 500)
 		}
 	}
 }
 
-// DeleteRowHandler returns an HTTP handler that processes requests to delete birthday records by index.
+// DeleteRowHandler returns an HTTP handler that processes requests to delete birthday records by ID.
+// Deleted records are moved to the trash, and the response shows a toast to undo the deletion.
+// Only users who may change the chat of the record may delete it.
 func DeleteRowHandler(tpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idx, err := parseIdx(r)
+		if !requirePost(w, r) {
+			return
+		}
+		id, err := parseRecordID(r)
 		if err != nil {
-			logger.Error("HANDLERS", "parseIdx error: %v", err)
-			http.Error(w, "Invalid idx", 400)
+			logger.ErrorContext(r.Context(), "HANDLERS", "parseRecordID error: %v", err)
+			http.Error(w, "Invalid form data", 400)
 			return
 		}
-
-		bs, ok := loadBirthdaysOrError(w)
-		if !ok {
+		if id == "" {
+			logger.ErrorContext(r.Context(), "HANDLERS", "DeleteRowHandler without a record ID")
+			http.Error(w, "Missing record ID", http.StatusBadRequest)
 			return
 		}
 
-		if idx >= 0 && idx < len(bs) {
-			bs = append(bs[:idx], bs[idx+1:]...)
-			if err := storage.SaveBirthdays(bs); err != nil {
-				logger.Error("HANDLERS", "SaveBirthdays error: %v", err)
-				http.Error(w, "Save error", 500)
-				return
+		var deleted models.Birthday
+		var saved []models.Birthday
+		err = storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+			for i := range bs {
+				if bs[i].ID != id {
+					continue
+				}
+				deleted = bs[i].Clone()
+				if !canEdit(r, &bs[i]) {
+					return nil, errForbidden
+				}
+				// The record goes to the trash first, so it is never lost if saving fails
+				if err := trashRecord(r, &deleted); err != nil {
+					return nil, err
+				}
+				saved = append(bs[:i], bs[i+1:]...)
+				return saved, nil
 			}
-		} else {
-			logger.Error("HANDLERS", "DeleteRowHandler invalid idx: %d", idx)
-			http.Error(w, "Invalid idx", http.StatusBadRequest)
+			return nil, errNotFound
+		})
+
+		switch {
+		case errors.Is(err, errForbidden):
+			denyAccess(w, r, &deleted)
+			return
+		case errors.Is(err, errNotFound):
+			logger.ErrorContext(r.Context(), "HANDLERS", "DeleteRowHandler unknown record: %s", id)
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		case err != nil:
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to delete record %s: %v", id, err)
+			http.Error(w, "Save error", 500)
 			return
 		}
-		if err := tpl.ExecuteTemplate(w, "table", bs); err != nil {
-			logger.Error("HANDLERS", "Template execute error: %v", err)
+		audit.Deleted(auditActor(r), models.SourceWeb, deleted)
+		if err := tpl.ExecuteTemplate(w, "table", requestTableData(r, saved, r.FormValue("filter"), r.FormValue("tag_filter"))); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Template execute error: %v", err)
 			http.Error(w, "Render error", 500)
+			return
+		}
+		toast := UndoToastData{Lang: requestLanguage(r), ID: deleted.ID, Name: deleted.Name, CSRFToken: auth.CSRFToken(r.Context())}
+		if err := tpl.ExecuteTemplate(w, "undo-toast", toast); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Toast template execute error: %v", err)
 		}
 	}
 }
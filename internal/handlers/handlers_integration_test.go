@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"5mdt/bd_bot/internal/models"
 	"5mdt/bd_bot/internal/storage"
 	"5mdt/bd_bot/internal/templates"
 )
@@ -20,7 +21,7 @@ func TestIntegration_SaveAndDeleteRow(t *testing.T) {
 	tpl := templates.LoadTemplates()
 
 	// Add new row
-	form := "idx=-1&name=TestUser&birth_date=12-31&last_notification=2024-01-01T12:00:00Z&chat_id=123"
+	form := "name=TestUser&birth_date=12-31&last_notification=2024-01-01T12:00:00Z&chat_id=123"
 	req := httptest.NewRequest("POST", "/save-row", strings.NewReader(form))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
@@ -31,7 +32,11 @@ func TestIntegration_SaveAndDeleteRow(t *testing.T) {
 	}
 
 	// Delete row
-	req = httptest.NewRequest("POST", "/delete-row", strings.NewReader("idx=0"))
+	bs, err := storage.LoadBirthdays()
+	if err != nil || len(bs) != 1 {
+		t.Fatalf("expected the added row, got %+v, %v", bs, err)
+	}
+	req = httptest.NewRequest("POST", "/delete-row", strings.NewReader("id="+bs[0].ID))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w = httptest.NewRecorder()
 	DeleteRowHandler(tpl)(w, req)
@@ -40,7 +45,7 @@ func TestIntegration_SaveAndDeleteRow(t *testing.T) {
 		t.Fatalf("expected 200 on delete, got %d", w.Code)
 	}
 
-	bs, err := storage.LoadBirthdays()
+	bs, err = storage.LoadBirthdays()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,3 +53,30 @@ func TestIntegration_SaveAndDeleteRow(t *testing.T) {
 		t.Fatalf("expected 0 records, got %d", len(bs))
 	}
 }
+
+func TestIntegration_MutationsRequirePostBody(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "test.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	if err := storage.SaveBirthdays([]models.Birthday{{ID: "carol", Name: "Carol", BirthDate: "1990-05-10", ChatID: 1}}); err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	DeleteRowHandler(tpl)(w, httptest.NewRequest("GET", "/delete-row?id=carol", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET delete: got %d, want 405", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	DeleteRowHandler(tpl)(w, httptest.NewRequest("POST", "/delete-row?id=carol", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ID in the query string: got %d, want 400", w.Code)
+	}
+
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 1 {
+		t.Errorf("no record should be deleted, got %d records", len(bs))
+	}
+}
@@ -2,6 +2,7 @@
 package handlers
 
 import (
+	"5mdt/bd_bot/internal/dateparse"
 	"5mdt/bd_bot/internal/models"
 	"net/http"
 	"net/url"
@@ -109,3 +110,126 @@ func TestUpdateBirthdayFromForm_EmptyOptionalFields(t *testing.T) {
 		t.Errorf("ChatID should be 0 for empty input, got %d", b.ChatID)
 	}
 }
+
+func TestNormalizeDateWithOriginal_FreeFormInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		original string
+		order    dateparse.Order
+		want     string
+	}{
+		{"ISO date", "1999-12-31", "", dateparse.DayFirst, "1999-12-31"},
+		{"month-day", "12-31", "", dateparse.DayFirst, "0000-12-31"},
+		{"dotted date", "31.12.1999", "", dateparse.MonthFirst, "1999-12-31"},
+		{"slashes day first", "03/04/1985", "", dateparse.DayFirst, "1985-04-03"},
+		{"slashes month first", "03/04/1985", "", dateparse.MonthFirst, "1985-03-04"},
+		{"month name", "Dec 31", "", dateparse.DayFirst, "0000-12-31"},
+		{"russian month name", "31 декабря 1999", "", dateparse.DayFirst, "1999-12-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeDateWithOriginal(tt.input, tt.original, tt.order)
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeDateWithOriginal(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+
+	for _, input := range []string{"someday", "1850-06-01"} {
+		if got, err := normalizeDateWithOriginal(input, "1999-12-31", dateparse.DayFirst); err == nil {
+			t.Errorf("normalizeDateWithOriginal(%q) = %q; want an error", input, got)
+		}
+	}
+}
+
+func TestRequestDateOrder(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	if got := requestDateOrder(req); got != dateparse.MonthFirst {
+		t.Errorf("en-US should read dates month first, got %d", got)
+	}
+
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.8")
+	if got := requestDateOrder(req); got != dateparse.DayFirst {
+		t.Errorf("ru-RU should read dates day first, got %d", got)
+	}
+}
+
+func TestUpdateLinksFromForm(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/", nil)
+	req.Form = url.Values{
+		"link_notify_-100": {"muted"},
+		"link_notify_-200": {"unlink"},
+	}
+
+	b := &models.Birthday{Name: "Alice", Links: []models.ChatLink{
+		{ChatID: -100, Title: "Team A"},
+		{ChatID: -200, Title: "Team B"},
+		{ChatID: -300, Title: "Team C", Notify: models.NotifyGreeting},
+	}}
+	if err := updateLinksFromForm(b, req); err != nil {
+		t.Fatalf("updateLinksFromForm returned unexpected error: %v", err)
+	}
+
+	if len(b.Links) != 2 {
+		t.Fatalf("expected 2 links after unlinking, got %+v", b.Links)
+	}
+	if b.Links[0].ChatID != -100 || b.Links[0].Notify != models.NotifyMuted {
+		t.Errorf("expected muted link to Team A, got %+v", b.Links[0])
+	}
+	if b.Links[1].ChatID != -300 || b.Links[1].Notify != models.NotifyGreeting {
+		t.Errorf("links without a submitted value should be unchanged, got %+v", b.Links[1])
+	}
+
+	req.Form = url.Values{"link_notify_-100": {"sometimes"}}
+	if err := updateLinksFromForm(b, req); err == nil {
+		t.Error("expected an error for an unknown notification preference")
+	}
+}
+
+func TestUpdateBirthdayFromForm_EventType(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/", nil)
+	req.Form = url.Values{"name": {"Alice & Bob"}, "birth_date": {"2015-06-01"}, "type": {"anniversary"}}
+
+	b := &models.Birthday{}
+	if err := updateBirthdayFromForm(b, req); err != nil {
+		t.Fatalf("updateBirthdayFromForm returned unexpected error: %v", err)
+	}
+	if b.EventType() != models.EventAnniversary {
+		t.Errorf("EventType = %q; want anniversary", b.EventType())
+	}
+
+	req.Form.Set("type", "birthday")
+	if err := updateBirthdayFromForm(b, req); err != nil || b.Type != "" {
+		t.Errorf("birthdays should be stored without a type, got %q, %v", b.Type, err)
+	}
+
+	req.Form.Set("type", "holiday")
+	if err := updateBirthdayFromForm(b, req); err == nil {
+		t.Error("expected an error for an unknown event type")
+	}
+}
+
+func TestUpdateBirthdayFromForm_TagsAndNotes(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/", nil)
+	req.Form = url.Values{"name": {"Alice"}, "birth_date": {"12-31"}, "tags": {"Family, #team backend"}, "notes": {" Likes books \n"}}
+
+	b := &models.Birthday{}
+	if err := updateBirthdayFromForm(b, req); err != nil {
+		t.Fatalf("updateBirthdayFromForm returned unexpected error: %v", err)
+	}
+	if strings.Join(b.Tags, ",") != "family,team-backend" {
+		t.Errorf("Tags = %v; want [family team-backend]", b.Tags)
+	}
+	if b.Notes != "Likes books" {
+		t.Errorf("Notes = %q; want %q", b.Notes, "Likes books")
+	}
+
+	// Forms without the fields leave them unchanged
+	req.Form = url.Values{"name": {"Alice"}, "birth_date": {"12-31"}}
+	if err := updateBirthdayFromForm(b, req); err != nil || len(b.Tags) != 2 || b.Notes == "" {
+		t.Errorf("tags and notes should be kept, got %v, %q, %v", b.Tags, b.Notes, err)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/storage"
+)
+
+const (
+	// HealthzPath is the liveness probe: the process is up and serving requests.
+	HealthzPath = "/healthz"
+	// ReadyzPath is the readiness probe: storage and bot work as expected.
+	ReadyzPath = "/readyz"
+
+	// maxCheckAge is how old the last birthday check pass may be before the bot counts as stuck.
+	// Outside the notification hours passes run once per hour.
+	maxCheckAge = 90 * time.Minute
+)
+
+// healthCheck is the result of a single readiness check.
+type healthCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthResponse is the body of the health endpoints.
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks,omitempty"`
+}
+
+// checkResult converts an error into a check result.
+func checkResult(err error) healthCheck {
+	if err != nil {
+		return healthCheck{Status: "fail", Error: err.Error()}
+	}
+	return healthCheck{Status: "ok"}
+}
+
+// HealthHandler returns the liveness probe handler, which always answers 200 while the process serves requests.
+func HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+	}
+}
+
+// ReadyHandler returns the readiness probe handler. It checks that the birthday file can be read and
+// parsed and, if the bot is required (a token is configured), that the bot is running and its last
+// birthday check pass is recent. It answers 200 if all checks pass and 503 otherwise.
+func ReadyHandler(botProvider BotStatusProvider, botRequired bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checks := map[string]healthCheck{
+			"storage": checkResult(storage.CheckBirthdays()),
+		}
+		if botRequired {
+			checks["bot"] = checkResult(checkBotRunning(botProvider))
+			checks["birthday_check"] = checkResult(checkLastPass(botProvider, time.Now()))
+		}
+
+		resp := healthResponse{Status: "ok", Checks: checks}
+		status := http.StatusOK
+		for name, c := range checks {
+			if c.Status != "ok" {
+				resp.Status = "fail"
+				status = http.StatusServiceUnavailable
+				logger.WarnContext(r.Context(), "HEALTH", "Readiness check %s failed: %s", name, c.Error)
+			}
+		}
+		writeJSON(w, status, resp)
+	}
+}
+
+// checkBotRunning fails unless the bot reports the "running" status.
+func checkBotRunning(botProvider BotStatusProvider) error {
+	if botProvider == nil {
+		return fmt.Errorf("bot is not configured")
+	}
+	if status := botProvider.GetStatus(); status != "running" {
+		return fmt.Errorf("bot status is %q", status)
+	}
+	return nil
+}
+
+// checkLastPass fails unless a birthday check pass completed within maxCheckAge before now.
+func checkLastPass(botProvider BotStatusProvider, now time.Time) error {
+	if botProvider == nil {
+		return fmt.Errorf("bot is not configured")
+	}
+	last := botProvider.GetLastCheck()
+	if last.IsZero() {
+		return fmt.Errorf("no birthday check completed yet")
+	}
+	if age := now.Sub(last); age > maxCheckAge {
+		return fmt.Errorf("last birthday check completed %v ago", age.Truncate(time.Second))
+	}
+	return nil
+}
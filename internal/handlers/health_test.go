@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readiness(t *testing.T, botProvider BotStatusProvider, botRequired bool) (int, healthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ReadyHandler(botProvider, botRequired)(w, httptest.NewRequest(http.MethodGet, ReadyzPath, nil))
+	var resp healthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	return w.Code, resp
+}
+
+func TestHealthHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	HealthHandler()(w, httptest.NewRequest(http.MethodGet, HealthzPath, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.yaml")
+	os.Setenv("YAML_PATH", path)
+	defer os.Unsetenv("YAML_PATH")
+
+	if err := os.WriteFile(path, []byte("- name: Alice\n  birth_date: \"1990-05-10\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	healthy := fakeBot{status: "running", lastCheck: time.Now().Add(-time.Minute)}
+	if code, resp := readiness(t, healthy, true); code != http.StatusOK || resp.Status != "ok" || len(resp.Checks) != 3 {
+		t.Errorf("healthy: got %d %+v", code, resp)
+	}
+
+	if code, resp := readiness(t, nil, false); code != http.StatusOK || len(resp.Checks) != 1 {
+		t.Errorf("without bot token the bot should not be checked, got %d %+v", code, resp)
+	}
+
+	code, resp := readiness(t, fakeBot{status: "connecting", lastCheck: time.Now()}, true)
+	if code != http.StatusServiceUnavailable || resp.Checks["bot"].Status != "fail" {
+		t.Errorf("bot not running: got %d %+v", code, resp)
+	}
+
+	code, resp = readiness(t, fakeBot{status: "running", lastCheck: time.Now().Add(-3 * time.Hour)}, true)
+	if code != http.StatusServiceUnavailable || resp.Checks["birthday_check"].Status != "fail" {
+		t.Errorf("stale birthday check: got %d %+v", code, resp)
+	}
+
+	if err := os.WriteFile(path, []byte("not: [valid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, resp = readiness(t, healthy, true)
+	if code != http.StatusServiceUnavailable || resp.Checks["storage"].Status != "fail" || resp.Checks["storage"].Error == "" {
+		t.Errorf("unparseable storage: got %d %+v", code, resp)
+	}
+}
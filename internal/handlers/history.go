@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+const (
+	// HistoryPath is the path of the notification history page.
+	HistoryPath = "/notifications"
+	// RecordHistoryPath is the path of the notification history panel of a record.
+	RecordHistoryPath = "/notifications/record"
+
+	// historyPageSize is the number of notifications per page of the history.
+	historyPageSize = 50
+	// recordHistoryLimit is the number of notifications shown in the panel of a record.
+	recordHistoryLimit = 10
+)
+
+// HistoryFilter holds the filters of the notification history page.
+type HistoryFilter struct {
+	// Query matches the record name, case-insensitively.
+	Query string
+	// RecordID limits the history to a record.
+	RecordID string
+	// Type limits the history to a notification type.
+	Type string
+	// Outcome limits the history to sent or failed notifications.
+	Outcome string
+	// ChatID limits the history to a chat.
+	ChatID string
+}
+
+// HistoryPageData contains the data passed to the notification history templates.
+type HistoryPageData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// User is the signed-in user.
+	User string
+	// Entries are the notifications of the current page, newest first.
+	Entries []models.NotificationEntry
+	// Types are the notification types found in the history, offered as filters.
+	Types []string
+	// Outcomes are the outcomes offered as filters.
+	Outcomes []string
+	// Filter holds the active filters.
+	Filter HistoryFilter
+	// Page is the current page, starting at 1.
+	Page int
+	// Pages is the number of pages.
+	Pages int
+	// Total is the number of notifications matching the filters.
+	Total int
+	// PrevURL and NextURL link to the newer and older pages, empty on the first and last page.
+	PrevURL, NextURL string
+}
+
+// RecordHistoryData contains the data passed to the history panel of a record.
+type RecordHistoryData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// Entries are the latest notifications of the record, newest first.
+	Entries []models.NotificationEntry
+	// MoreURL links to the full history of the record, empty if all notifications are shown.
+	MoreURL string
+}
+
+// parseHistoryFilter reads the filters from the query string.
+func parseHistoryFilter(r *http.Request) HistoryFilter {
+	q := r.URL.Query()
+	return HistoryFilter{
+		Query:    strings.TrimSpace(q.Get("q")),
+		RecordID: q.Get("record"),
+		Type:     q.Get("type"),
+		Outcome:  q.Get("outcome"),
+		ChatID:   strings.TrimSpace(q.Get("chat_id")),
+	}
+}
+
+// matches reports whether the notification passes the filters.
+func (f HistoryFilter) matches(e models.NotificationEntry) bool {
+	if f.Query != "" && !strings.Contains(strings.ToLower(e.RecordName), strings.ToLower(f.Query)) {
+		return false
+	}
+	if f.RecordID != "" && e.RecordID != f.RecordID {
+		return false
+	}
+	if f.Type != "" && e.Type != f.Type {
+		return false
+	}
+	if f.Outcome != "" && e.Outcome != f.Outcome {
+		return false
+	}
+	if f.ChatID != "" && strconv.FormatInt(e.ChatID, 10) != f.ChatID {
+		return false
+	}
+	return true
+}
+
+// url returns the URL of the given page of the history with the filters.
+func (f HistoryFilter) url(page int) string {
+	q := url.Values{}
+	for key, value := range map[string]string{
+		"q": f.Query, "record": f.RecordID, "type": f.Type, "outcome": f.Outcome, "chat_id": f.ChatID,
+	} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if len(q) == 0 {
+		return HistoryPath
+	}
+	return HistoryPath + "?" + q.Encode()
+}
+
+// visibleHistory loads the notification history the signed-in user may see, newest first.
+func visibleHistory(r *http.Request) ([]models.NotificationEntry, error) {
+	es, err := storage.LoadNotificationHistory()
+	if err != nil {
+		return nil, err
+	}
+	identity := auth.IdentityFromContext(r.Context())
+	visible := make([]models.NotificationEntry, 0, len(es))
+	for i := len(es) - 1; i >= 0; i-- {
+		if identity.CanView(es[i].ChatID) {
+			visible = append(visible, es[i])
+		}
+	}
+	return visible, nil
+}
+
+// newHistoryPageData filters and paginates the notification history.
+func newHistoryPageData(r *http.Request, es []models.NotificationEntry) HistoryPageData {
+	data := HistoryPageData{
+		Lang:     requestLanguage(r),
+		User:     auth.UserFromContext(r.Context()),
+		Outcomes: []string{models.OutcomeSent, models.OutcomeFailed},
+		Filter:   parseHistoryFilter(r),
+	}
+
+	types := make(map[string]bool)
+	var matching []models.NotificationEntry
+	for _, e := range es {
+		types[e.Type] = true
+		if data.Filter.matches(e) {
+			matching = append(matching, e)
+		}
+	}
+	for t := range types {
+		data.Types = append(data.Types, t)
+	}
+	sort.Strings(data.Types)
+
+	data.Total = len(matching)
+	var start, end int
+	data.Page, data.Pages, start, end = paginate(r, len(matching), historyPageSize)
+	data.Entries = matching[start:end]
+	if data.Page > 1 {
+		data.PrevURL = data.Filter.url(data.Page - 1)
+	}
+	if data.Page < data.Pages {
+		data.NextURL = data.Filter.url(data.Page + 1)
+	}
+	return data
+}
+
+// paginate returns the page requested by the page query parameter, clamped to the pages of
+// total items, the number of pages and the bounds of the page's items.
+func paginate(r *http.Request, total, size int) (page, pages, start, end int) {
+	pages = (total + size - 1) / size
+	if pages == 0 {
+		pages = 1
+	}
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	if page > pages {
+		page = pages
+	}
+
+	start = (page - 1) * size
+	end = start + size
+	if end > total {
+		end = total
+	}
+	return page, pages, start, end
+}
+
+// HistoryHandler returns an HTTP handler for the notification history page, filtered by record name,
+// record, type, outcome and chat and paginated with the page query parameter. Users only see the
+// notifications of the chats they may view. HTMX requests get the table alone.
+func HistoryHandler(tpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		es, err := visibleHistory(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to load notification history: %v", err)
+			http.Error(w, "Load error", http.StatusInternalServerError)
+			return
+		}
+
+		name := "history-page"
+		if r.Header.Get("HX-Request") == "true" {
+			name = "history-table"
+		}
+		if err := tpl.ExecuteTemplate(w, name, newHistoryPageData(r, es)); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "History template execute error: %v", err)
+			http.Error(w, "Render error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// RecordHistoryHandler returns an HTTP handler rendering the latest notifications of the record
+// given by the id query parameter, for the history panel of its card.
+func RecordHistoryHandler(tpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bs, ok := loadBirthdaysOrError(w, r)
+		if !ok {
+			return
+		}
+		id := r.URL.Query().Get("id")
+		var record *models.Birthday
+		for i := range bs {
+			if bs[i].ID == id {
+				record = &bs[i]
+				break
+			}
+		}
+		if id == "" || record == nil {
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		}
+		if !canView(r, record) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		es, err := visibleHistory(r)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to load notification history: %v", err)
+			http.Error(w, "Load error", http.StatusInternalServerError)
+			return
+		}
+		data := RecordHistoryData{Lang: requestLanguage(r)}
+		for _, e := range es {
+			if e.RecordID != id {
+				continue
+			}
+			if len(data.Entries) == recordHistoryLimit {
+				data.MoreURL = HistoryFilter{RecordID: id}.url(1)
+				break
+			}
+			data.Entries = append(data.Entries, e)
+		}
+
+		if err := tpl.ExecuteTemplate(w, "history-record", data); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Record history template execute error: %v", err)
+			http.Error(w, "Render error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+	"5mdt/bd_bot/internal/templates"
+)
+
+// requestAsTelegramUser returns a request of a Telegram user, who may only view their own private chat.
+func requestAsTelegramUser(target string, telegramID int64) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	identity := &auth.Identity{Name: "Carol", TelegramID: telegramID, Role: models.RoleEditor}
+	return req.WithContext(auth.WithIdentity(req.Context(), identity))
+}
+
+// saveHistory stores birthdays and a notification history in a temporary data directory.
+func saveHistory(t *testing.T, bs []models.Birthday, es []models.NotificationEntry) {
+	t.Helper()
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	if err := storage.SaveBirthdays(bs); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.AppendNotificationHistory(es...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryHandlerFiltersAndPaginates(t *testing.T) {
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	var es []models.NotificationEntry
+	for i := 0; i < 60; i++ {
+		es = append(es, models.NotificationEntry{
+			Time: start.Add(time.Duration(i) * time.Minute), Type: "BIRTHDAY_TODAY", RecordID: "alice",
+			RecordName: "Alice", ChatID: 1, Message: fmt.Sprintf("Greeting %d", i), Outcome: models.OutcomeSent,
+		})
+	}
+	es = append(es, models.NotificationEntry{
+		Time: start.Add(2 * time.Hour), Type: "REMINDER_2_WEEKS", RecordID: "bob", RecordName: "Bob",
+		ChatID: 2, Message: "Bob in two weeks", Outcome: models.OutcomeFailed, Error: "Forbidden: bot was blocked",
+	})
+	saveHistory(t, nil, es)
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	HistoryHandler(tpl)(w, httptest.NewRequest(http.MethodGet, HistoryPath, nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	if !strings.Contains(body, "Page 1 of 2 (61 notifications)") || !strings.Contains(body, "Greeting 59") || strings.Contains(body, "Greeting 9<") {
+		t.Error("first page should show the 50 newest notifications")
+	}
+	if strings.Index(body, "Bob in two weeks") > strings.Index(body, "Greeting 59") {
+		t.Error("newest notifications should come first")
+	}
+	if !strings.Contains(body, "/notifications?page=2") {
+		t.Error("first page should link to the next page")
+	}
+
+	w = httptest.NewRecorder()
+	HistoryHandler(tpl)(w, httptest.NewRequest(http.MethodGet, HistoryPath+"?page=2", nil))
+	if body := w.Body.String(); !strings.Contains(body, "Greeting 0<") || strings.Contains(body, "Greeting 59") {
+		t.Error("second page should show the oldest notifications")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, HistoryPath+"?outcome=failed&q=bo", nil)
+	req.Header.Set("HX-Request", "true")
+	w = httptest.NewRecorder()
+	HistoryHandler(tpl)(w, req)
+	body = w.Body.String()
+	if !strings.Contains(body, "Bob in two weeks") || !strings.Contains(body, "bot was blocked") || strings.Contains(body, "Greeting") {
+		t.Error("filters should leave the failed notification of Bob only")
+	}
+	if strings.Contains(body, "<html") {
+		t.Error("HTMX requests should get the table alone")
+	}
+}
+
+func TestHistoryHandlerHidesOtherChats(t *testing.T) {
+	saveHistory(t, nil, []models.NotificationEntry{
+		{Type: "BIRTHDAY_TODAY", RecordName: "Carol", ChatID: 1, Message: "Happy birthday Carol", Outcome: models.OutcomeSent},
+		{Type: "BIRTHDAY_TODAY", RecordName: "Dave", ChatID: 2, Message: "Happy birthday Dave", Outcome: models.OutcomeSent},
+	})
+
+	w := httptest.NewRecorder()
+	HistoryHandler(templates.LoadTemplates())(w, requestAsTelegramUser(HistoryPath, 1))
+	body := w.Body.String()
+	if !strings.Contains(body, "Happy birthday Carol") || strings.Contains(body, "Happy birthday Dave") {
+		t.Error("users should only see notifications of chats they may view")
+	}
+}
+
+func TestRecordHistoryHandler(t *testing.T) {
+	var es []models.NotificationEntry
+	for i := 0; i < 12; i++ {
+		es = append(es, models.NotificationEntry{Type: fmt.Sprintf("TYPE_%d", i), RecordID: "carol", ChatID: 1, Outcome: models.OutcomeSent})
+	}
+	es = append(es, models.NotificationEntry{Type: "OTHER_RECORD", RecordID: "dave", ChatID: 2, Outcome: models.OutcomeSent})
+	saveHistory(t, []models.Birthday{
+		{ID: "carol", Name: "Carol", BirthDate: "1990-05-10", ChatID: 1},
+		{ID: "dave", Name: "Dave", BirthDate: "1985-02-03", ChatID: 2},
+	}, es)
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	RecordHistoryHandler(tpl)(w, httptest.NewRequest(http.MethodGet, RecordHistoryPath+"?id=carol", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "TYPE_11") || strings.Contains(body, "TYPE_1 ") || strings.Contains(body, "OTHER_RECORD") {
+		t.Errorf("panel should show the latest notifications of the record only, got %s", body)
+	}
+	if !strings.Contains(body, "/notifications?record=carol") {
+		t.Error("panel should link to the full history of the record")
+	}
+
+	w = httptest.NewRecorder()
+	RecordHistoryHandler(tpl)(w, requestAsTelegramUser(RecordHistoryPath+"?id=dave", 1))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("record of another chat: got %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	RecordHistoryHandler(tpl)(w, httptest.NewRequest(http.MethodGet, RecordHistoryPath+"?id=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown record: got %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	IndexHandler(tpl, nil)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(w.Body.String(), `hx-get="/notifications/record?id=carol"`) {
+		t.Error("cards should offer the history panel")
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+const (
+	// ImportPath is the path of the CSV import page.
+	ImportPath = "/import"
+
+	// importMaxMemory is how much of an uploaded file is kept in memory; request bodies are limited anyway.
+	importMaxMemory = 1 << 20
+)
+
+// errImportRejected aborts the import when the records changed so that the file no longer imports cleanly.
+var errImportRejected = errors.New("import has conflicts or invalid rows")
+
+// ImportPageData contains the data passed to the CSV import templates.
+type ImportPageData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// User is the signed-in user.
+	User string
+	// CSV is the content of the uploaded file, submitted again with the mapping.
+	CSV string
+	// Header holds the column names of the file.
+	Header []string
+	// Fields are the record fields that can be mapped to columns.
+	Fields []string
+	// Mapping holds the column index of each field, -1 for unmapped fields.
+	Mapping map[string]int
+	// Plan is the dry run of the import, nil until a file with name and date columns is mapped.
+	Plan *ImportPlan
+	// Created and Updated are the numbers of records saved by a committed import.
+	Created, Updated int
+	// Committed indicates whether the import was saved.
+	Committed bool
+	// Error is the catalog key of the error message, empty if there is none.
+	Error string
+	// CSRFToken is the token the forms must send.
+	CSRFToken string
+}
+
+// readImportFile returns the uploaded file, or the file submitted again with the mapping form.
+func readImportFile(r *http.Request) (string, error) {
+	if err := r.ParseMultipartForm(importMaxMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return "", err
+	}
+	file, _, err := r.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+		return r.FormValue("csv"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	return string(data), err
+}
+
+// commitImport saves the import in a single update of the records. The plan is worked out again
+// on the current records, and nothing is saved unless every row imports cleanly.
+func commitImport(r *http.Request, rows [][]string, mapping map[string]int) (ImportPlan, error) {
+	var plan ImportPlan
+	var saved []models.Birthday
+	var created []int
+	before := make(map[int]models.Birthday)
+	err := storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+		plan = planImport(r, bs, rows, mapping)
+		if !plan.CanCommit() {
+			return nil, errImportRejected
+		}
+		for _, row := range plan.Rows {
+			switch row.Status {
+			case importCreate:
+				bs = append(bs, row.Record)
+				created = append(created, len(bs)-1)
+			case importUpdate:
+				before[row.target] = bs[row.target]
+				bs[row.target] = row.Record
+			}
+		}
+		saved = bs
+		return bs, nil
+	})
+	if err != nil {
+		return plan, err
+	}
+
+	// The saved records hold the IDs assigned to new records
+	actor := auditActor(r)
+	for _, i := range created {
+		audit.Created(actor, models.SourceImport, saved[i])
+	}
+	for i, b := range before {
+		audit.Updated(actor, models.SourceImport, b, saved[i])
+	}
+	return plan, nil
+}
+
+// ImportHandler returns an HTTP handler for the CSV import page. GET renders the upload form.
+// POST reads the uploaded file (or the file submitted again with the column mapping) and either
+// renders a dry run of the import (action=preview) or saves it (action=commit). Only users who
+// may add records may use it; rows changing chats they may not change are rejected.
+func ImportHandler(tpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IdentityFromContext(r.Context()).CanEditAny() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		data := ImportPageData{
+			Lang:      requestLanguage(r),
+			User:      auth.UserFromContext(r.Context()),
+			Fields:    importFields,
+			CSRFToken: auth.CSRFToken(r.Context()),
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			renderImport(w, r, tpl, "import-page", data)
+			return
+		case http.MethodPost:
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		csvData, err := readImportFile(r)
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			data.Error = "web.import.error_too_large"
+		case err != nil:
+			logger.WarnContext(r.Context(), "HANDLERS", "Failed to read imported file: %v", err)
+			data.Error = "web.import.error_read"
+		case csvData == "":
+			data.Error = "web.import.error_no_file"
+		}
+		if data.Error != "" {
+			renderImport(w, r, tpl, "import-body", data)
+			return
+		}
+
+		rows, err := parseCSV(csvData)
+		if err != nil {
+			logger.WarnContext(r.Context(), "HANDLERS", "Failed to parse imported CSV file: %v", err)
+			data.Error = "web.import.error_parse"
+			renderImport(w, r, tpl, "import-body", data)
+			return
+		}
+		data.CSV, data.Header = csvData, rows[0]
+		data.Mapping = parseMapping(r, data.Header)
+		if data.Mapping["name"] < 0 || data.Mapping["birth_date"] < 0 {
+			data.Error = "web.import.error_mapping"
+			renderImport(w, r, tpl, "import-body", data)
+			return
+		}
+
+		var plan ImportPlan
+		if r.FormValue("action") == "commit" {
+			plan, err = commitImport(r, rows[1:], data.Mapping)
+			switch {
+			case errors.Is(err, errImportRejected):
+				data.Error = "web.import.error_rejected"
+			case err != nil:
+				logger.ErrorContext(r.Context(), "HANDLERS", "Failed to save imported records: %v", err)
+				data.Error = "web.import.error_save"
+			default:
+				data.Committed = true
+				data.Created, data.Updated = plan.Counts[importCreate], plan.Counts[importUpdate]
+				logger.Info("HANDLERS", "User '%s' imported %d new and %d changed records from CSV",
+					data.User, data.Created, data.Updated)
+			}
+		} else {
+			bs, ok := loadBirthdaysOrError(w, r)
+			if !ok {
+				return
+			}
+			plan = planImport(r, bs, rows[1:], data.Mapping)
+		}
+		data.Plan = &plan
+		renderImport(w, r, tpl, "import-body", data)
+	}
+}
+
+// renderImport renders an import template.
+func renderImport(w http.ResponseWriter, r *http.Request, tpl *template.Template, name string, data ImportPageData) {
+	if err := tpl.ExecuteTemplate(w, name, data); err != nil {
+		logger.ErrorContext(r.Context(), "HANDLERS", "Import template execute error: %v", err)
+		http.Error(w, "Render error", http.StatusInternalServerError)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"testing"
 
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
 	"5mdt/bd_bot/internal/templates"
 )
 
@@ -30,3 +32,86 @@ func TestIntegration_IndexHandler(t *testing.T) {
 		t.Fatal("response missing birthday container")
 	}
 }
+
+func TestIntegration_IndexHandlerFollowsAcceptLanguage(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "test.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	tpl := templates.LoadTemplates()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9,en;q=0.8")
+
+	IndexHandler(tpl, nil)(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, `<html lang="ru">`) {
+		t.Error("page should declare the negotiated language")
+	}
+	if !strings.Contains(body, "Дни рождения") {
+		t.Error("page should be rendered in Russian")
+	}
+	if strings.Contains(body, "Birthday Manager") {
+		t.Error("page should not contain English headings")
+	}
+}
+
+func TestIntegration_IndexHandlerFiltersEventTypes(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "test.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	err := storage.SaveBirthdays([]models.Birthday{
+		{Name: "Carol", BirthDate: "1990-05-10", ChatID: 1},
+		{ID: "anniversary", Name: "Alice & Bob", Type: models.EventAnniversary, BirthDate: "2015-06-01", ChatID: -100},
+	})
+	if err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+
+	tpl := templates.LoadTemplates()
+	w := httptest.NewRecorder()
+	IndexHandler(tpl, nil)(w, httptest.NewRequest("GET", "/?type=anniversary", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Alice &amp; Bob") || strings.Contains(body, `value="Carol"`) {
+		t.Error("only anniversaries should be shown")
+	}
+	// Cards submit the ID of their record
+	if !strings.Contains(body, `name="id" value="anniversary"`) {
+		t.Error("filtered card should submit its record ID")
+	}
+	if !strings.Contains(body, `id="event-filter" name="filter" value="anniversary"`) {
+		t.Error("filter should be submitted with the forms")
+	}
+}
+
+func TestIntegration_IndexHandlerFiltersTags(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "test.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	err := storage.SaveBirthdays([]models.Birthday{
+		{Name: "Carol", BirthDate: "1990-05-10", ChatID: 1, Tags: []string{"family"}, Notes: "Likes tea"},
+		{Name: "Dave", BirthDate: "1985-02-03", ChatID: 2, Tags: []string{"team-backend"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to save birthdays: %v", err)
+	}
+
+	tpl := templates.LoadTemplates()
+	w := httptest.NewRecorder()
+	IndexHandler(tpl, nil)(w, httptest.NewRequest("GET", "/?tag=family", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, `value="Carol"`) || strings.Contains(body, `value="Dave"`) {
+		t.Error("only records tagged family should be shown")
+	}
+	if !strings.Contains(body, "#team-backend") {
+		t.Error("all tags should be offered as filters")
+	}
+	if !strings.Contains(body, "Likes tea</textarea>") {
+		t.Error("notes should be editable on the card")
+	}
+}
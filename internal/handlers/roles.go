@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+// RolesPath is the path of the admin page for role assignments.
+const RolesPath = "/admin/roles"
+
+// RolesPageData contains the data passed to the role assignment templates.
+type RolesPageData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// User is the signed-in admin.
+	User string
+	// Assignments are the stored role assignments.
+	Assignments []models.RoleAssignment
+	// Roles are the roles to choose from.
+	Roles []string
+	// LocalUsers are the names of the local users, offered as suggestions.
+	LocalUsers []string
+	// ConfiguredAdmins are the users that are always admins and can't be changed here.
+	ConfiguredAdmins []string
+	// Error is the catalog key of the error message, empty if there is none.
+	Error string
+	// CSRFToken is the token the forms must send.
+	CSRFToken string
+}
+
+// parseChatIDs parses a comma- or space-separated list of chat IDs.
+func parseChatIDs(s string) ([]int64, error) {
+	var ids []int64
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// changeRole applies the submitted role assignment change and returns the catalog key of the
+// error message, or "" on success. Admins can't change their own role or the configured admins.
+func changeRole(r *http.Request, a *auth.Authenticator) string {
+	user := strings.TrimSpace(r.PostFormValue("user"))
+	if user == "" {
+		return "web.roles.error_user"
+	}
+	identity := auth.IdentityFromContext(r.Context())
+	if identity != nil && identity.Key() == user {
+		return "web.roles.error_self"
+	}
+	if a.IsConfiguredAdmin(user) {
+		return "web.roles.error_configured"
+	}
+
+	actor := auth.UserFromContext(r.Context())
+	switch r.PostFormValue("action") {
+	case "delete":
+		if _, err := storage.DeleteRoleAssignment(user); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to delete role assignment of '%s': %v", user, err)
+			return "web.roles.error_save"
+		}
+		logger.Info("AUTH", "User '%s' removed the role assignment of '%s'", actor, user)
+	default:
+		role := r.PostFormValue("role")
+		if !models.IsRole(role) {
+			return "web.roles.error_role"
+		}
+		chatIDs, err := parseChatIDs(r.PostFormValue("chat_ids"))
+		if err != nil {
+			return "web.roles.error_chats"
+		}
+		if role != models.RoleEditor {
+			chatIDs = nil
+		}
+		if err := storage.SetRoleAssignment(models.RoleAssignment{User: user, Role: role, ChatIDs: chatIDs}); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to save role assignment of '%s': %v", user, err)
+			return "web.roles.error_save"
+		}
+		logger.Info("AUTH", "User '%s' assigned role %s to '%s' (chats: %v)", actor, role, user, chatIDs)
+	}
+	return ""
+}
+
+// RolesHandler returns an HTTP handler for the admin page that manages role assignments.
+// GET renders the page; POST saves (action=save) or removes (action=delete) the assignment
+// of a user and renders the updated list. Only admins may use it.
+func RolesHandler(tpl *template.Template, a *auth.Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IdentityFromContext(r.Context()).IsAdmin() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		data := RolesPageData{
+			Lang:             requestLanguage(r),
+			User:             auth.UserFromContext(r.Context()),
+			Roles:            models.Roles,
+			LocalUsers:       a.LocalUsers(),
+			ConfiguredAdmins: a.ConfiguredAdmins(),
+			CSRFToken:        auth.CSRFToken(r.Context()),
+		}
+
+		name := "roles-page"
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost:
+			data.Error = changeRole(r, a)
+			name = "roles-table"
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		assignments, err := storage.LoadRoleAssignments()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "LoadRoleAssignments error: %v", err)
+			http.Error(w, "Load error", http.StatusInternalServerError)
+			return
+		}
+		data.Assignments = assignments
+
+		if err := tpl.ExecuteTemplate(w, name, data); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Roles template execute error: %v", err)
+			http.Error(w, "Render error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+	"5mdt/bd_bot/internal/templates"
+)
+
+// requestAs returns a request of a local user with the role.
+func requestAs(method, target, body, role string, chatIDs ...int64) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if method == "POST" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	identity := &auth.Identity{Name: "alice", Role: role, ChatIDs: chatIDs}
+	return req.WithContext(auth.WithIdentity(req.Context(), identity))
+}
+
+func TestViewerCanOnlyRead(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	if err := storage.SaveBirthdays([]models.Birthday{{ID: "carol", Name: "Carol", BirthDate: "1990-05-10", ChatID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	IndexHandler(tpl, nil)(w, requestAs("GET", "/", "", models.RoleViewer))
+	body := w.Body.String()
+	if !strings.Contains(body, `value="Carol"`) {
+		t.Error("viewers should see records")
+	}
+	if strings.Contains(body, "/delete-row") || strings.Contains(body, "add-birthday-card") || !strings.Contains(body, "disabled") {
+		t.Error("viewers should get read-only cards without delete and add controls")
+	}
+	if strings.Contains(body, `hx-get="/bot-info"`) || strings.Contains(body, RolesPath) {
+		t.Error("bot status and role management are for admins only")
+	}
+
+	w = httptest.NewRecorder()
+	SaveRowHandler(tpl)(w, requestAs("POST", "/save-row", "id=carol&name=Eve&birth_date=1990-05-10", models.RoleViewer))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("viewer save: got %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	BotInfoHandler(tpl, nil)(w, requestAs("GET", "/bot-info", "", models.RoleViewer))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("viewer bot info: got %d, want 403", w.Code)
+	}
+
+	bs, _ := storage.LoadBirthdays()
+	w = httptest.NewRecorder()
+	APIBirthdaysHandler()(w, requestAs("DELETE", APIBirthdaysPath+"/"+bs[0].ID, "", models.RoleViewer))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("viewer API delete: got %d, want 403", w.Code)
+	}
+}
+
+func TestEditorChangesAssignedChats(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	if err := storage.SaveBirthdays([]models.Birthday{
+		{ID: "carol", Name: "Carol", BirthDate: "1990-05-10", ChatID: -100},
+		{ID: "dave", Name: "Dave", BirthDate: "1985-02-03", ChatID: -200},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	IndexHandler(tpl, nil)(w, requestAs("GET", "/", "", models.RoleEditor, -100))
+	body := w.Body.String()
+	if strings.Count(body, "/delete-row") != 1 || !strings.Contains(body, "add-birthday-card") {
+		t.Error("editors should get controls for the assigned chat only")
+	}
+
+	w = httptest.NewRecorder()
+	DeleteRowHandler(tpl)(w, requestAs("POST", "/delete-row", "id=dave", models.RoleEditor, -100))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("deleting a record of another chat: got %d, want 403", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	SaveRowHandler(tpl)(w, requestAs("POST", "/save-row", "id=carol&name=Caroline&birth_date=1990-05-10&chat_id=-100", models.RoleEditor, -100))
+	if w.Code != http.StatusOK {
+		t.Errorf("saving a record of the assigned chat: got %d, want 200", w.Code)
+	}
+}
+
+func TestRolesHandler(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	a := auth.New(auth.Config{Admins: []string{"root"}})
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	RolesHandler(tpl, a)(w, requestAs("GET", RolesPath, "", models.RoleEditor))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: got %d, want 403", w.Code)
+	}
+
+	post := func(values url.Values) string {
+		w := httptest.NewRecorder()
+		RolesHandler(tpl, a)(w, requestAs("POST", RolesPath, values.Encode(), models.RoleAdmin))
+		return w.Body.String()
+	}
+
+	body := post(url.Values{"action": {"save"}, "user": {"carol"}, "role": {"editor"}, "chat_ids": {"-100, 42"}})
+	if !strings.Contains(body, "<td>carol</td>") || !strings.Contains(body, "-100, 42") {
+		t.Errorf("assignment should be listed, got %s", body)
+	}
+	rs, _ := storage.LoadRoleAssignments()
+	if len(rs) != 1 || rs[0].Role != models.RoleEditor || len(rs[0].ChatIDs) != 2 {
+		t.Errorf("stored assignments = %+v", rs)
+	}
+
+	if body := post(url.Values{"action": {"save"}, "user": {"alice"}, "role": {"viewer"}}); !strings.Contains(body, "You can&#39;t change your own role") {
+		t.Error("admins should not change their own role")
+	}
+	if body := post(url.Values{"action": {"save"}, "user": {"root"}, "role": {"viewer"}}); !strings.Contains(body, "AUTH_ADMINS") {
+		t.Error("configured admins should not be changed")
+	}
+	if body := post(url.Values{"action": {"save"}, "user": {"dave"}, "role": {"owner"}}); !strings.Contains(body, "Choose a valid role") {
+		t.Error("unknown roles should be rejected")
+	}
+
+	post(url.Values{"action": {"delete"}, "user": {"carol"}})
+	if rs, _ := storage.LoadRoleAssignments(); len(rs) != 0 {
+		t.Errorf("assignment should be removed, got %+v", rs)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"5mdt/bd_bot/internal/storage"
 	"5mdt/bd_bot/internal/templates"
 )
 
@@ -21,7 +22,6 @@ func TestIntegration_SaveRowHandler(t *testing.T) {
 	tpl := templates.LoadTemplates()
 
 	form := url.Values{}
-	form.Set("idx", "-1")
 	form.Set("name", "TestName")
 	form.Set("birth_date", "12-31")
 	form.Set("last_notification", "2025-01-01T12:00:00Z")
@@ -40,3 +40,42 @@ func TestIntegration_SaveRowHandler(t *testing.T) {
 		t.Fatal("response missing saved name")
 	}
 }
+
+func TestIntegration_SaveRowHandlerConfirmsTypedDates(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "test.yaml"))
+	tpl := templates.LoadTemplates()
+
+	save := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/save-row", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept-Language", "en-GB")
+		w := httptest.NewRecorder()
+		SaveRowHandler(tpl)(w, req)
+		return w
+	}
+	form := url.Values{"name": {"Typed"}, "birth_date": {"03/04/1985"}, "chat_id": {"789"}}
+
+	w := save(form)
+	if w.Code != http.StatusOK || w.Header().Get("HX-Retarget") != "#date-confirm-new" {
+		t.Fatalf("want the confirmation in the add form, got %d retargeted to %q", w.Code, w.Header().Get("HX-Retarget"))
+	}
+	if body := w.Body.String(); !strings.Contains(body, "April 3, 1985") || !strings.Contains(body, `value="1985-04-03"`) {
+		t.Errorf("confirmation should spell out the date as read, got:\n%s", body)
+	}
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 0 {
+		t.Fatalf("nothing should be saved before the date is confirmed, got %+v", bs)
+	}
+
+	form.Set("birth_date_confirmed", "1985-04-03")
+	if w := save(form); w.Code != http.StatusOK || w.Header().Get("HX-Retarget") != "" {
+		t.Fatalf("want the confirmed record saved, got %d retargeted to %q", w.Code, w.Header().Get("HX-Retarget"))
+	}
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 1 || bs[0].BirthDate != "1985-04-03" {
+		t.Fatalf("want the record saved with the confirmed date, got %+v", bs)
+	}
+
+	form.Set("birth_date", "someday")
+	if w := save(form); w.Code != http.StatusBadRequest {
+		t.Errorf("want 400 for a date that can't be read, got %d", w.Code)
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"5mdt/bd_bot/internal/audit"
+	"5mdt/bd_bot/internal/auth"
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+)
+
+const (
+	// TrashPath is the path of the trash page.
+	TrashPath = "/trash"
+	// UndoDeletePath is the path that restores a record from the undo toast shown after deleting it.
+	UndoDeletePath = "/undo-delete"
+)
+
+// TrashItem is a deleted record shown in the trash.
+type TrashItem struct {
+	models.TrashedRecord
+	// ExpiresAt is when the record is purged from the trash.
+	ExpiresAt time.Time
+}
+
+// TrashPageData contains the data passed to the trash templates.
+type TrashPageData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// User is the signed-in user.
+	User string
+	// Items are the deleted records the user may restore, most recently deleted first.
+	Items []TrashItem
+	// RetentionDays is how many days deleted records are kept.
+	RetentionDays int
+	// Error is the catalog key of the error message, empty if there is none.
+	Error string
+	// CSRFToken is the token the forms must send.
+	CSRFToken string
+}
+
+// UndoToastData contains the data passed to the toast offering to undo a deletion.
+type UndoToastData struct {
+	// Lang is the language of the interface.
+	Lang string
+	// ID is the ID of the deleted record.
+	ID string
+	// Name is the name of the deleted record.
+	Name string
+	// CSRFToken is the token the undo form must send.
+	CSRFToken string
+}
+
+// trashRecord moves a deleted record to the trash on behalf of the signed-in user.
+// Records without an ID get one, so that they can be restored.
+func trashRecord(r *http.Request, b *models.Birthday) error {
+	if b.ID == "" {
+		b.ID = storage.NewID()
+	}
+	return storage.AddToTrash(auditActor(r), *b)
+}
+
+// restoreRecord moves the deleted record with the ID from the trash back to the records.
+// It fails with errNotFound if the record is not in the trash and errForbidden if the
+// signed-in user may not change it.
+func restoreRecord(r *http.Request, id string) error {
+	ts, err := storage.LoadTrash()
+	if err != nil {
+		return err
+	}
+	var record *models.Birthday
+	for i := range ts {
+		if ts[i].ID == id {
+			record = &ts[i].Birthday
+			break
+		}
+	}
+	if id == "" || record == nil {
+		return errNotFound
+	}
+	if !canEdit(r, record) {
+		return errForbidden
+	}
+
+	// The record is saved before it leaves the trash, so it is never lost in between;
+	// a record that is already back is not added twice
+	err = storage.UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+		for _, b := range bs {
+			if b.ID == id {
+				return bs, nil
+			}
+		}
+		return append(bs, *record), nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := removeFromTrash(id); err != nil {
+		return err
+	}
+	audit.Restored(auditActor(r), models.SourceWeb, *record)
+	logger.Info("HANDLERS", "Restored record %s ('%s') from the trash", id, record.Name)
+	return nil
+}
+
+// purgeRecord permanently deletes the record with the ID from the trash. It fails like restoreRecord.
+func purgeRecord(r *http.Request, id string) error {
+	var purged models.Birthday
+	err := storage.UpdateTrash(func(ts []models.TrashedRecord) ([]models.TrashedRecord, error) {
+		for i := range ts {
+			if ts[i].ID != id {
+				continue
+			}
+			if !canEdit(r, &ts[i].Birthday) {
+				return nil, errForbidden
+			}
+			purged = ts[i].Birthday
+			return append(ts[:i], ts[i+1:]...), nil
+		}
+		return nil, errNotFound
+	})
+	if err != nil {
+		return err
+	}
+	audit.Purged(auditActor(r), models.SourceWeb, purged)
+	logger.Info("HANDLERS", "Purged record %s ('%s') from the trash", id, purged.Name)
+	return nil
+}
+
+// removeFromTrash removes the record with the ID from the trash.
+func removeFromTrash(id string) error {
+	return storage.UpdateTrash(func(ts []models.TrashedRecord) ([]models.TrashedRecord, error) {
+		kept := ts[:0]
+		for _, t := range ts {
+			if t.ID != id {
+				kept = append(kept, t)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// trashErrorKey returns the catalog key of the message for a failed trash action.
+func trashErrorKey(err error) string {
+	switch {
+	case errors.Is(err, errNotFound):
+		return "web.trash.error_not_found"
+	case errors.Is(err, errForbidden):
+		return "web.trash.error_forbidden"
+	default:
+		return "web.trash.error_save"
+	}
+}
+
+// TrashHandler returns an HTTP handler for the trash page. GET lists the deleted records the
+// signed-in user may change; POST restores (action=restore) or permanently deletes (action=purge)
+// the record with the given id and renders the updated list.
+func TrashHandler(tpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.IdentityFromContext(r.Context())
+		if !identity.CanEditAny() {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		retention := storage.TrashRetention()
+		data := TrashPageData{
+			Lang:          requestLanguage(r),
+			User:          auth.UserFromContext(r.Context()),
+			RetentionDays: int(retention / (24 * time.Hour)),
+			CSRFToken:     auth.CSRFToken(r.Context()),
+		}
+
+		name := "trash-page"
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost:
+			var err error
+			switch id := r.FormValue("id"); r.FormValue("action") {
+			case "restore":
+				err = restoreRecord(r, id)
+			case "purge":
+				err = purgeRecord(r, id)
+			default:
+				http.Error(w, "Unknown action", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				logger.WarnContext(r.Context(), "HANDLERS", "Trash action %s on record %s failed: %v", r.FormValue("action"), r.FormValue("id"), err)
+				data.Error = trashErrorKey(err)
+			}
+			name = "trash-table"
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ts, err := storage.LoadTrash()
+		if err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "LoadTrash error: %v", err)
+			http.Error(w, "Load error", http.StatusInternalServerError)
+			return
+		}
+		for i := len(ts) - 1; i >= 0; i-- {
+			if identity.CanEdit(ts[i].ChatID) {
+				data.Items = append(data.Items, TrashItem{TrashedRecord: ts[i], ExpiresAt: ts[i].DeletedAt.Add(retention)})
+			}
+		}
+
+		if err := tpl.ExecuteTemplate(w, name, data); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Trash template execute error: %v", err)
+			http.Error(w, "Render error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// UndoDeleteHandler returns an HTTP handler that restores the record with the given id from the
+// trash, for the undo toast shown after a delete. It renders the table and clears the toast.
+func UndoDeleteHandler(tpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+
+		err := restoreRecord(r, r.FormValue("id"))
+		switch {
+		case errors.Is(err, errNotFound):
+			http.Error(w, "Record not found", http.StatusNotFound)
+			return
+		case errors.Is(err, errForbidden):
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		case err != nil:
+			logger.ErrorContext(r.Context(), "HANDLERS", "Failed to restore record %s: %v", r.FormValue("id"), err)
+			http.Error(w, "Save error", http.StatusInternalServerError)
+			return
+		}
+
+		bs, ok := loadBirthdaysOrError(w, r)
+		if !ok {
+			return
+		}
+		if err := tpl.ExecuteTemplate(w, "table", requestTableData(r, bs, r.FormValue("filter"), r.FormValue("tag_filter"))); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Template execute error: %v", err)
+			http.Error(w, "Render error", http.StatusInternalServerError)
+			return
+		}
+		if err := tpl.ExecuteTemplate(w, "undo-toast", nil); err != nil {
+			logger.ErrorContext(r.Context(), "HANDLERS", "Toast template execute error: %v", err)
+		}
+	}
+}
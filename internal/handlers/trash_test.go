@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/models"
+	"5mdt/bd_bot/internal/storage"
+	"5mdt/bd_bot/internal/templates"
+)
+
+// postForm returns a POST request with the form values.
+func postForm(target string, values url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestDeleteAndUndo(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	if err := storage.SaveBirthdays([]models.Birthday{{Name: "Alice", BirthDate: "1990-05-10", ChatID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := storage.LoadBirthdays()
+	id := bs[0].ID
+	tpl := templates.LoadTemplates()
+
+	w := httptest.NewRecorder()
+	DeleteRowHandler(tpl)(w, postForm("/delete-row", url.Values{"id": {id}}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `name="id" value="`+id+`"`) {
+		t.Error("the toast should undo the deletion of the record")
+	}
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 0 {
+		t.Fatalf("record should be deleted, got %+v", bs)
+	}
+	ts, err := storage.LoadTrash()
+	if err != nil || len(ts) != 1 || ts[0].ID != id || ts[0].DeletedAt.IsZero() {
+		t.Fatalf("record should be in the trash, got %+v, %v", ts, err)
+	}
+
+	w = httptest.NewRecorder()
+	UndoDeleteHandler(tpl)(w, postForm(UndoDeletePath, url.Values{"id": {id}}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `value="Alice"`) || !strings.Contains(w.Body.String(), `<div id="toast" hx-swap-oob="true"></div>`) {
+		t.Error("undo should render the table with the record and clear the toast")
+	}
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 1 || bs[0].ID != id {
+		t.Errorf("record should be restored with its ID, got %+v", bs)
+	}
+	if ts, _ := storage.LoadTrash(); len(ts) != 0 {
+		t.Errorf("restored record should leave the trash, got %+v", ts)
+	}
+
+	w = httptest.NewRecorder()
+	UndoDeleteHandler(tpl)(w, postForm(UndoDeletePath, url.Values{"id": {id}}))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("undoing twice should fail with 404, got %d", w.Code)
+	}
+}
+
+func TestTrashHandler(t *testing.T) {
+	t.Setenv("YAML_PATH", t.TempDir()+"/test.yaml")
+	err := storage.AddToTrash("admin",
+		models.Birthday{ID: "a1", Name: "Alice", BirthDate: "1990-05-10", ChatID: 42},
+		models.Birthday{ID: "b2", Name: "Bob", BirthDate: "1985-02-03", ChatID: 7},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := templates.LoadTemplates()
+
+	// Telegram users only see the deleted records of their own chat
+	w := httptest.NewRecorder()
+	TrashHandler(tpl)(w, requestAsTelegramUser(TrashPath, 42))
+	if body := w.Body.String(); !strings.Contains(body, "Alice") || strings.Contains(body, "Bob") {
+		t.Errorf("only records of editable chats should be listed, got %s", body)
+	}
+
+	req := postForm(TrashPath, url.Values{"action": {"purge"}, "id": {"b2"}})
+	identityReq := requestAsTelegramUser(TrashPath, 42)
+	w = httptest.NewRecorder()
+	TrashHandler(tpl)(w, req.WithContext(identityReq.Context()))
+	if !strings.Contains(w.Body.String(), "You may not change records of this chat.") {
+		t.Error("purging records of other chats should be refused")
+	}
+
+	w = httptest.NewRecorder()
+	TrashHandler(tpl)(w, postForm(TrashPath, url.Values{"action": {"purge"}, "id": {"b2"}}))
+	if strings.Contains(w.Body.String(), "Bob") {
+		t.Error("purged record should leave the trash")
+	}
+
+	w = httptest.NewRecorder()
+	TrashHandler(tpl)(w, postForm(TrashPath, url.Values{"action": {"restore"}, "id": {"a1"}}))
+	if !strings.Contains(w.Body.String(), "The trash is empty.") {
+		t.Error("restored record should leave the trash")
+	}
+	if bs, _ := storage.LoadBirthdays(); len(bs) != 1 || bs[0].Name != "Alice" {
+		t.Errorf("record should be restored, got %+v", bs)
+	}
+
+	es, _ := storage.LoadAuditLog()
+	if len(es) != 2 || es[0].Action != models.AuditPurge || es[1].Action != models.AuditRestore {
+		t.Errorf("purge and restore should be audited, got %+v", es)
+	}
+}
@@ -0,0 +1,187 @@
+// Package i18n provides message catalogs for the bot replies and the web interface.
+// Catalogs are embedded YAML files keyed by message ID; plural messages carry CLDR
+// plural forms (one, few, many, other) selected per language.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLanguage is used when no supported language can be determined.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.yaml
+var localesFS embed.FS
+
+// message is a catalog entry: either a plain text or a set of plural forms.
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+// UnmarshalYAML accepts either a scalar string or a mapping of plural forms.
+func (m *message) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&m.text)
+	}
+	return node.Decode(&m.plural)
+}
+
+// catalogs maps language codes to their messages.
+var catalogs = map[string]map[string]message{}
+
+func init() {
+	files, err := localesFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: reading catalogs: %v", err))
+	}
+	for _, f := range files {
+		data, err := localesFS.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: reading %s: %v", f.Name(), err))
+		}
+		catalog := map[string]message{}
+		if err := yaml.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: parsing %s: %v", f.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(f.Name(), path.Ext(f.Name()))] = catalog
+	}
+	if _, ok := catalogs[DefaultLanguage]; !ok {
+		panic("i18n: default catalog is missing")
+	}
+}
+
+// Supported returns the codes of all languages with a catalog, sorted alphabetically.
+func Supported() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Normalize maps a language tag such as "ru-RU" or "EN" to a supported language code.
+// It returns an empty string if the language is not supported.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tag, _, _ = strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	return ""
+}
+
+// Resolve returns the supported language for the tag, falling back to DefaultLanguage.
+func Resolve(tag string) string {
+	if lang := Normalize(tag); lang != "" {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// MatchAcceptLanguage picks the first supported language from an Accept-Language header,
+// honouring the listed order and skipping entries with q=0.
+func MatchAcceptLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if _, err := fmt.Sscanf(params, "q=%g", &q); err != nil {
+				q = 0
+			}
+		}
+		if lang := Normalize(tag); lang != "" && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	if len(candidates) > 0 {
+		return candidates[0].lang
+	}
+	return DefaultLanguage
+}
+
+// lookup finds the message in the language catalog, falling back to the default language.
+func lookup(lang, key string) (message, bool) {
+	if msg, ok := catalogs[Resolve(lang)][key]; ok {
+		return msg, true
+	}
+	msg, ok := catalogs[DefaultLanguage][key]
+	return msg, ok
+}
+
+// T returns the translated message for the key, formatted with args.
+// Missing messages are returned as the key itself so they are easy to spot.
+func T(lang, key string, args ...interface{}) string {
+	msg, ok := lookup(lang, key)
+	if !ok {
+		return key
+	}
+	text := msg.text
+	if msg.plural != nil {
+		text = msg.plural["other"]
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// N returns the plural form of the message matching n, formatted with n followed by args.
+func N(lang, key string, n int, args ...interface{}) string {
+	msg, ok := lookup(lang, key)
+	if !ok {
+		return key
+	}
+	text := msg.text
+	if msg.plural != nil {
+		text, ok = msg.plural[pluralCategory(Resolve(lang), n)]
+		if !ok {
+			text = msg.plural["other"]
+		}
+	}
+	return fmt.Sprintf(text, append([]interface{}{n}, args...)...)
+}
+
+// HTML returns the translated message as trusted HTML. Catalogs are embedded in the binary,
+// so only messages authored with markup (keys ending in "_html") should be rendered this way.
+func HTML(lang, key string, args ...interface{}) template.HTML {
+	return template.HTML(T(lang, key, args...))
+}
+
+// pluralCategory returns the CLDR plural category of n for the language.
+func pluralCategory(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	switch lang {
+	case "ru", "uk":
+		mod10, mod100 := n%10, n%100
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
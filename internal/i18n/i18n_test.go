@@ -0,0 +1,92 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	base := catalogs[DefaultLanguage]
+	for lang, catalog := range catalogs {
+		for key := range base {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("catalog %q is missing key %q", lang, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := base[key]; !ok {
+				t.Errorf("catalog %q has key %q not present in %q", lang, key, DefaultLanguage)
+			}
+		}
+	}
+}
+
+func TestPluralMessagesHaveOther(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for key, msg := range catalog {
+			if msg.plural != nil && msg.plural["other"] == "" {
+				t.Errorf("plural message %q in %q has no \"other\" form", key, lang)
+			}
+		}
+	}
+}
+
+func TestN(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 1, "in 1 week"},
+		{"en", 2, "in 2 weeks"},
+		{"ru", 1, "через 1 неделю"},
+		{"ru", 2, "через 2 недели"},
+		{"ru", 5, "через 5 недель"},
+		{"ru", 11, "через 11 недель"},
+		{"ru", 21, "через 21 неделю"},
+		{"ru", 22, "через 22 недели"},
+	}
+	for _, tt := range tests {
+		if got := N(tt.lang, "time.in_weeks", tt.n); got != tt.want {
+			t.Errorf("N(%q, time.in_weeks, %d) = %q; want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTFallbacks(t *testing.T) {
+	if got := T("xx", "web.delete"); got != "Delete" {
+		t.Errorf("unsupported language should fall back to English, got %q", got)
+	}
+	if got := T("en", "no.such.key"); got != "no.such.key" {
+		t.Errorf("missing key should be returned as is, got %q", got)
+	}
+	if got := T("ru", "bot.permission_denied", "language"); !strings.Contains(got, "/language") {
+		t.Errorf("arguments should be formatted into the message, got %q", got)
+	}
+}
+
+func TestMatchAcceptLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                           "en",
+		"ru-RU,ru;q=0.9,en-US;q=0.8": "ru",
+		"de-DE,de;q=0.9,ru;q=0.8":    "ru",
+		"en-US,en;q=0.9,ru;q=0.8":    "en",
+		"ru;q=0.5,en;q=0.9":          "en",
+		"ru;q=0,fr":                  "en",
+		"*":                          "en",
+	}
+	for header, want := range tests {
+		if got := MatchAcceptLanguage(header); got != want {
+			t.Errorf("MatchAcceptLanguage(%q) = %q; want %q", header, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{"ru-RU": "ru", "EN": "en", "pt_BR": "", "": ""}
+	for tag, want := range tests {
+		if got := Normalize(tag); got != want {
+			t.Errorf("Normalize(%q) = %q; want %q", tag, got, want)
+		}
+	}
+}
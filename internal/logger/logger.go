@@ -5,6 +5,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -102,6 +103,38 @@ func Error(component string, format string, args ...interface{}) {
 	}
 }
 
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID, which WarnContext and ErrorContext add to log lines.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID prefixes the format with the request ID of ctx, as in LogRequest lines.
+func withRequestID(ctx context.Context, format string) string {
+	if id := RequestID(ctx); id != "" {
+		return "[" + id + "] " + format
+	}
+	return format
+}
+
+// WarnContext logs a warning like Warn, prefixed with the request ID of ctx.
+func WarnContext(ctx context.Context, component string, format string, args ...interface{}) {
+	Warn(component, withRequestID(ctx, format), args...)
+}
+
+// ErrorContext logs an error like Error, prefixed with the request ID of ctx.
+func ErrorContext(ctx context.Context, component string, format string, args ...interface{}) {
+	Error(component, withRequestID(ctx, format), args...)
+}
+
 // IsDebugEnabled returns true if debug logging is enabled via environment variables.
 func IsDebugEnabled() bool {
 	return debugEnabled
@@ -127,13 +160,13 @@ func Errorf(format string, args ...interface{}) {
 	Error("", format, args...)
 }
 
-// LogRequest logs HTTP request information including method, path, status code, and duration.
+// LogRequest logs HTTP request information including request ID, method, path, status code, and duration.
 // In debug mode, it also logs the user agent string.
-func LogRequest(method, path, userAgent string, statusCode int, duration time.Duration) {
+func LogRequest(requestID, method, path, userAgent string, statusCode int, duration time.Duration) {
 	if debugEnabled {
-		Debug("HTTP", "%s %s - %d (%v) - %s", method, path, statusCode, duration, userAgent)
+		Debug("HTTP", "[%s] %s %s - %d (%v) - %s", requestID, method, path, statusCode, duration, userAgent)
 	} else {
-		Info("HTTP", "%s %s - %d", method, path, statusCode)
+		Info("HTTP", "[%s] %s %s - %d (%v)", requestID, method, path, statusCode, duration)
 	}
 }
 
@@ -169,6 +202,11 @@ func LogStorage(operation string, details string, err error) {
 	}
 }
 
+// LogAudit logs security-relevant actions (e.g., denied commands) attributed to a Telegram user.
+func LogAudit(action string, userID int64, format string, args ...interface{}) {
+	Info("AUDIT", "%s by user %d: %s", action, userID, fmt.Sprintf(format, args...))
+}
+
 // LogNotification logs birthday notification events with the specified severity level.
 func LogNotification(level string, message string, args ...interface{}) {
 	component := "NOTIFICATION"
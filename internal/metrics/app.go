@@ -0,0 +1,35 @@
+package metrics
+
+// Application metrics. Durations are in seconds.
+var (
+	// NotificationsSent counts notifications sent by the bot, by notification type (e.g. BIRTHDAY_TODAY, DIGEST_WEEKLY).
+	NotificationsSent = NewCounter("bdbot_notifications_sent_total",
+		"Notifications sent by the bot, by notification type.", "type")
+	// NotificationsFailed counts notifications that could not be sent, by notification type.
+	NotificationsFailed = NewCounter("bdbot_notifications_failed_total",
+		"Notifications that failed to send, by notification type.", "type")
+	// CheckDuration observes the duration of birthday check passes within the notification hours.
+	CheckDuration = NewHistogram("bdbot_birthday_check_duration_seconds",
+		"Duration of birthday check passes.", DefaultBuckets)
+	// RecordsLoaded is the number of records loaded by the last birthday check pass.
+	RecordsLoaded = NewGauge("bdbot_records_loaded",
+		"Records loaded by the last birthday check pass.")
+	// RecordsSkipped counts the records skipped by birthday check passes, by reason.
+	RecordsSkipped = NewCounter("bdbot_records_skipped_total",
+		"Records skipped by birthday check passes, by reason.", "reason")
+	// TelegramRequestDuration observes the latency of Telegram Bot API requests, by API method.
+	TelegramRequestDuration = NewHistogram("bdbot_telegram_request_duration_seconds",
+		"Latency of Telegram Bot API requests, by API method.", SlowBuckets, "method")
+	// TelegramErrors counts failed Telegram Bot API requests, by API method.
+	TelegramErrors = NewCounter("bdbot_telegram_errors_total",
+		"Failed Telegram Bot API requests, by API method.", "method")
+	// HTTPRequests counts HTTP requests, by route, method and status code.
+	HTTPRequests = NewCounter("bdbot_http_requests_total",
+		"HTTP requests, by route, method and status code.", "route", "method", "code")
+	// HTTPRequestDuration observes the latency of HTTP requests, by route.
+	HTTPRequestDuration = NewHistogram("bdbot_http_request_duration_seconds",
+		"Latency of HTTP requests, by route.", DefaultBuckets, "route")
+	// StorageDuration observes the duration of storage operations, by operation (load or save) and file.
+	StorageDuration = NewHistogram("bdbot_storage_operation_duration_seconds",
+		"Duration of storage operations, by operation and file.", DefaultBuckets, "operation", "file")
+)
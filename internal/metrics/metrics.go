@@ -0,0 +1,347 @@
+// Package metrics collects application metrics and serves them in the Prometheus text exposition format.
+// It implements the small subset of the Prometheus client needed here: counters, gauges and histograms with labels.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"5mdt/bd_bot/internal/logger"
+)
+
+// contentType is the media type of the Prometheus text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DefaultBuckets are the histogram buckets, in seconds, for fast operations such as HTTP requests.
+	DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// SlowBuckets are the histogram buckets, in seconds, for operations that may take up to a minute,
+	// such as Telegram long polling.
+	SlowBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+)
+
+// metric is a metric family that can be written in the text format.
+type metric interface {
+	// metricName returns the name of the metric family.
+	metricName() string
+	// write writes the HELP and TYPE lines and all samples of the family.
+	write(w io.Writer)
+}
+
+// registry holds the registered metric families.
+type registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// defaultRegistry holds all metrics created with NewCounter, NewGauge and NewHistogram.
+var defaultRegistry = &registry{metrics: make(map[string]metric)}
+
+// register adds the metric family to the registry. Registering a name twice is a programming error.
+func (r *registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[m.metricName()]; ok {
+		panic("metrics: duplicate metric " + m.metricName())
+	}
+	r.metrics[m.metricName()] = m
+}
+
+// write writes all metric families sorted by name.
+func (r *registry) write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	families := make([]metric, len(names))
+	for i, name := range names {
+		families[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	for _, m := range families {
+		m.write(w)
+	}
+}
+
+// Handler returns the HTTP handler serving all metrics in the Prometheus text format.
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		defaultRegistry.write(w)
+	}
+}
+
+// desc describes a metric family.
+type desc struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+func (d desc) metricName() string {
+	return d.name
+}
+
+// key returns the map key of the label values, checking that they match the label names.
+func (d desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// writeHeader writes the HELP and TYPE lines of the family.
+func (d desc) writeHeader(w io.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, typ)
+}
+
+// labels formats the label pairs, followed by an optional extra pair such as le="0.5".
+func (d desc) labels(values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range d.labelNames {
+		pairs = append(pairs, name+`="`+escapeLabelValue(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeHelp escapes backslashes and line feeds in HELP text.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds in label values.
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// formatFloat formats a sample value.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of the map in sorted order, so that the output is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter is a monotonically increasing value per combination of label values.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+// counterValue is the value of a counter or gauge for one combination of label values.
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounter creates and registers a counter. A counter without labels is exported as 0 until it is increased.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labelNames: labelNames}, values: make(map[string]*counterValue)}
+	if len(labelNames) == 0 {
+		c.values[""] = &counterValue{}
+	}
+	defaultRegistry.register(c)
+	return c
+}
+
+// Inc increases the counter of the label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter of the label values by v, which must not be negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		logger.Warn("METRICS", "Ignoring negative increment of %s", c.name)
+		return
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+// Value returns the current value of the counter of the label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cv, ok := c.values[key]; ok {
+		return cv.value
+	}
+	return 0
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeHeader(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labels(cv.labelValues, "", ""), formatFloat(cv.value))
+	}
+}
+
+// Gauge is a value that can go up and down, such as the size of the last batch, per combination of label values.
+type Gauge struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+// NewGauge creates and registers a gauge. A gauge without labels is exported as 0 until it is set.
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help, labelNames: labelNames}, values: make(map[string]*counterValue)}
+	if len(labelNames) == 0 {
+		g.values[""] = &counterValue{}
+	}
+	defaultRegistry.register(g)
+	return g
+}
+
+// Set sets the gauge of the label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	gv, ok := g.values[key]
+	if !ok {
+		gv = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		g.values[key] = gv
+	}
+	gv.value = v
+}
+
+// Value returns the current value of the gauge of the label values.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gv, ok := g.values[key]; ok {
+		return gv.value
+	}
+	return 0
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeHeader(w, "gauge")
+	for _, key := range sortedKeys(g.values) {
+		gv := g.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labels(gv.labelValues, "", ""), formatFloat(gv.value))
+	}
+}
+
+// Histogram counts observations, such as durations in seconds, in cumulative buckets per combination of label values.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+// histogramValue holds the observations of a histogram for one combination of label values.
+type histogramValue struct {
+	labelValues []string
+	// counts holds the number of observations per bucket, not cumulated.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates and registers a histogram with the given ascending bucket upper bounds.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labelNames: labelNames},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	if len(labelNames) == 0 {
+		h.values[""] = &histogramValue{counts: make([]uint64, len(buckets))}
+	}
+	defaultRegistry.register(h)
+	return h
+}
+
+// Observe adds an observation for the label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labelValues: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+// ObserveSince adds the time elapsed since start, in seconds, as an observation for the label values.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns the number of observations for the label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hv, ok := h.values[key]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(hv.labelValues, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labels(hv.labelValues, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labels(hv.labelValues, "", ""), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labels(hv.labelValues, "", ""), hv.count)
+	}
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// sampleLine matches a sample in the text exposition format: name, optional labels and a value.
+var sampleLine = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*(\{[a-zA-Z_][a-zA-Z0-9_]*="(\\.|[^"\\])*"(,[a-zA-Z_][a-zA-Z0-9_]*="(\\.|[^"\\])*")*\})? (\+Inf|-Inf|NaN|-?[0-9.eE+-]+)$`)
+
+// scrape serves the metrics handler and returns the response body, checking the format of every line.
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler()(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	body := w.Body.String()
+	typed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+		case strings.HasPrefix(line, "# TYPE "):
+			fields := strings.Fields(line)
+			if len(fields) != 4 || (fields[3] != "counter" && fields[3] != "gauge" && fields[3] != "histogram") {
+				t.Errorf("invalid TYPE line %q", line)
+				continue
+			}
+			typed[fields[2]] = true
+		case sampleLine.MatchString(line):
+			name := line[:strings.IndexAny(line, "{ ")]
+			base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+			if !typed[name] && !typed[base] {
+				t.Errorf("sample %q has no preceding TYPE line", line)
+			}
+		default:
+			t.Errorf("invalid line %q", line)
+		}
+	}
+	return body
+}
+
+func TestHandlerExposesAppMetrics(t *testing.T) {
+	NotificationsSent.Inc("BIRTHDAY_TODAY")
+	HTTPRequests.Inc("/api/v1/birthdays/", "GET", "200")
+	StorageDuration.Observe(0.003, "load", "birthdays.yaml")
+
+	body := scrape(t)
+	for _, want := range []string{
+		"# TYPE bdbot_notifications_sent_total counter",
+		`bdbot_notifications_sent_total{type="BIRTHDAY_TODAY"} 1`,
+		"# TYPE bdbot_records_loaded gauge",
+		"bdbot_records_loaded 0",
+		`bdbot_http_requests_total{route="/api/v1/birthdays/",method="GET",code="200"} 1`,
+		"# TYPE bdbot_storage_operation_duration_seconds histogram",
+		`bdbot_storage_operation_duration_seconds_bucket{operation="load",file="birthdays.yaml",le="0.005"} 1`,
+		"bdbot_birthday_check_duration_seconds_count 0",
+		"# HELP bdbot_telegram_request_duration_seconds",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %q", want)
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	c := NewCounter("test_counter_total", "Test counter.", "label")
+	c.Inc("a")
+	c.Add(2.5, "a")
+	c.Add(-1, "a")
+	c.Inc(`quote"back\slash`)
+
+	if got := c.Value("a"); got != 3.5 {
+		t.Errorf("Value() = %v, want 3.5", got)
+	}
+	body := scrape(t)
+	if !strings.Contains(body, `test_counter_total{label="a"} 3.5`) {
+		t.Error("counter value missing from scrape")
+	}
+	if !strings.Contains(body, `test_counter_total{label="quote\"back\\slash"} 1`) {
+		t.Error("label values should be escaped")
+	}
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge("test_gauge", "Test gauge.", "label")
+	g.Set(5, "a")
+	g.Set(2, "a")
+
+	if got := g.Value("a"); got != 2 {
+		t.Errorf("Value() = %v, want 2", got)
+	}
+	body := scrape(t)
+	if !strings.Contains(body, "# TYPE test_gauge gauge") || !strings.Contains(body, `test_gauge{label="a"} 2`) {
+		t.Error("gauge value missing from scrape")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_duration_seconds", "Test histogram.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	if got := h.Count(); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+	body := scrape(t)
+	for _, want := range []string{
+		`test_duration_seconds_bucket{le="0.1"} 1`,
+		`test_duration_seconds_bucket{le="1"} 2`,
+		`test_duration_seconds_bucket{le="+Inf"} 3`,
+		"test_duration_seconds_sum 5.55",
+		"test_duration_seconds_count 3",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %q", want)
+		}
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	c := NewCounter("test_mismatch_total", "Test counter.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("wrong number of label values should panic")
+		}
+	}()
+	c.Inc("only-one")
+}
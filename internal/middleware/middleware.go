@@ -0,0 +1,189 @@
+// Package middleware provides the HTTP middleware shared by all routes of the web server:
+// request IDs, request logging, metrics, panic recovery and request body limits.
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/metrics"
+)
+
+// RequestIDHeader is the header carrying the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the length of request IDs accepted from clients.
+const maxRequestIDLength = 64
+
+// Chain wraps the handler with the middleware; the first middleware is the outermost one.
+func Chain(h http.Handler, middleware ...func(http.Handler) http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// RequestIDFromContext returns the ID of the request, or "" if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	return logger.RequestID(ctx)
+}
+
+// validRequestID reports whether a request ID sent by a client can be used in logs:
+// short and made of letters, digits, dashes, dots and underscores only.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID of 16 hex characters.
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// RequestID assigns every request an ID: the X-Request-ID header of the client (e.g. a reverse
+// proxy) if it is valid, otherwise a new random one. The ID is stored in the request context
+// and returned in the X-Request-ID response header; logger.ErrorContext and WarnContext add it to log lines.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+	})
+}
+
+// statusRecorder remembers the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it.
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the implicit 200 status of responses without WriteHeader.
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// recorderFor returns w as a status recorder, wrapping it if needed.
+func recorderFor(w http.ResponseWriter) *statusRecorder {
+	if rec, ok := w.(*statusRecorder); ok {
+		return rec
+	}
+	return &statusRecorder{ResponseWriter: w}
+}
+
+// LogRequests logs every request with its request ID, status code and duration.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := recorderFor(w)
+		defer func() {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logger.LogRequest(RequestIDFromContext(r.Context()), r.Method, r.URL.Path, r.UserAgent(), status, time.Since(start))
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// Metrics counts requests and observes their latency per route. The route function maps a request
+// to the pattern it is served by, so that e.g. all record IDs share one route label.
+func Metrics(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := recorderFor(w)
+			defer func() {
+				status := rec.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				name := route(r)
+				metrics.HTTPRequests.Inc(name, r.Method, strconv.Itoa(status))
+				metrics.HTTPRequestDuration.ObserveSince(start, name)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// Recover turns panics of the handler into a 500 response and logs them with the stack trace.
+// Panics with http.ErrAbortHandler are passed on, as they deliberately abort the response.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recorderFor(w)
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logger.Error("HTTP", "[%s] Panic serving %s %s: %v\n%s",
+				RequestIDFromContext(r.Context()), r.Method, r.URL.Path, p, debug.Stack())
+			if rec.status == 0 {
+				http.Error(rec, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// LimitBody rejects request bodies larger than maxBytes: requests announcing a larger
+// Content-Length get 413 right away, and reading beyond the limit fails in the handler.
+func LimitBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				logger.Warn("HTTP", "[%s] Rejected %s %s with a %d byte body",
+					RequestIDFromContext(r.Context()), r.Method, r.URL.Path, r.ContentLength)
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"5mdt/bd_bot/internal/logger"
+	"5mdt/bd_bot/internal/metrics"
+)
+
+// captureLogs redirects the logger output for the duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { order = append(order, "handler") }),
+		mw("first"), mw("second"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := strings.Join(order, ","); got != "first,second,handler" {
+		t.Errorf("order = %s, want first,second,handler", got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(seen) != 16 || w.Header().Get(RequestIDHeader) != seen {
+		t.Errorf("generated ID %q, header %q", seen, w.Header().Get(RequestIDHeader))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "proxy-123")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if seen != "proxy-123" || w.Header().Get(RequestIDHeader) != "proxy-123" {
+		t.Errorf("incoming ID should be kept, got %q", seen)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if seen == "bad id\nwith newline" || len(seen) != 16 {
+		t.Errorf("invalid incoming ID should be replaced, got %q", seen)
+	}
+}
+
+func TestRequestIDInHandlerLogs(t *testing.T) {
+	logs := captureLogs(t)
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.ErrorContext(r.Context(), "TEST", "failed: %s", "disk full")
+		logger.Error("TEST", "without context")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-3")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := logs.String()
+	if !strings.Contains(out, "[ERROR] [TEST] [req-3] failed: disk full") {
+		t.Errorf("handler errors should be logged with the request ID, got %q", out)
+	}
+	if !strings.Contains(out, "[ERROR] [TEST] without context") {
+		t.Errorf("logs without context should be unchanged, got %q", out)
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	logs := captureLogs(t)
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), RequestID, LogRequests)
+
+	req := httptest.NewRequest(http.MethodGet, "/brew", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if out := logs.String(); !strings.Contains(out, "[req-1] GET /brew - 418") {
+		t.Errorf("request should be logged with ID and status, got %q", out)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logs := captureLogs(t)
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}), RequestID, LogRequests, Recover)
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIDHeader, "req-2")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("want 500, got %d", w.Code)
+	}
+	out := logs.String()
+	if !strings.Contains(out, "[req-2] Panic serving GET /panic: boom") || !strings.Contains(out, "goroutine") {
+		t.Errorf("panic should be logged with the stack, got %q", out)
+	}
+	if !strings.Contains(out, "[req-2] GET /panic - 500") {
+		t.Errorf("request should be logged with status 500, got %q", out)
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	var readErr error
+	h := LimitBody(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save-row", strings.NewReader("name=0123456789")))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: want 413, got %d", w.Code)
+	}
+
+	// Bodies of unknown length are cut off while reading
+	req := httptest.NewRequest(http.MethodPost, "/save-row", io.NopCloser(strings.NewReader("name=0123456789")))
+	req.ContentLength = -1
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if readErr == nil {
+		t.Error("reading beyond the limit should fail")
+	}
+
+	readErr = nil
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save-row", strings.NewReader("name=a")))
+	if w.Code != http.StatusOK || readErr != nil {
+		t.Errorf("small body: got %d, %v", w.Code, readErr)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	route := func(*http.Request) string { return "/api/v1/birthdays/" }
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}), Metrics(route), Recover)
+
+	before := metrics.HTTPRequests.Value("/api/v1/birthdays/", http.MethodGet, "404")
+	observed := metrics.HTTPRequestDuration.Count("/api/v1/birthdays/")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/birthdays/abc", nil))
+
+	if got := metrics.HTTPRequests.Value("/api/v1/birthdays/", http.MethodGet, "404"); got != before+1 {
+		t.Errorf("request count = %v, want %v", got, before+1)
+	}
+	if got := metrics.HTTPRequestDuration.Count("/api/v1/birthdays/"); got != observed+1 {
+		t.Errorf("latency observations = %d, want %d", got, observed+1)
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Actions of audit entries.
+const (
+	// AuditCreate is the creation of a record.
+	AuditCreate = "create"
+	// AuditUpdate is a change of a record.
+	AuditUpdate = "update"
+	// AuditDelete is the deletion of a record, which moves it to the trash.
+	AuditDelete = "delete"
+	// AuditRestore is the restoration of a deleted record from the trash.
+	AuditRestore = "restore"
+	// AuditPurge is the permanent deletion of a record from the trash.
+	AuditPurge = "purge"
+	// AuditExport is the export of a record with the personal data of a chat.
+	AuditExport = "export"
+)
+
+// Sources of audit entries.
+const (
+	// SourceWeb is a change made in the web interface.
+	SourceWeb = "web"
+	// SourceAPI is a change made through the JSON API.
+	SourceAPI = "api"
+	// SourceBot is a change made with a bot command.
+	SourceBot = "bot"
+	// SourceImport is a change made by importing a CSV file in the web interface.
+	SourceImport = "import"
+)
+
+// AuditEntry records a change of a birthday record.
+type AuditEntry struct {
+	// Time is when the change was made.
+	Time time.Time `yaml:"time" json:"time"`
+	// Actor is who made the change: the web user, or "telegram:<id>" for Telegram users.
+	Actor string `yaml:"actor" json:"actor"`
+	// Source is where the change was made (SourceWeb, SourceAPI, SourceBot or SourceImport).
+	Source string `yaml:"source" json:"source"`
+	// Action is AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge or AuditExport.
+	Action string `yaml:"action" json:"action"`
+	// RecordID is the ID of the changed record.
+	RecordID string `yaml:"record_id" json:"record_id"`
+	// RecordName is the name of the record after the change, or before a deletion.
+	RecordName string `yaml:"record_name" json:"record_name"`
+	// Changes are the changed fields with their values before and after the change.
+	Changes []FieldChange `yaml:"changes,omitempty" json:"changes,omitempty"`
+	// Redacted indicates that the record name and field values were removed because the chat was forgotten.
+	Redacted bool `yaml:"redacted,omitempty" json:"redacted,omitempty"`
+}
+
+// FieldChange is the change of one field of a record.
+type FieldChange struct {
+	// Field is the name of the field, as in the YAML file (e.g. birth_date).
+	Field string `yaml:"field" json:"field"`
+	// Before is the value before the change, empty for created records.
+	Before string `yaml:"before,omitempty" json:"before,omitempty"`
+	// After is the value after the change, empty for deleted records.
+	After string `yaml:"after,omitempty" json:"after,omitempty"`
+}
@@ -1,16 +1,145 @@
 // Package models defines the data structures for the birthday notification application.
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
-// Birthday represents a person's birthday information stored for notifications.
+// Birthday represents a recurring event stored for notifications: a person's birthday,
+// or another event type such as a work anniversary (see EventTypes).
 type Birthday struct {
+	// ID is the stable identifier of the record, assigned by the storage layer.
+	ID string `yaml:"id,omitempty" json:"id,omitempty"`
 	// Name is the person's name or chat title.
-	Name string `yaml:"name"`
-	// BirthDate is the birth date in YYYY-MM-DD or 0000-MM-DD (year unknown) format.
-	BirthDate string `yaml:"birth_date"`
+	Name string `yaml:"name" json:"name"`
+	// Type is the event type; empty for birthdays (see EventType).
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// BirthDate is the birth date, or the original date of other events, in YYYY-MM-DD or 0000-MM-DD (year unknown) format.
+	BirthDate string `yaml:"birth_date" json:"birth_date"`
 	// LastNotification is the timestamp of the last birthday notification sent.
-	LastNotification time.Time `yaml:"last_notification"`
+	LastNotification time.Time `yaml:"last_notification" json:"last_notification"`
 	// ChatID is the Telegram chat ID for sending notifications.
-	ChatID int64 `yaml:"chat_id"`
+	ChatID int64 `yaml:"chat_id" json:"chat_id"`
+	// Tags label the record, e.g. "family" or "team-backend" (see ParseTags).
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Notes is free text kept with the record, e.g. gift ideas.
+	Notes string `yaml:"notes,omitempty" json:"notes,omitempty"`
+	// Links are additional chats that follow this birthday, e.g. team groups.
+	Links []ChatLink `yaml:"links,omitempty" json:"links,omitempty"`
+	// Routes are the chats that announce the record because they subscribe to one of its tags.
+	// They only hold the dedup state of those chats and are rebuilt from the chat settings.
+	Routes []ChatLink `yaml:"routes,omitempty" json:"routes,omitempty"`
+	// GiftCollections are the gift collections organised for upcoming birthdays in group chats.
+	GiftCollections []GiftCollection `yaml:"gift_collections,omitempty" json:"gift_collections,omitempty"`
+}
+
+// Notification preferences of a chat link.
+const (
+	// NotifyAll sends the birthday greeting and the reminders before it.
+	NotifyAll = ""
+	// NotifyGreeting sends only the greeting on the birthday itself.
+	NotifyGreeting = "greeting"
+	// NotifyMuted sends nothing; the birthday is still listed for the chat.
+	NotifyMuted = "muted"
+)
+
+// ChatLink connects a birthday record to an additional chat that announces it.
+type ChatLink struct {
+	// ChatID is the Telegram chat ID of the linked chat.
+	ChatID int64 `yaml:"chat_id" json:"chat_id"`
+	// Title is the display name of the linked chat.
+	Title string `yaml:"title,omitempty" json:"title,omitempty"`
+	// Notify is the notification preference of the link (NotifyAll, NotifyGreeting or NotifyMuted).
+	Notify string `yaml:"notify,omitempty" json:"notify,omitempty"`
+	// LastNotification is the timestamp of the last notification sent to the linked chat.
+	LastNotification time.Time `yaml:"last_notification,omitempty" json:"last_notification,omitempty"`
+}
+
+// Clone returns a deep copy of the record, so that changes to the copy's slices don't affect the original.
+func (b Birthday) Clone() Birthday {
+	c := b
+	c.Tags = append([]string(nil), b.Tags...)
+	c.Links = append([]ChatLink(nil), b.Links...)
+	c.Routes = append([]ChatLink(nil), b.Routes...)
+	c.GiftCollections = nil
+	for _, g := range b.GiftCollections {
+		g.Participants = append([]GiftParticipant(nil), g.Participants...)
+		c.GiftCollections = append(c.GiftCollections, g)
+	}
+	return c
+}
+
+// GiftCollection is a gift collection for one birthday in one group chat.
+type GiftCollection struct {
+	// ChatID is the group chat the collection runs in.
+	ChatID int64 `yaml:"chat_id" json:"chat_id"`
+	// Date is the birthday the collection is for, in YYYY-MM-DD format.
+	Date string `yaml:"date" json:"date"`
+	// MessageID is the bot message carrying the join button.
+	MessageID int `yaml:"message_id" json:"message_id"`
+	// Participants are the group members who joined the collection.
+	Participants []GiftParticipant `yaml:"participants,omitempty" json:"participants,omitempty"`
+	// SummarySent indicates whether the summary was posted; the collection is closed afterwards.
+	SummarySent bool `yaml:"summary_sent,omitempty" json:"summary_sent,omitempty"`
+}
+
+// GiftParticipant is a group member taking part in a gift collection.
+type GiftParticipant struct {
+	// UserID is the Telegram user ID of the participant.
+	UserID int64 `yaml:"user_id" json:"user_id"`
+	// Name is the display name of the participant.
+	Name string `yaml:"name" json:"name"`
+}
+
+// GiftCollectionIndex returns the index of the collection for the chat and birthday date, or -1.
+func (b *Birthday) GiftCollectionIndex(chatID int64, date string) int {
+	for i, c := range b.GiftCollections {
+		if c.ChatID == chatID && c.Date == date {
+			return i
+		}
+	}
+	return -1
+}
+
+// LinkIndex returns the index of the link to the chat, or -1 if the record is not linked to it.
+func (b *Birthday) LinkIndex(chatID int64) int {
+	for i, l := range b.Links {
+		if l.ChatID == chatID {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseTags splits user input such as "family, #team backend" into normalised tags:
+// lower case, without a leading '#', with inner spaces replaced by '-' and without duplicates.
+// Tags are separated by commas, or by spaces if the input has no commas.
+func ParseTags(s string) []string {
+	sep := ","
+	if !strings.Contains(s, ",") {
+		sep = " "
+	}
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, sep) {
+		tag := strings.Join(strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(part), "#"))), "-")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// HasTag reports whether the record is labelled with the tag.
+func (b Birthday) HasTag(tag string) bool {
+	for _, t := range b.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
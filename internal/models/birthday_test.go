@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -26,3 +27,74 @@ func TestBirthdayStruct(t *testing.T) {
 		t.Errorf("expected ChatID 12345, got %d", b.ChatID)
 	}
 }
+
+func TestBirthdayLinkIndex(t *testing.T) {
+	b := Birthday{ChatID: 1, Links: []ChatLink{{ChatID: -100}, {ChatID: -200, Notify: NotifyMuted}}}
+	if got := b.LinkIndex(-200); got != 1 {
+		t.Errorf("expected link index 1, got %d", got)
+	}
+	if got := b.LinkIndex(1); got != -1 {
+		t.Errorf("the owner chat is not a link, got index %d", got)
+	}
+}
+
+func TestBirthdayEventType(t *testing.T) {
+	b := Birthday{Name: "Alice"}
+	if b.EventType() != EventBirthday || !b.IsBirthday() {
+		t.Errorf("records without a type should be birthdays, got %q", b.EventType())
+	}
+
+	b.SetEventType(EventAnniversary)
+	if b.Type != EventAnniversary || b.IsBirthday() {
+		t.Errorf("expected anniversary, got %q", b.Type)
+	}
+
+	b.SetEventType(EventBirthday)
+	if b.Type != "" {
+		t.Errorf("birthdays should be stored without a type, got %q", b.Type)
+	}
+}
+
+func TestParseEventType(t *testing.T) {
+	tests := map[string]string{
+		"birthday":         EventBirthday,
+		"Anniversary":      EventAnniversary,
+		"wedding":          EventAnniversary,
+		"work-anniversary": EventWorkAnniversary,
+		"name_day":         EventNameDay,
+		"nameday":          EventNameDay,
+		"memorial":         EventMemorial,
+	}
+	for input, want := range tests {
+		if got, ok := ParseEventType(input); !ok || got != want {
+			t.Errorf("ParseEventType(%q) = %q, %t; want %q", input, got, ok, want)
+		}
+	}
+	if _, ok := ParseEventType("holiday"); ok {
+		t.Error("expected unknown event type to be rejected")
+	}
+	for _, eventType := range EventTypes {
+		if len(ReminderDays(eventType)) == 0 {
+			t.Errorf("event type %q has no reminder defaults", eventType)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := map[string][]string{
+		"family, #Team Backend, family": {"family", "team-backend"},
+		"family #friends":               {"family", "friends"},
+		"  ":                            nil,
+	}
+	for input, want := range tests {
+		got := ParseTags(input)
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("ParseTags(%q) = %v; want %v", input, got, want)
+		}
+	}
+
+	b := Birthday{Tags: []string{"family"}}
+	if !b.HasTag("family") || b.HasTag("team-backend") {
+		t.Error("HasTag should match only the record's tags")
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ChatSettings holds per-chat preferences configured through bot commands.
+type ChatSettings struct {
+	// ChatID is the Telegram chat ID the settings belong to.
+	ChatID int64 `yaml:"chat_id" json:"chat_id"`
+	// Language is the language code for bot replies and notifications (empty means auto-detect).
+	Language string `yaml:"language,omitempty" json:"language,omitempty"`
+	// Digest is the digest mode: "daily", "weekly" or "monthly" (empty disables digests).
+	Digest string `yaml:"digest,omitempty" json:"digest,omitempty"`
+	// DigestWeekday is the day of the week weekly digests are sent on (0 = Sunday).
+	DigestWeekday time.Weekday `yaml:"digest_weekday,omitempty" json:"digest_weekday,omitempty"`
+	// Tags routes notifications to the chat: records with any of these tags are announced in it.
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// TagsSetBy is the Telegram user ID of who set the tags. Only records owned by or linked to
+	// a chat that user manages are routed by tag.
+	TagsSetBy int64 `yaml:"tags_set_by,omitempty" json:"tags_set_by,omitempty"`
+	// GiftOffsetDays is how many days before a member's birthday a gift collection starts (0 disables it).
+	GiftOffsetDays int `yaml:"gift_offset_days,omitempty" json:"gift_offset_days,omitempty"`
+	// LastDigest is the timestamp of the last digest sent to the chat.
+	LastDigest time.Time `yaml:"last_digest,omitempty" json:"last_digest,omitempty"`
+}
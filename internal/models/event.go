@@ -0,0 +1,79 @@
+package models
+
+import "strings"
+
+// Event types of recurring records. Records without a type are birthdays, so files written
+// before event types were introduced load unchanged.
+const (
+	// EventBirthday is a person's or chat's birthday.
+	EventBirthday = "birthday"
+	// EventWorkAnniversary is the anniversary of joining a company or team.
+	EventWorkAnniversary = "work_anniversary"
+	// EventAnniversary is a wedding anniversary.
+	EventAnniversary = "anniversary"
+	// EventNameDay is a name day.
+	EventNameDay = "name_day"
+	// EventMemorial is the anniversary of a death, remembered rather than celebrated.
+	EventMemorial = "memorial"
+)
+
+// EventTypes lists the supported event types in display order.
+var EventTypes = []string{EventBirthday, EventWorkAnniversary, EventAnniversary, EventNameDay, EventMemorial}
+
+// eventReminderDays are the default reminder offsets of each event type, in days before the event.
+var eventReminderDays = map[string][]int{
+	EventBirthday:        {14, 28},
+	EventWorkAnniversary: {14},
+	EventAnniversary:     {14, 28},
+	EventNameDay:         {7},
+	EventMemorial:        {7},
+}
+
+// eventAliases are the alternative spellings accepted by ParseEventType.
+var eventAliases = map[string]string{
+	"bday":         EventBirthday,
+	"work":         EventWorkAnniversary,
+	"workiversary": EventWorkAnniversary,
+	"wedding":      EventAnniversary,
+	"nameday":      EventNameDay,
+	"memory":       EventMemorial,
+}
+
+// ParseEventType returns the event type for user input such as "anniversary", "work-anniversary"
+// or "wedding". It reports false for unknown types.
+func ParseEventType(s string) (string, bool) {
+	s = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "-", "_")
+	if alias, ok := eventAliases[s]; ok {
+		return alias, true
+	}
+	if _, ok := eventReminderDays[s]; ok {
+		return s, true
+	}
+	return "", false
+}
+
+// ReminderDays returns the default reminder offsets of the event type, in days before the event.
+func ReminderDays(eventType string) []int {
+	return eventReminderDays[eventType]
+}
+
+// EventType returns the type of the record, EventBirthday for records without a type.
+func (b Birthday) EventType() string {
+	if b.Type == "" {
+		return EventBirthday
+	}
+	return b.Type
+}
+
+// IsBirthday reports whether the record is a birthday rather than another kind of event.
+func (b Birthday) IsBirthday() bool {
+	return b.EventType() == EventBirthday
+}
+
+// SetEventType sets the type of the record. Birthdays are stored without a type.
+func (b *Birthday) SetEventType(eventType string) {
+	if eventType == EventBirthday {
+		eventType = ""
+	}
+	b.Type = eventType
+}
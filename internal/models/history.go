@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Outcomes of notification attempts.
+const (
+	// OutcomeSent means the notification was delivered to Telegram.
+	OutcomeSent = "sent"
+	// OutcomeFailed means sending the notification failed.
+	OutcomeFailed = "failed"
+)
+
+// NotificationEntry records a single notification attempt of the bot.
+type NotificationEntry struct {
+	// Time is when the notification was attempted.
+	Time time.Time `yaml:"time" json:"time"`
+	// Type is the notification type (e.g. BIRTHDAY_TODAY, REMINDER_2_WEEKS, DIGEST_WEEKLY).
+	Type string `yaml:"type" json:"type"`
+	// RecordID is the ID of the record the notification is about (empty for digests).
+	RecordID string `yaml:"record_id,omitempty" json:"record_id,omitempty"`
+	// RecordName is the name of the record at the time of the notification.
+	RecordName string `yaml:"record_name,omitempty" json:"record_name,omitempty"`
+	// ChatID is the chat the notification was sent to.
+	ChatID int64 `yaml:"chat_id" json:"chat_id"`
+	// Message is the rendered message text.
+	Message string `yaml:"message" json:"message"`
+	// Outcome is OutcomeSent or OutcomeFailed.
+	Outcome string `yaml:"outcome" json:"outcome"`
+	// Error is the error of a failed attempt.
+	Error string `yaml:"error,omitempty" json:"error,omitempty"`
+}
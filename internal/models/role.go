@@ -0,0 +1,34 @@
+package models
+
+// Roles of web interface users.
+const (
+	// RoleViewer may view records but not change them.
+	RoleViewer = "viewer"
+	// RoleEditor may change the records of the assigned chats.
+	RoleEditor = "editor"
+	// RoleAdmin may change everything, including bot settings and role assignments.
+	RoleAdmin = "admin"
+)
+
+// Roles lists the roles from least to most privileged.
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// IsRole reports whether s is a known role.
+func IsRole(s string) bool {
+	for _, r := range Roles {
+		if r == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleAssignment grants a web interface user a role.
+type RoleAssignment struct {
+	// User is the user name of local and proxy users, or "telegram:<id>" for users signed in with Telegram.
+	User string `yaml:"user" json:"user"`
+	// Role is one of the Roles.
+	Role string `yaml:"role" json:"role"`
+	// ChatIDs are the chats whose records an editor may change.
+	ChatIDs []int64 `yaml:"chat_ids,omitempty" json:"chat_ids,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// BotStats holds the lifetime statistics of the bot, kept across restarts.
+type BotStats struct {
+	// FirstStart is when the bot was started for the first time with this data.
+	FirstStart time.Time `yaml:"first_start,omitempty" json:"first_start,omitempty"`
+	// Starts is how often the bot was started.
+	Starts int64 `yaml:"starts,omitempty" json:"starts,omitempty"`
+	// NotificationsSent is the number of notifications sent.
+	NotificationsSent int64 `yaml:"notifications_sent,omitempty" json:"notifications_sent,omitempty"`
+	// NotificationsFailed is the number of notifications that could not be sent.
+	NotificationsFailed int64 `yaml:"notifications_failed,omitempty" json:"notifications_failed,omitempty"`
+	// SentByType is the number of notifications sent per notification type (e.g. BIRTHDAY_TODAY).
+	SentByType map[string]int64 `yaml:"sent_by_type,omitempty" json:"sent_by_type,omitempty"`
+	// LastSent is when the last notification was sent.
+	LastSent time.Time `yaml:"last_sent,omitempty" json:"last_sent,omitempty"`
+	// LastError is the error of the last notification that could not be sent.
+	LastError string `yaml:"last_error,omitempty" json:"last_error,omitempty"`
+	// LastErrorAt is when the last notification failed.
+	LastErrorAt time.Time `yaml:"last_error_at,omitempty" json:"last_error_at,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TrashedRecord is a deleted birthday record, kept in the trash until it is restored, purged
+// or its retention period ends.
+type TrashedRecord struct {
+	// Birthday is the record as it was when it was deleted.
+	Birthday `yaml:",inline"`
+	// DeletedAt is when the record was deleted.
+	DeletedAt time.Time `yaml:"deleted_at" json:"deleted_at"`
+	// DeletedBy is who deleted the record: the web user, or "telegram:<id>" for Telegram users.
+	DeletedBy string `yaml:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
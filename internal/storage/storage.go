@@ -1,17 +1,48 @@
 // Package storage provides YAML-based persistence for birthday data.
 // It manages loading and saving birthday records from/to a configurable file path.
+// Auxiliary data (e.g., chat settings) is kept in separate files next to the birthday file.
 package storage
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
+	"5mdt/bd_bot/internal/metrics"
 	"5mdt/bd_bot/internal/models"
 	"gopkg.in/yaml.v3"
 )
 
 const filePerm = 0644
 
+// chatsFileName is the name of the chat settings file stored next to the birthday file.
+const chatsFileName = "chats.yaml"
+
+// rolesFileName is the name of the file with the role assignments of web interface users.
+const rolesFileName = "roles.yaml"
+
+// statsFileName is the name of the file with the lifetime statistics of the bot.
+const statsFileName = "bot_stats.yaml"
+
+// historyFileName is the name of the file with the notification history.
+const historyFileName = "notifications.yaml"
+
+// auditFileName is the name of the append-only audit log of record changes.
+const auditFileName = "audit.yaml"
+
+// trashFileName is the name of the file with the deleted records.
+const trashFileName = "trash.yaml"
+
+// defaultTrashRetentionDays is how many days deleted records are kept unless TRASH_RETENTION_DAYS is set.
+const defaultTrashRetentionDays = 30
+
+// defaultHistorySize is how many notification attempts are kept unless NOTIFICATION_HISTORY_SIZE is set.
+const defaultHistorySize = 1000
+
 func getPath() string {
 	if path := os.Getenv("YAML_PATH"); path != "" {
 		return path
@@ -19,6 +50,11 @@ func getPath() string {
 	return "/data/birthdays.yaml"
 }
 
+// siblingPath returns the path of an auxiliary data file in the directory of the birthday file.
+func siblingPath(name string) string {
+	return filepath.Join(filepath.Dir(getPath()), name)
+}
+
 // ensureParentDir creates the parent directory for the given file path if it doesn't exist.
 func ensureParentDir(filePath string) error {
 	if dir := filepath.Dir(filePath); dir != "" && dir != "." {
@@ -27,41 +63,477 @@ func ensureParentDir(filePath string) error {
 	return nil
 }
 
-// LoadBirthdays reads and parses birthday data from the configured YAML file.
-// It creates an empty file (and parent directories) if it doesn't exist.
-// Returns a nil slice and error on failure.
-func LoadBirthdays() ([]models.Birthday, error) {
-	filePath := getPath()
+// readYAML parses the YAML file into out.
+// It creates the file with an empty list (and parent directories) if it doesn't exist.
+func readYAML(filePath string, out interface{}) error {
+	defer metrics.StorageDuration.ObserveSince(time.Now(), "load", filepath.Base(filePath))
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		// Ensure parent directory exists
 		if err := ensureParentDir(filePath); err != nil {
-			return nil, err
+			return err
 		}
 		if err := os.WriteFile(filePath, []byte("[]\n"), filePerm); err != nil {
-			return nil, err
+			return err
 		}
 	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, out)
+}
+
+// writeYAML marshals in to YAML and writes it to the file, creating parent directories if needed.
+func writeYAML(filePath string, in interface{}) error {
+	defer metrics.StorageDuration.ObserveSince(time.Now(), "save", filepath.Base(filePath))
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	// Ensure parent directory exists
+	if err := ensureParentDir(filePath); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, data, filePerm)
+}
+
+// LoadBirthdays reads and parses birthday data from the configured YAML file.
+// It creates an empty file (and parent directories) if it doesn't exist, but never rewrites it:
+// records without an ID get one at startup (AssignRecordIDs) or when they are next saved.
+// Returns a nil slice and error on failure.
+func LoadBirthdays() ([]models.Birthday, error) {
+	var bs []models.Birthday
+	if err := readYAML(getPath(), &bs); err != nil {
 		return nil, err
 	}
+	return bs, nil
+}
+
+// AssignRecordIDs gives the stored records without an ID (e.g. written by older versions) a stable one.
+// It is meant to run once at startup, and only writes the file if an ID was assigned.
+func AssignRecordIDs() error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	bs, err := LoadBirthdays()
+	if err != nil {
+		return err
+	}
+	if !assignIDs(bs) {
+		return nil
+	}
+	return writeYAML(getPath(), bs)
+}
+
+// CheckBirthdays reports whether the birthday file can be read and parsed, without changing it.
+// A missing file is fine, as it is created on the first load.
+func CheckBirthdays() error {
+	data, err := os.ReadFile(getPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 	var bs []models.Birthday
-	return bs, yaml.Unmarshal(data, &bs)
+	return yaml.Unmarshal(data, &bs)
 }
 
 // SaveBirthdays marshals birthday data to YAML and writes it to the configured file path.
-// It creates parent directories if they don't exist.
+// Records without an ID get one. It creates parent directories if they don't exist.
+// It does not take the update lock; changes to stored data go through UpdateBirthdays.
 func SaveBirthdays(bs []models.Birthday) error {
-	filePath := getPath()
-	data, err := yaml.Marshal(bs)
+	assignIDs(bs)
+	return writeYAML(getPath(), bs)
+}
+
+// assignIDs gives every record without an ID a new random one. It reports whether any ID was assigned.
+func assignIDs(bs []models.Birthday) bool {
+	assigned := false
+	for i := range bs {
+		if bs[i].ID == "" {
+			bs[i].ID = NewID()
+			assigned = true
+		}
+	}
+	return assigned
+}
+
+// NewID returns a new random record identifier of 16 hex characters.
+func NewID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// updateMu serializes read-modify-write cycles of UpdateBirthdays.
+var updateMu sync.Mutex
+
+// UpdateBirthdays loads the birthday data, applies update and saves the result.
+// All writers of the bot, the web UI and the API go through it, and concurrent calls are
+// serialized, so that no change is lost. Nothing is saved if update returns an error.
+// update must not block on the network, as it holds the lock for every other writer.
+func UpdateBirthdays(update func(bs []models.Birthday) ([]models.Birthday, error)) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	bs, err := LoadBirthdays()
+	if err != nil {
+		return err
+	}
+	bs, err = update(bs)
 	if err != nil {
 		return err
 	}
+	return SaveBirthdays(bs)
+}
 
-	// Ensure parent directory exists
-	if err := ensureParentDir(filePath); err != nil {
+// LoadChatSettings reads the settings of all chats from the chat settings file.
+func LoadChatSettings() ([]models.ChatSettings, error) {
+	var cs []models.ChatSettings
+	if err := readYAML(siblingPath(chatsFileName), &cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// SaveChatSettings writes the settings of all chats to the chat settings file.
+func SaveChatSettings(cs []models.ChatSettings) error {
+	return writeYAML(siblingPath(chatsFileName), cs)
+}
+
+// GetChatSettings returns the settings of a single chat.
+// Chats without stored settings get zero-value settings with the chat ID filled in.
+func GetChatSettings(chatID int64) (models.ChatSettings, error) {
+	cs, err := LoadChatSettings()
+	if err != nil {
+		return models.ChatSettings{ChatID: chatID}, err
+	}
+	for _, s := range cs {
+		if s.ChatID == chatID {
+			return s, nil
+		}
+	}
+	return models.ChatSettings{ChatID: chatID}, nil
+}
+
+// settingsMu serializes read-modify-write cycles of the chat settings.
+var settingsMu sync.Mutex
+
+// UpdateChatSettings applies update to the settings of a chat and saves them,
+// creating the settings entry if the chat has none yet.
+func UpdateChatSettings(chatID int64, update func(s *models.ChatSettings)) error {
+	_, err := updateChatSettings(chatID, true, update)
+	return err
+}
+
+// UpdateStoredChatSettings applies update to the settings of a chat and saves them, like
+// UpdateChatSettings, but leaves chats without settings alone, e.g. chats deleted with /forget_me
+// in the meantime. It reports whether the chat had settings.
+func UpdateStoredChatSettings(chatID int64, update func(s *models.ChatSettings)) (bool, error) {
+	return updateChatSettings(chatID, false, update)
+}
+
+func updateChatSettings(chatID int64, create bool, update func(s *models.ChatSettings)) (bool, error) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	cs, err := LoadChatSettings()
+	if err != nil {
+		return false, err
+	}
+	for i := range cs {
+		if cs[i].ChatID == chatID {
+			update(&cs[i])
+			return true, SaveChatSettings(cs)
+		}
+	}
+	if !create {
+		return false, nil
+	}
+	s := models.ChatSettings{ChatID: chatID}
+	update(&s)
+	return false, SaveChatSettings(append(cs, s))
+}
+
+// DeleteChatSettings removes the settings of a chat. It reports whether the chat had settings.
+func DeleteChatSettings(chatID int64) (bool, error) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	cs, err := LoadChatSettings()
+	if err != nil {
+		return false, err
+	}
+	for i := range cs {
+		if cs[i].ChatID == chatID {
+			return true, SaveChatSettings(append(cs[:i], cs[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// LoadRoleAssignments reads the role assignments of web interface users from the roles file.
+func LoadRoleAssignments() ([]models.RoleAssignment, error) {
+	var rs []models.RoleAssignment
+	if err := readYAML(siblingPath(rolesFileName), &rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// SaveRoleAssignments writes the role assignments of web interface users to the roles file.
+func SaveRoleAssignments(rs []models.RoleAssignment) error {
+	return writeYAML(siblingPath(rolesFileName), rs)
+}
+
+// rolesMu serializes read-modify-write cycles of the role assignments.
+var rolesMu sync.Mutex
+
+// SetRoleAssignment stores the role assignment, replacing an earlier assignment of the same user.
+func SetRoleAssignment(r models.RoleAssignment) error {
+	rolesMu.Lock()
+	defer rolesMu.Unlock()
+
+	rs, err := LoadRoleAssignments()
+	if err != nil {
 		return err
 	}
+	for i := range rs {
+		if rs[i].User == r.User {
+			rs[i] = r
+			return SaveRoleAssignments(rs)
+		}
+	}
+	return SaveRoleAssignments(append(rs, r))
+}
 
-	return os.WriteFile(filePath, data, filePerm)
+// DeleteRoleAssignment removes the role assignment of the user. It reports whether the user had one.
+func DeleteRoleAssignment(user string) (bool, error) {
+	rolesMu.Lock()
+	defer rolesMu.Unlock()
+
+	rs, err := LoadRoleAssignments()
+	if err != nil {
+		return false, err
+	}
+	for i := range rs {
+		if rs[i].User == user {
+			return true, SaveRoleAssignments(append(rs[:i], rs[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// LoadBotStats reads the lifetime statistics of the bot. A missing file yields empty statistics.
+func LoadBotStats() (models.BotStats, error) {
+	var st models.BotStats
+	path := siblingPath(statsFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return st, nil
+	}
+	if err := readYAML(path, &st); err != nil {
+		return models.BotStats{}, err
+	}
+	return st, nil
+}
+
+// SaveBotStats writes the lifetime statistics of the bot.
+func SaveBotStats(st models.BotStats) error {
+	return writeYAML(siblingPath(statsFileName), st)
+}
+
+// historyMu serializes appends to the notification history.
+var historyMu sync.Mutex
+
+// historySize returns how many notification attempts are kept, from NOTIFICATION_HISTORY_SIZE.
+func historySize() int {
+	if v := os.Getenv("NOTIFICATION_HISTORY_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultHistorySize
+}
+
+// LoadNotificationHistory reads the recorded notification attempts, oldest first.
+func LoadNotificationHistory() ([]models.NotificationEntry, error) {
+	var es []models.NotificationEntry
+	if err := readYAML(siblingPath(historyFileName), &es); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// AppendNotificationHistory records notification attempts. Only the most recent attempts are kept,
+// as many as NOTIFICATION_HISTORY_SIZE (default 1000).
+func AppendNotificationHistory(entries ...models.NotificationEntry) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	es, err := LoadNotificationHistory()
+	if err != nil {
+		return err
+	}
+	es = append(es, entries...)
+	if limit := historySize(); len(es) > limit {
+		es = es[len(es)-limit:]
+	}
+	return writeYAML(siblingPath(historyFileName), es)
+}
+
+// DeleteNotificationHistory removes the recorded notification attempts that match and returns how many were removed.
+// The file is only written if an attempt was removed.
+func DeleteNotificationHistory(match func(e models.NotificationEntry) bool) (int, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	es, err := LoadNotificationHistory()
+	if err != nil {
+		return 0, err
+	}
+	kept := es[:0]
+	for _, e := range es {
+		if !match(e) {
+			kept = append(kept, e)
+		}
+	}
+	removed := len(es) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, writeYAML(siblingPath(historyFileName), kept)
+}
+
+// auditMu serializes appends to the audit log.
+var auditMu sync.Mutex
+
+// AppendAuditLog appends entries to the audit log. The file is only ever appended to: each call
+// writes its entries as YAML list items at the end, so the file stays one valid YAML list.
+// The only exception is RedactAuditLog, which removes the data of forgotten chats.
+func AppendAuditLog(entries ...models.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	defer metrics.StorageDuration.ObserveSince(time.Now(), "save", auditFileName)
+	data, err := yaml.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	path := siblingPath(auditFileName)
+	if err := ensureParentDir(path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RedactAuditLog lets redact change the entries of the audit log in place. The file is only
+// rewritten if redact reports a change.
+func RedactAuditLog(redact func(es []models.AuditEntry) bool) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	es, err := LoadAuditLog()
+	if err != nil {
+		return err
+	}
+	if !redact(es) {
+		return nil
+	}
+	return writeYAML(siblingPath(auditFileName), es)
+}
+
+// LoadAuditLog reads the audit log, oldest entry first. A missing file yields an empty log.
+func LoadAuditLog() ([]models.AuditEntry, error) {
+	var es []models.AuditEntry
+	path := siblingPath(auditFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err := readYAML(path, &es); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// trashMu serializes changes to the trash.
+var trashMu sync.Mutex
+
+// TrashRetention returns how long deleted records are kept in the trash, TRASH_RETENTION_DAYS
+// (default 30) days.
+func TrashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// unexpired returns the trashed records whose retention period hasn't ended.
+func unexpired(ts []models.TrashedRecord, now time.Time) []models.TrashedRecord {
+	cutoff := now.Add(-TrashRetention())
+	kept := ts[:0]
+	for _, t := range ts {
+		if t.DeletedAt.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// LoadTrash reads the deleted records, oldest deletion first. Records past the retention period are left out.
+func LoadTrash() ([]models.TrashedRecord, error) {
+	var ts []models.TrashedRecord
+	if err := readYAML(siblingPath(trashFileName), &ts); err != nil {
+		return nil, err
+	}
+	return unexpired(ts, time.Now()), nil
+}
+
+// UpdateTrash loads the deleted records, applies the update and saves the result. Records past
+// the retention period are purged on every update. Updates are serialized, so concurrent
+// changes don't overwrite each other; birthday updates may change the trash, but not the other way round.
+func UpdateTrash(update func(ts []models.TrashedRecord) ([]models.TrashedRecord, error)) error {
+	trashMu.Lock()
+	defer trashMu.Unlock()
+
+	ts, err := LoadTrash()
+	if err != nil {
+		return err
+	}
+	ts, err = update(ts)
+	if err != nil {
+		return err
+	}
+	return writeYAML(siblingPath(trashFileName), ts)
+}
+
+// AddToTrash moves deleted records to the trash, on behalf of the user who deleted them.
+func AddToTrash(deletedBy string, bs ...models.Birthday) error {
+	now := time.Now().UTC()
+	return UpdateTrash(func(ts []models.TrashedRecord) ([]models.TrashedRecord, error) {
+		for _, b := range bs {
+			ts = append(ts, models.TrashedRecord{Birthday: b, DeletedAt: now, DeletedBy: deletedBy})
+		}
+		return ts, nil
+	})
 }
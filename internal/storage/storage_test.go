@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,3 +44,293 @@ func TestLoadSaveBirthdays(t *testing.T) {
 		}
 	}
 }
+
+func TestChatSettings(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	s, err := GetChatSettings(42)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if s.ChatID != 42 || s.Language != "" {
+		t.Errorf("unexpected default settings: %+v", s)
+	}
+
+	if err := UpdateChatSettings(42, func(s *models.ChatSettings) { s.Language = "ru" }); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if err := UpdateChatSettings(7, func(s *models.ChatSettings) { s.Language = "en" }); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if err := UpdateChatSettings(42, func(s *models.ChatSettings) { s.Language = "en" }); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	all, err := LoadChatSettings()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 chats, got %d", len(all))
+	}
+	if s, _ := GetChatSettings(42); s.Language != "en" {
+		t.Errorf("expected updated language en, got %q", s.Language)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, chatsFileName)); err != nil {
+		t.Errorf("chat settings should be stored next to the birthday file: %v", err)
+	}
+
+	if found, err := UpdateStoredChatSettings(99, func(s *models.ChatSettings) { s.Language = "ru" }); err != nil || found {
+		t.Errorf("chats without settings should be left alone, got %t, %v", found, err)
+	}
+	if all, _ := LoadChatSettings(); len(all) != 2 {
+		t.Errorf("expected 2 chats, got %d", len(all))
+	}
+}
+
+func TestRoleAssignments(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	if err := SetRoleAssignment(models.RoleAssignment{User: "alice", Role: models.RoleEditor, ChatIDs: []int64{-100}}); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := SetRoleAssignment(models.RoleAssignment{User: "telegram:42", Role: models.RoleViewer}); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := SetRoleAssignment(models.RoleAssignment{User: "alice", Role: models.RoleAdmin}); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	rs, err := LoadRoleAssignments()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(rs) != 2 || rs[0].Role != models.RoleAdmin || rs[0].ChatIDs != nil {
+		t.Errorf("assignment should be replaced, got %+v", rs)
+	}
+
+	if removed, err := DeleteRoleAssignment("alice"); err != nil || !removed {
+		t.Fatalf("delete = %t, %v", removed, err)
+	}
+	if removed, _ := DeleteRoleAssignment("alice"); removed {
+		t.Error("deleting a missing assignment should report false")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, rolesFileName)); err != nil {
+		t.Errorf("role assignments should be stored next to the birthday file: %v", err)
+	}
+}
+
+func TestConcurrentRoleAssignmentsAreKept(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := SetRoleAssignment(models.RoleAssignment{User: fmt.Sprintf("user%d", i), Role: models.RoleViewer}); err != nil {
+				t.Errorf("set failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if rs, _ := LoadRoleAssignments(); len(rs) != 20 {
+		t.Errorf("want 20 assignments, got %d", len(rs))
+	}
+}
+
+func TestUpdateBirthdays(t *testing.T) {
+	tmp := t.TempDir()
+	os.Setenv("YAML_PATH", filepath.Join(tmp, "birthdays.yaml"))
+	defer os.Unsetenv("YAML_PATH")
+
+	if err := SaveBirthdays([]models.Birthday{{Name: "Alice", BirthDate: "2000-01-01", ChatID: 1}}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	done := make(chan error)
+	for i := 0; i < 10; i++ {
+		go func() {
+			done <- UpdateBirthdays(func(bs []models.Birthday) ([]models.Birthday, error) {
+				bs[0].GiftCollections = append(bs[0].GiftCollections, models.GiftCollection{ChatID: -100})
+				return bs, nil
+			})
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+	}
+
+	bs, err := LoadBirthdays()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if got := len(bs[0].GiftCollections); got != 10 {
+		t.Errorf("concurrent updates were lost: got %d collections, want 10", got)
+	}
+}
+
+func TestLoadLegacyRecordsAsBirthdays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.yaml")
+	os.Setenv("YAML_PATH", path)
+	defer os.Unsetenv("YAML_PATH")
+
+	legacy := "- name: Alice\n  birth_date: \"2000-01-01\"\n  last_notification: 0001-01-01T00:00:00Z\n  chat_id: 123\n" +
+		"- name: Alice & Bob\n  type: anniversary\n  birth_date: \"2015-06-01\"\n  last_notification: 0001-01-01T00:00:00Z\n  chat_id: -100\n"
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	got, err := LoadBirthdays()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(got) != 2 || got[0].EventType() != models.EventBirthday || got[1].EventType() != models.EventAnniversary {
+		t.Errorf("unexpected event types: %+v", got)
+	}
+}
+
+func TestAssignRecordIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.yaml")
+	os.Setenv("YAML_PATH", path)
+	defer os.Unsetenv("YAML_PATH")
+
+	legacy := "- name: Alice\n  birth_date: \"2000-01-01\"\n  chat_id: 123\n"
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// Loading never writes the file
+	if _, err := LoadBirthdays(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != legacy {
+		t.Errorf("loading should leave the file as it is, got %q", data)
+	}
+
+	if err := AssignRecordIDs(); err != nil {
+		t.Fatalf("assign failed: %v", err)
+	}
+	first, err := LoadBirthdays()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(first) != 1 || len(first[0].ID) != 16 {
+		t.Fatalf("expected a generated ID, got %+v", first)
+	}
+
+	if err := AssignRecordIDs(); err != nil {
+		t.Fatalf("assign failed: %v", err)
+	}
+	second, err := LoadBirthdays()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if second[0].ID != first[0].ID {
+		t.Errorf("IDs should be stable: %q != %q", second[0].ID, first[0].ID)
+	}
+
+	bs := append(second, models.Birthday{Name: "Bob", BirthDate: "0000-12-31"})
+	if err := SaveBirthdays(bs); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if bs[1].ID == "" || bs[1].ID == bs[0].ID {
+		t.Errorf("new records should get a unique ID, got %q", bs[1].ID)
+	}
+}
+
+func TestNotificationHistoryIsBounded(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	t.Setenv("NOTIFICATION_HISTORY_SIZE", "3")
+
+	for _, typ := range []string{"A", "B", "C", "D"} {
+		if err := AppendNotificationHistory(models.NotificationEntry{Type: typ, ChatID: 1, Outcome: models.OutcomeSent}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+
+	es, err := LoadNotificationHistory()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(es) != 3 || es[0].Type != "B" || es[2].Type != "D" {
+		t.Errorf("want the 3 most recent entries B..D, got %+v", es)
+	}
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+
+	if es, err := LoadAuditLog(); err != nil || es != nil {
+		t.Fatalf("missing audit log should be empty, got %+v, %v", es, err)
+	}
+
+	first := models.AuditEntry{Actor: "admin", Action: models.AuditCreate, RecordID: "a",
+		Changes: []models.FieldChange{{Field: "name", After: "Alice"}}}
+	if err := AppendAuditLog(first); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	path := filepath.Join(filepath.Dir(os.Getenv("YAML_PATH")), auditFileName)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := models.AuditEntry{Actor: "telegram:1", Action: models.AuditDelete, RecordID: "a"}
+	if err := AppendAuditLog(second); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after[:len(before)]) != string(before) {
+		t.Error("appending should keep the existing entries untouched")
+	}
+
+	es, err := LoadAuditLog()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(es) != 2 || es[0].Changes[0].After != "Alice" || es[1].Actor != "telegram:1" {
+		t.Errorf("want both entries in order, got %+v", es)
+	}
+}
+
+func TestTrashRetention(t *testing.T) {
+	t.Setenv("YAML_PATH", filepath.Join(t.TempDir(), "birthdays.yaml"))
+	t.Setenv("TRASH_RETENTION_DAYS", "7")
+
+	old := models.TrashedRecord{Birthday: models.Birthday{ID: "old"}, DeletedAt: time.Now().Add(-8 * 24 * time.Hour)}
+	err := UpdateTrash(func(ts []models.TrashedRecord) ([]models.TrashedRecord, error) {
+		return append(ts, old), nil
+	})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if err := AddToTrash("admin", models.Birthday{ID: "new", Name: "Alice"}); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	ts, err := LoadTrash()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(ts) != 1 || ts[0].ID != "new" || ts[0].DeletedBy != "admin" {
+		t.Errorf("records past the retention period should be purged, got %+v", ts)
+	}
+	data, err := os.ReadFile(siblingPath(trashFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "old") {
+		t.Error("expired records should be removed from the file")
+	}
+}
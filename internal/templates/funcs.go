@@ -4,8 +4,11 @@ package templates
 import (
 	"errors"
 	"fmt"
+	"html/template"
 	"strings"
 	"time"
+
+	"5mdt/bd_bot/internal/i18n"
 )
 
 // nowYear returns the current year and can be overridden in tests for deterministic behavior.
@@ -30,6 +33,20 @@ func dict(v ...interface{}) (map[string]interface{}, error) {
 	return m, nil
 }
 
+// translate returns the catalog message for key in the given language, formatted with args.
+// The language is passed as interface{} because partial templates may be executed without one,
+// in which case the default language is used.
+func translate(lang interface{}, key string, args ...interface{}) string {
+	l, _ := lang.(string)
+	return i18n.T(l, key, args...)
+}
+
+// translateHTML is like translate but returns trusted HTML for messages authored with markup.
+func translateHTML(lang interface{}, key string, args ...interface{}) template.HTML {
+	l, _ := lang.(string)
+	return i18n.HTML(l, key, args...)
+}
+
 // formatTime returns a time.Time as an RFC3339 string for JavaScript consumption,
 // or an empty string if the time is zero.
 func formatTime(t time.Time) string {
@@ -39,6 +56,11 @@ func formatTime(t time.Time) string {
 	return t.UTC().Format(time.RFC3339)
 }
 
+// joinTags returns the tags of a record as the comma-separated text edited on the card.
+func joinTags(tags []string) string {
+	return strings.Join(tags, ", ")
+}
+
 // isZeroTime returns true if the given time.Time value is zero (unset).
 func isZeroTime(t time.Time) bool {
 	return t.IsZero()
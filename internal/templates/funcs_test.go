@@ -199,3 +199,12 @@ func TestIsUnknownYear(t *testing.T) {
 		})
 	}
 }
+
+func TestJoinTags(t *testing.T) {
+	if got := joinTags([]string{"family", "team-backend"}); got != "family, team-backend" {
+		t.Errorf("joinTags() = %q; want %q", got, "family, team-backend")
+	}
+	if got := joinTags(nil); got != "" {
+		t.Errorf("joinTags(nil) = %q; want empty string", got)
+	}
+}
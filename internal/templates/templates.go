@@ -27,6 +27,9 @@ func LoadTemplates() *template.Template {
 			"formatBirthDate":         formatBirthDate,
 			"formatBirthDateForInput": formatBirthDateForInput,
 			"isUnknownYear":           isUnknownYear,
+			"joinTags":                joinTags,
+			"t":                       translate,
+			"thtml":                   translateHTML,
 		})
 		tpl = template.Must(tpl.ParseFS(tmplFS, "tmpl/*.gohtml"))
 	})